@@ -3,6 +3,8 @@ package archive
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
 	"errors"
@@ -14,6 +16,7 @@ import (
 	"strings"
 
 	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
 )
 
 // Type defines the archive types that can be processed
@@ -27,6 +30,7 @@ const (
 	TarGz
 	TarXz
 	Zip
+	TarLzma
 )
 
 // String returns a string representation of the archive type.
@@ -42,6 +46,8 @@ func (t Type) String() (result string) {
 		result = "TarXz"
 	case Zip:
 		result = "Zip"
+	case TarLzma:
+		result = "TarLzma"
 	}
 	return
 }
@@ -51,14 +57,38 @@ const (
 	fmtErrArchiveOpen   string = "archive: failed to open archive: %v"
 	fmtErrNewGzReader   string = "archive: failed to gz reader: %v"
 	fmtErrNewXzReader   string = "archive: failed to xz reader: %v"
+	fmtErrNewLzmaReader string = "archive: failed to lzma reader: %v"
 	fmtErrTarReadFailed string = "archive: failed while reading tar contents: %v"
 	fmtErrZipReadFailed string = "archive: failed while reading zip contents: %v"
 )
 
+// Format strings for errors returned by callbacks. WalkTar, WalkZip, and the other
+// original walk functions report such errors as text only; the walks added since wrap
+// them, so that the callback's own errors can be told apart with errors.Is and errors.As.
+const (
+	fmtErrTarCallbackFailed string = "archive: failed while reading tar contents: %w"
+	fmtErrZipCallbackFailed string = "archive: failed while reading zip contents: %w"
+)
+
 // errUnknownType is returned by DetermineType if the provided filename
 // fails to match an archive type supported by this package.
 var errUnknownType = errors.New("archive: unable to determine type")
 
+// errLzip is returned when an archive identified as TarLzma is instead compressed
+// with lzip, which shares the ".tlz" extension but is not supported.
+var errLzip = errors.New("archive: lzip-compressed archives are not supported")
+
+// lzipMagic begins every lzip-compressed file.
+var lzipMagic = []byte("LZIP")
+
+// errNotZip is returned when a function that only supports zip archives is given
+// an archive of another type.
+var errNotZip = errors.New("archive: not a zip archive")
+
+// errNotTar is returned when a tar stream is requested for an archive type
+// that is not a member of the tar family.
+var errNotTar = errors.New("archive: not a tar archive type")
+
 func init() {
 	typeInfoMap = make(map[Type]typeInfo)
 
@@ -67,6 +97,7 @@ func init() {
 	typeInfoMap[TarGz] = typeInfo{extensions: []string{".tar.gz", ".tgz"}}
 	typeInfoMap[TarXz] = typeInfo{extensions: []string{".tar.xz", ".txz"}}
 	typeInfoMap[Zip] = typeInfo{extensions: []string{".zip"}}
+	typeInfoMap[TarLzma] = typeInfo{extensions: []string{".tar.lzma", ".tlz"}}
 }
 
 // DetermineType identifies the archive file type based on the extensions present in the
@@ -74,7 +105,8 @@ func init() {
 // with ".tar.bz2", ".tar.bzip2", ".tbz", or ".tbz2" extensions will be identified
 // as TarBz2. Files with ".tar.gz" or ".tgz" extensions will be identified
 // as TarGz. Files with ".tar.xz" or ".txz" extensions will be identified
-// as TarXz. Files with the ".zip" extension will be identified as Zip. Anything
+// as TarXz. Files with ".tar.lzma" or ".tlz" extensions will be identified as
+// TarLzma. Files with the ".zip" extension will be identified as Zip. Anything
 // else returns 0 and a non-nil error.
 func DetermineType(filename string) (Type, error) {
 	f := strings.ToLower(filename)
@@ -94,15 +126,27 @@ func DetermineType(filename string) (Type, error) {
 }
 
 // TarCallback is the type of function called for each file or directory entry
-// visited by the WalkTar functions.
+// visited by the WalkTar functions. A callback need not read the entry's body: any
+// unread portion is skipped when the walk advances to the next entry, so a callback
+// that only inspects the header may simply return.
 type TarCallback func(*tar.Reader, *tar.Header) error
 
+// SkipBody skips the body of the current entry of r. It exists to make the intent of a
+// TarCallback that ignores an entry's contents explicit, and does nothing: tar.Reader's
+// Next method discards any unread portion of the current entry before reading the next
+// header, seeking past it when the underlying reader supports seeking. Reading only part
+// of a body before returning from a callback is likewise safe.
+func SkipBody(r *tar.Reader) error {
+	return nil
+}
+
 // ZipCallback is the type of function called for each file or directory entry
 // visited by WalkZip.
 type ZipCallback func(*zip.File) error
 
 // WalkZip walks the contents of a zip file and invokes the callback
-// function for each entry.
+// function for each entry. An entry whose name is empty or consists only of
+// whitespace stops the walk with an error wrapping ErrInvalidEntryName.
 func WalkZip(archivePath string, callback ZipCallback) error {
 	r, err := zip.OpenReader(archivePath)
 	if err != nil {
@@ -110,16 +154,7 @@ func WalkZip(archivePath string, callback ZipCallback) error {
 	}
 	defer r.Close()
 
-	for _, f := range r.File {
-		if callback != nil {
-			err := callback(f)
-			if err != nil {
-				return fmt.Errorf(fmtErrZipReadFailed, err)
-			}
-		}
-	}
-
-	return nil
+	return readZip(r.File, callback)
 }
 
 // WalkTar walks the contents of a tar file and invokes the callback
@@ -203,8 +238,96 @@ func WalkTarXz(archivePath string, callback TarCallback) error {
 	return readTar(tar.NewReader(reader), callback)
 }
 
+// WalkTarLzma walks the contents of an lzma-compressed tar file, in the legacy
+// "lzma_alone" format that preceded xz, and invokes the callback function for each
+// entry. The format has no magic, so such archives can only be identified by their
+// extensions. The ".tlz" extension is also used for lzip-compressed tar files, which
+// are not supported; for these, a non-nil error naming lzip is returned.
+func WalkTarLzma(archivePath string, callback TarCallback) error {
+	return walkTarWrapping(archivePath, TarLzma, callback)
+}
+
+// Determines the type of the archive at archivePath and walks its contents, invoking
+// tarCallback for tar-family archives and zipCallback for zip archives. Errors returned
+// by the callbacks are wrapped.
+func walkArchive(archivePath string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if typ == Zip {
+		return walkZipWrapping(archivePath, zipCallback)
+	}
+	return walkTarWrapping(archivePath, typ, tarCallback)
+}
+
+// Walks the tar-family archive of the given type at archivePath as WalkTar and its
+// siblings do, but wraps errors returned by the callback.
+func walkTarWrapping(archivePath string, typ Type, callback TarCallback) error {
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return readTarWrapping(tar.NewReader(stream), callback)
+}
+
+// Walks the zip archive at archivePath as WalkZip does, but wraps errors returned by
+// the callback.
+func walkZipWrapping(archivePath string, callback ZipCallback) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	return readZipWrapping(r.File, callback)
+}
+
+// Reads the zip file contents.
+func readZip(files []*zip.File, callback ZipCallback) error {
+	return readZipFiles(files, callback, fmtErrZipReadFailed)
+}
+
+// Reads the zip file contents as readZip does, but wraps errors returned by the callback.
+func readZipWrapping(files []*zip.File, callback ZipCallback) error {
+	return readZipFiles(files, callback, fmtErrZipCallbackFailed)
+}
+
+// Reads the zip file contents, reporting errors returned by the callback with the
+// given format.
+func readZipFiles(files []*zip.File, callback ZipCallback, fmtCallbackErr string) error {
+	for _, f := range files {
+		if isBlankName(f.Name) {
+			return fmt.Errorf("%w: %q", ErrInvalidEntryName, f.Name)
+		}
+
+		if callback != nil {
+			err := callback(f)
+			if err != nil {
+				return fmt.Errorf(fmtCallbackErr, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Reads the tar file contents.
 func readTar(reader *tar.Reader, callback TarCallback) error {
+	return readTarEntries(reader, callback, fmtErrTarReadFailed)
+}
+
+// Reads the tar file contents as readTar does, but wraps errors returned by the callback.
+func readTarWrapping(reader *tar.Reader, callback TarCallback) error {
+	return readTarEntries(reader, callback, fmtErrTarCallbackFailed)
+}
+
+// Reads the tar file contents, reporting errors returned by the callback with the
+// given format.
+func readTarEntries(reader *tar.Reader, callback TarCallback, fmtCallbackErr string) error {
 	for {
 		header, err := reader.Next()
 		if err == io.EOF {
@@ -216,7 +339,7 @@ func readTar(reader *tar.Reader, callback TarCallback) error {
 		if callback != nil {
 			err := callback(reader, header)
 			if err != nil {
-				return fmt.Errorf(fmtErrTarReadFailed, err)
+				return fmt.Errorf(fmtCallbackErr, err)
 			}
 		}
 	}
@@ -224,6 +347,108 @@ func readTar(reader *tar.Reader, callback TarCallback) error {
 	return nil
 }
 
+// OpenDecompressed opens the archive at path and returns a reader over its decompressed
+// contents along with its type, as determined by DetermineTypeSmart. For tar-family
+// archives, the reader yields the decompressed tar stream, which can be passed to
+// tar.NewReader or processed in any other way; for zip archives, which are compressed
+// per entry, it yields the raw file. Closing the returned reader closes the
+// decompressor as well as the underlying file.
+func OpenDecompressed(path string) (io.ReadCloser, Type, error) {
+	typ, err := DetermineTypeSmart(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == Zip {
+		file, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return nil, 0, fmt.Errorf(fmtErrArchiveOpen, err)
+		}
+		return file, typ, nil
+	}
+
+	stream, err := openTarStream(path, typ)
+	if err != nil {
+		return nil, 0, err
+	}
+	return stream, typ, nil
+}
+
+// Opens the archive at archivePath and returns a reader over its decompressed
+// tar stream. Closing the returned reader closes the decompressor as well as the
+// underlying file.
+func openTarStream(archivePath string, typ Type) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	if typ == Tar {
+		return file, nil
+	}
+
+	stream, err := newTarStream(file, typ)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &multiReadCloser{Reader: stream, closers: []io.Closer{stream, file}}, nil
+}
+
+// Returns a reader over the decompressed tar stream of a tar-family archive of
+// the given type read from r. Closing the returned reader closes the decompressor
+// but not r.
+func newTarStream(r io.Reader, typ Type) (io.ReadCloser, error) {
+	switch typ {
+	case Tar:
+		return io.NopCloser(r), nil
+	case TarBz2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case TarGz:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf(fmtErrNewGzReader, err)
+		}
+		return reader, nil
+	case TarXz:
+		reader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf(fmtErrNewXzReader, err)
+		}
+		return io.NopCloser(reader), nil
+	case TarLzma:
+		buffered := bufio.NewReader(r)
+		if magic, _ := buffered.Peek(len(lzipMagic)); bytes.Equal(magic, lzipMagic) {
+			return nil, errLzip
+		}
+
+		reader, err := lzma.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf(fmtErrNewLzmaReader, err)
+		}
+		return io.NopCloser(reader), nil
+	}
+
+	return nil, errNotTar
+}
+
+// Struct multiReadCloser is a reader whose Close method closes each of
+// the contained closers in order.
+type multiReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes each of the contained closers, returning the first error encountered.
+func (m *multiReadCloser) Close() (err error) {
+	for _, c := range m.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return
+}
+
 // Struct typeInfo contains information relating to a given
 // archive type.
 type typeInfo struct {
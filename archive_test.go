@@ -3,13 +3,171 @@ package archive
 import (
 	"archive/tar"
 	"archive/zip"
+	"compress/gzip"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
 	"go.uber.org/goleak"
 )
 
+// testEntry describes an entry written by writeTestArchive.
+type testEntry struct {
+	name     string
+	body     string
+	typeflag byte // tar only; zero means a regular file, or a directory for names ending in "/"
+	linkname string
+	modTime  time.Time // zero means testModTime
+	mode     int64     // tar-style permission and special bits; zero means the default
+}
+
+// testModTime is the default modification time of entries written by writeTestArchive.
+var testModTime = time.Date(2016, time.May, 12, 15, 7, 0, 0, time.UTC)
+
+// Returns the entry's modification time, or testModTime if none was specified.
+func (e testEntry) mtime() time.Time {
+	if e.modTime.IsZero() {
+		return testModTime
+	}
+	return e.modTime
+}
+
+// Writes an archive named filename into a temporary directory and returns its path. The
+// archive type is determined from filename; bzip2 compression is not supported.
+func writeTestArchive(t *testing.T, filename string, entries []testEntry) string {
+	t.Helper()
+
+	typ, err := DetermineType(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), filename)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if typ == Zip {
+		writeTestZip(t, file, entries)
+		return path
+	}
+
+	var w io.WriteCloser
+	switch typ {
+	case TarGz:
+		w = gzip.NewWriter(file)
+	case TarXz:
+		if w, err = xz.NewWriter(file); err != nil {
+			t.Fatal(err)
+		}
+	case TarLzma:
+		if w, err = lzma.NewWriter(file); err != nil {
+			t.Fatal(err)
+		}
+	case Tar:
+		w = file
+	default:
+		t.Fatalf("unsupported test archive type %s", typ)
+	}
+
+	writeTestTar(t, w, entries)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Writes entries to w as a tar stream.
+func writeTestTar(t *testing.T, w io.Writer, entries []testEntry) {
+	t.Helper()
+
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			ModTime:  e.mtime(),
+		}
+		if header.Typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+			if strings.HasSuffix(e.name, "/") {
+				header.Typeflag = tar.TypeDir
+			}
+		}
+		if header.Typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if e.mode != 0 {
+			header.Mode = e.mode
+		}
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(e.body))
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Writes entries to w as a zip archive.
+func writeTestZip(t *testing.T, w io.Writer, entries []testEntry) {
+	t.Helper()
+
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.mtime()}
+		body := e.body
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			header.Method = zip.Store
+			header.SetMode(os.ModeDir | 0755)
+		case e.typeflag == tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+			body = e.linkname
+		default:
+			header.SetMode(0644)
+		}
+		if e.mode != 0 {
+			header.SetMode((&tar.Header{Mode: e.mode}).FileInfo().Mode() | header.Mode().Type())
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 type typeTest struct {
 	filename      string
 	expectedType  Type
@@ -26,6 +184,8 @@ var types = []typeTest{
 	{"foo.tgz", TarGz, nil},
 	{"foo.tar.xz", TarXz, nil},
 	{"foo.txz", TarXz, nil},
+	{"foo.tar.lzma", TarLzma, nil},
+	{"foo.tlz", TarLzma, nil},
 	{"foo.zip", Zip, nil},
 	{"foo.123", 0, errUnknownType},
 	{"foo.tar1", 0, errUnknownType},
@@ -123,6 +283,102 @@ func TestWalkTarXz(t *testing.T) {
 	}
 }
 
+func TestWalkTarLzma(t *testing.T) {
+	var names []string
+	err := WalkTarLzma("testdata/sample.tar.lzma", func(reader *tar.Reader, header *tar.Header) error {
+		names = append(names, header.Name)
+		return nil
+	})
+	if err != nil || len(names) != 3 {
+		t.Errorf("Expecting 3 entries, got %d (error: %v)\n", len(names), err)
+	}
+
+	path := writeTestArchive(t, "written.tlz", findEntries)
+	names = walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+		return walkArchive(path, tarCb, zipCb)
+	})
+	if len(names) != len(findEntries) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(findEntries), len(names))
+	}
+
+	err = WalkTarLzma("nonexistent.tar.lzma", nil)
+	if err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent tar.lzma file.")
+	}
+
+	err = WalkTarLzma("testdata/sample.tar.gz", nil)
+	if err == nil {
+		t.Error("Failed to receive non-nil error when walking a tar.gz file via the WalkTarLzma function.")
+	}
+
+	lzip := filepath.Join(t.TempDir(), "sample.tlz")
+	if err := os.WriteFile(lzip, append([]byte("LZIP\x01"), make([]byte, 32)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkTarLzma(lzip, nil); err != errLzip {
+		t.Errorf("Expecting '%s', got '%v'\n", errLzip, err)
+	}
+
+	err = WalkTarLzma("testdata/sample.tar.lzma", func(reader *tar.Reader, header *tar.Header) error {
+		return errors.New("an error in callback processing")
+	})
+	if err == nil {
+		t.Error("Failed to return error from callback.")
+	}
+}
+
+// Names in testdata/longname.tar, written by GNU tar in its own format, which
+// records names and link targets longer than 100 bytes in preceding "././@LongLink"
+// entries.
+const (
+	longNameDir    = "sample/very-long-directory-name-01/very-long-directory-name-02/very-long-directory-name-03/very-long-directory-name-04/"
+	longNameFile   = longNameDir + "file-with-a-rather-long-name-as-well.txt"
+	longNameTarget = "../very-long-directory-name-04/../very-long-directory-name-04/file-with-a-rather-long-name-as-well.txt"
+)
+
+func TestWalkTar_gnuLongNames(t *testing.T) {
+	var names, linknames []string
+	err := WalkTar("testdata/longname.tar", func(reader *tar.Reader, header *tar.Header) error {
+		names = append(names, header.Name)
+		if header.Typeflag == tar.TypeSymlink {
+			linknames = append(linknames, header.Linkname)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	if len(names) != 7 || names[5] != longNameFile || !strings.HasPrefix(names[6], longNameDir+"link-") {
+		t.Errorf("Unexpected names: %v\n", names)
+	}
+	if len(linknames) != 1 || linknames[0] != longNameTarget {
+		t.Errorf("Expecting '%s', got '%v'\n", longNameTarget, linknames)
+	}
+
+	infos, err := List("testdata/longname.tar")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	for _, info := range infos {
+		if strings.Contains(info.Name, "@LongLink") {
+			t.Errorf("Unexpected entry '%s'\n", info.Name)
+		}
+	}
+	if len(infos) != len(names) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(names), len(infos))
+	}
+
+	dest := t.TempDir()
+	if err := ExtractAll("testdata/longname.tar", dest); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(names[6])))
+	if string(content) != "lorem ipsum\n" || err != nil {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "lorem ipsum\n", content, err)
+	}
+}
+
 func TestWalkZip(t *testing.T) {
 	callback := func(file *zip.File) error {
 		fmt.Printf("%s\n", file.Name)
@@ -142,6 +398,66 @@ func TestWalkZip(t *testing.T) {
 	if err == nil {
 		t.Error("Failed to return error from callback.")
 	}
+
+	var names []string
+	err = WalkZip("testdata/emptyname.zip", func(file *zip.File) error {
+		names = append(names, file.Name)
+		return nil
+	})
+	if !errors.Is(err, ErrInvalidEntryName) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrInvalidEntryName, err)
+	}
+	if len(names) != 1 || names[0] != "readme.txt" {
+		t.Errorf("Expecting '%v', got '%v'\n", []string{"readme.txt"}, names)
+	}
+}
+
+// zip64EntryCount exceeds the 16-bit entry count of the end of central directory
+// record, forcing a zip64 end of central directory record to be written.
+const zip64EntryCount = 70000
+
+func TestWalkZip_zip64EntryCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "many.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(file)
+	for i := range zip64EntryCount {
+		if _, err := zw.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("%05d", i), Method: zip.Store}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eocd := data[len(data)-zipEndOfCentralLen:]
+	if count := binary.LittleEndian.Uint16(eocd[zipEndOfCentralCountPos:]); count != zipUint16Max {
+		t.Fatalf("Expecting an overflowed entry count of %d, got %d\n", zipUint16Max, count)
+	}
+
+	visited := 0
+	err = WalkZip(path, func(f *zip.File) error {
+		if expected := fmt.Sprintf("%05d", visited); f.Name != expected {
+			return fmt.Errorf("expecting '%s', got '%s'", expected, f.Name)
+		}
+		visited++
+		return nil
+	})
+	if err != nil || visited != zip64EntryCount {
+		t.Errorf("Expecting %d entries, got %d (error: %v)\n", zip64EntryCount, visited, err)
+	}
+
+	if err := CheckTruncation(path); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
 }
 
 type typeStringTest struct {
@@ -155,6 +471,7 @@ var typeStrings = []typeStringTest{
 	{TarGz, "TarGz"},
 	{TarXz, "TarXz"},
 	{Zip, "Zip"},
+	{TarLzma, "TarLzma"},
 }
 
 func TestType_String(t *testing.T) {
@@ -167,6 +484,119 @@ func TestType_String(t *testing.T) {
 	}
 }
 
+func TestOpenDecompressed(t *testing.T) {
+	for _, archivePath := range sampleArchives[:4] {
+		stream, typ, err := OpenDecompressed(archivePath)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+			continue
+		}
+
+		expected, _ := DetermineType(archivePath)
+		if typ != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, typ)
+		}
+
+		var names []string
+		reader := tar.NewReader(stream)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("Unexpected error reading %s: %v\n", archivePath, err)
+				break
+			}
+			names = append(names, header.Name)
+		}
+		if len(names) != 3 {
+			t.Errorf("Expecting '%d', got '%d'\n", 3, len(names))
+		}
+
+		if err := stream.Close(); err != nil {
+			t.Errorf("Unexpected error closing %s: %v\n", archivePath, err)
+		}
+	}
+
+	stream, typ, err := OpenDecompressed("testdata/sample.zip")
+	if err != nil || typ != Zip {
+		t.Fatalf("Expecting '%s', got '%s' (error: %v)\n", Zip, typ, err)
+	}
+	data, err := io.ReadAll(stream)
+	stream.Close()
+	if expected, _ := os.ReadFile("testdata/sample.zip"); err != nil || string(data) != string(expected) {
+		t.Error("Expected the raw zip file to be returned.")
+	}
+
+	path := filepath.Join(t.TempDir(), "download")
+	if data, err := os.ReadFile("testdata/sample.tar.xz"); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if stream, typ, err := OpenDecompressed(path); err != nil || typ != TarXz {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", TarXz, typ, err)
+	} else {
+		stream.Close()
+	}
+
+	if _, _, err := OpenDecompressed("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+var skipBodyEntries = []testEntry{
+	{name: "skipped.txt", body: strings.Repeat("s", 1500)},
+	{name: "partial.txt", body: strings.Repeat("p", 700)},
+	{name: "empty.txt"},
+	{name: "read.txt", body: "read in full"},
+	{name: "last.txt", body: "last"},
+}
+
+func TestSkipBody(t *testing.T) {
+	// A callback that reads none or only part of an entry's body must still
+	// land on the next header, whether or not the stream is seekable.
+	for _, filename := range []string{"skip.tar", "skip.tar.gz"} {
+		path := writeTestArchive(t, filename, skipBodyEntries)
+
+		var names []string
+		err := walkArchive(path, func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+
+			switch header.Name {
+			case "skipped.txt", "empty.txt":
+				return SkipBody(reader)
+			case "partial.txt":
+				_, err := io.ReadFull(reader, make([]byte, 10))
+				return err
+			}
+
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			for _, e := range skipBodyEntries {
+				if e.name == header.Name && e.body != string(body) {
+					t.Errorf("Expecting '%s', got '%s'\n", e.body, body)
+				}
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v\n", filename, err)
+		}
+
+		if len(names) != len(skipBodyEntries) {
+			t.Fatalf("Expecting '%d', got '%d'\n", len(skipBodyEntries), len(names))
+		}
+		for i, e := range skipBodyEntries {
+			if names[i] != e.name {
+				t.Errorf("Expecting '%s', got '%s'\n", e.name, names[i])
+			}
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	goleak.VerifyTestMain(m)
 }
@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"archive/tar"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Special permission bits of a tar header's mode (POSIX.1-1988, section 10.1.1).
+const (
+	tarModeSetuid = 04000
+	tarModeSetgid = 02000
+	tarModeSticky = 01000
+)
+
+// SpecialBits decodes the setuid, setgid, and sticky bits from the mode of a tar header.
+func SpecialBits(header *tar.Header) (setuid, setgid, sticky bool) {
+	return header.Mode&tarModeSetuid != 0, header.Mode&tarModeSetgid != 0, header.Mode&tarModeSticky != 0
+}
+
+// FindSpecialBits returns, in archive order, the names of the entries in the archive at
+// path, whose type is determined by DetermineType, that have any of the setuid, setgid,
+// or sticky bits set. Extracting setuid or setgid executables from an untrusted archive
+// is a well-known attack vector, so such entries should be flagged before extraction.
+// Zip archives only carry these bits for entries created on Unix-like systems.
+func FindSpecialBits(path string) ([]string, error) {
+	var names []string
+	err := forEachEntry(path, func(e *entry) error {
+		if e.mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) != 0 {
+			names = append(names, e.name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// FindCaseCollisions returns groups of entry names from the archive at archivePath, whose
+// type is determined by DetermineType, that differ only by case. When such an archive is
+// extracted onto a case-insensitive file system, as is usual on macOS and Windows, the
+// later entries of each group silently overwrite the earlier ones. Names are compared
+// after Unicode case folding, using strings.EqualFold, and ignoring any trailing slash,
+// so that a directory colliding with a file is also reported. Within each group, names
+// appear in archive order; groups are ordered by the archive position of their first
+// name. Entries sharing exactly the same name are not reported.
+func FindCaseCollisions(archivePath string) ([][]string, error) {
+	var keys []string
+	groups := make(map[string][]string)
+	err := forEachEntry(archivePath, func(e *entry) error {
+		key := foldCase(strings.TrimSuffix(e.name, "/"))
+		group, ok := groups[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		if !slices.Contains(group, e.name) {
+			groups[key] = append(group, e.name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var collisions [][]string
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			collisions = append(collisions, groups[key])
+		}
+	}
+	return collisions, nil
+}
+
+// Default limits applied by FindOversizedPaths: those of Linux, whose paths may be up to
+// 4096 bytes (PATH_MAX) and whose path components may be up to 255 bytes (NAME_MAX), the
+// component limit of most other common file systems too.
+const (
+	DefaultMaxPathLen      = 4096
+	DefaultMaxComponentLen = 255
+)
+
+// FindOversizedPaths returns, in archive order, the names of the entries in the archive at
+// archivePath, whose type is determined by DetermineType, whose paths are longer than
+// maxPathLen bytes or have a component longer than maxComponentLen bytes. Extracting
+// such an entry fails partway through extraction, so checking beforehand lets an archive
+// be rejected, or its entries renamed, before anything is written. A limit of zero or
+// less is replaced by DefaultMaxPathLen or DefaultMaxComponentLen, as appropriate; on
+// Windows without long path support, for example, paths are limited to 260 characters.
+//
+// Lengths are measured in bytes of each entry's name in its canonical form, as produced
+// by path.Clean, without a trailing slash. The name is joined to the destination
+// directory on extraction, so to check the full path of each extracted file, reduce
+// maxPathLen by the length of the destination and its trailing separator.
+func FindOversizedPaths(archivePath string, maxPathLen, maxComponentLen int) ([]string, error) {
+	if maxPathLen <= 0 {
+		maxPathLen = DefaultMaxPathLen
+	}
+	if maxComponentLen <= 0 {
+		maxComponentLen = DefaultMaxComponentLen
+	}
+
+	var names []string
+	err := forEachEntry(archivePath, func(e *entry) error {
+		name := path.Clean(e.name)
+		oversized := len(name) > maxPathLen
+		for _, component := range strings.Split(name, "/") {
+			oversized = oversized || len(component) > maxComponentLen
+		}
+		if oversized {
+			names = append(names, e.name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// Returns a case-folded form of s such that two strings have the same form if
+// and only if strings.EqualFold reports them to be equal.
+func foldCase(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		// The smallest rune in a rune's case-folding orbit is a canonical representative.
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			folded = min(folded, f)
+		}
+		b.WriteRune(folded)
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"archive/tar"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type specialBitsTest struct {
+	mode                   int64
+	setuid, setgid, sticky bool
+}
+
+var specialBits = []specialBitsTest{
+	{0755, false, false, false},
+	{04755, true, false, false},
+	{02755, false, true, false},
+	{01777, false, false, true},
+	{07777, true, true, true},
+}
+
+func TestSpecialBits(t *testing.T) {
+	for _, c := range specialBits {
+		setuid, setgid, sticky := SpecialBits(&tar.Header{Mode: c.mode})
+		if setuid != c.setuid || setgid != c.setgid || sticky != c.sticky {
+			t.Errorf("%o: expecting '%t %t %t', got '%t %t %t'\n",
+				c.mode, c.setuid, c.setgid, c.sticky, setuid, setgid, sticky)
+		}
+	}
+}
+
+var specialBitsEntries = []testEntry{
+	{name: "bin/", mode: 0755},
+	{name: "bin/plain", body: "plain", mode: 0755},
+	{name: "bin/su", body: "su", mode: 04755},
+	{name: "bin/wall", body: "wall", mode: 02755},
+	{name: "tmp/", mode: 01777},
+}
+
+func TestFindSpecialBits(t *testing.T) {
+	expected := []string{"bin/su", "bin/wall", "tmp/"}
+
+	for _, filename := range []string{"special.tar", "special.tar.gz", "special.zip"} {
+		path := writeTestArchive(t, filename, specialBitsEntries)
+
+		names, err := FindSpecialBits(path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, expected, names)
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if names, err := FindSpecialBits(archivePath); len(names) != 0 || err != nil {
+			t.Errorf("Expecting no entries, got '%v' (error: %v)\n", names, err)
+		}
+	}
+
+	if _, err := FindSpecialBits("foo.123"); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+var caseCollisionEntries = []testEntry{
+	{name: "README.md", body: "one"},
+	{name: "docs/"},
+	{name: "Docs/guide.txt", body: "two"},
+	{name: "readme.md", body: "three"},
+	{name: "docs/Guide.TXT", body: "four"},
+	{name: "unique.txt", body: "five"},
+	{name: "Readme.MD", body: "six"},
+	{name: "DOCS", body: "seven"},
+	{name: "straße.txt", body: "eight"},
+	{name: "STRASSE.txt", body: "nine"},
+	{name: "ΣΊΣΥΦΟΣ.txt", body: "ten"},
+	{name: "σίσυφος.txt", body: "eleven"},
+}
+
+var expectedCaseCollisions = [][]string{
+	{"README.md", "readme.md", "Readme.MD"},
+	{"docs/", "DOCS"},
+	{"Docs/guide.txt", "docs/Guide.TXT"},
+	{"ΣΊΣΥΦΟΣ.txt", "σίσυφος.txt"},
+}
+
+func TestFindCaseCollisions(t *testing.T) {
+	for _, filename := range []string{"case.tar", "case.zip"} {
+		path := writeTestArchive(t, filename, caseCollisionEntries)
+
+		collisions, err := FindCaseCollisions(path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if !reflect.DeepEqual(collisions, expectedCaseCollisions) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, expectedCaseCollisions, collisions)
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if collisions, err := FindCaseCollisions(archivePath); len(collisions) != 0 || err != nil {
+			t.Errorf("Expecting no collisions, got '%v' (error: %v)\n", collisions, err)
+		}
+	}
+
+	if _, err := FindCaseCollisions("foo.123"); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestFindOversizedPaths(t *testing.T) {
+	long := strings.Repeat("a", 256)
+	entries := []testEntry{
+		{name: "dir/"},
+		{name: "dir/" + strings.Repeat("b", 255)},
+		{name: "dir/" + long + "/"},
+		{name: "dir/" + long + "/c.txt"},
+		{name: strings.Repeat("d/", 2048) + "e.txt"},
+		{name: "./" + strings.Repeat("f/", 2045) + "g.txt"},
+	}
+	path := writeTestArchive(t, "oversized.tar.gz", entries)
+
+	tests := []struct {
+		maxPathLen      int
+		maxComponentLen int
+		expected        []string
+	}{
+		{0, 0, []string{entries[2].name, entries[3].name, entries[4].name}},
+		{260, 0, []string{entries[2].name, entries[3].name, entries[4].name, entries[5].name}},
+		{0, 300, []string{entries[4].name}},
+		{260, 300, []string{entries[3].name, entries[4].name, entries[5].name}},
+	}
+	for _, test := range tests {
+		names, err := FindOversizedPaths(path, test.maxPathLen, test.maxComponentLen)
+		if !reflect.DeepEqual(names, test.expected) || err != nil {
+			t.Errorf("Limits (%d, %d): expecting %d names, got %d (error: %v)\n", test.maxPathLen, test.maxComponentLen, len(test.expected), len(names), err)
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if names, err := FindOversizedPaths(archivePath, 0, 0); names != nil || err != nil {
+			t.Errorf("Expecting no names, got '%v' (error: %v)\n", names, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WalkTarAuto walks the contents of the tar file at archivePath and invokes the callback
+// function for each entry. The file's compression is identified from its leading bytes
+// rather than its name: gzip, bzip2, and xz are recognised by their magic bytes, and
+// anything else is read as an uncompressed tar file.
+func WalkTarAuto(archivePath string, callback TarCallback) error {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	return WalkTarAutoReader(file, callback)
+}
+
+// WalkTarAutoReader walks the contents of a tar stream read from r and invokes the
+// callback function for each entry. As for WalkTarAuto, the stream's compression is
+// identified from its leading bytes, which are peeked from a buffer so that none are
+// lost to the decompressor; the reader need not support seeking, making this suitable
+// for network streams and pipes whose compression is not known in advance.
+func WalkTarAutoReader(r io.Reader, callback TarCallback) error {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(magicLen)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf(fmtErrReadMagic, err)
+	}
+
+	typ, ok := tarTypeFromCompression(detectCompression(magic))
+	if !ok {
+		typ = Tar
+	}
+
+	stream, err := newTarStream(buffered, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return readTarWrapping(tar.NewReader(stream), callback)
+}
+
+// Returns the tar-family archive type compressed with the given compression
+// format, reporting whether the stream is compressed.
+func tarTypeFromCompression(c compression) (Type, bool) {
+	switch c {
+	case gzipCompression:
+		return TarGz, true
+	case bzip2Compression:
+		return TarBz2, true
+	case xzCompression:
+		return TarXz, true
+	}
+	return 0, false
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"archive/tar"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWalkTarAuto(t *testing.T) {
+	for _, archivePath := range sampleArchives[:4] {
+		path := copyToTemp(t, archivePath, "download")
+
+		var names []string
+		err := WalkTarAuto(path, func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if len(names) != len(sampleEntries) {
+			t.Errorf("%s: expecting '%d', got '%d'\n", archivePath, len(sampleEntries), len(names))
+		}
+	}
+
+	if err := WalkTarAuto("testdata/sample.zip", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a zip file.")
+	}
+
+	if err := WalkTarAuto("nonexistent", nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestWalkTarAutoReader(t *testing.T) {
+	for _, archivePath := range sampleArchives[:4] {
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Deliver the stream a byte at a time to ensure that no peeked bytes are lost.
+		var names []string
+		err = WalkTarAutoReader(iotest.OneByteReader(strings.NewReader(string(data))), func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if len(names) != len(sampleEntries) {
+			t.Errorf("%s: expecting '%d', got '%d'\n", archivePath, len(sampleEntries), len(names))
+		}
+	}
+
+	if err := WalkTarAutoReader(strings.NewReader(""), nil); err != nil {
+		t.Errorf("Unexpected error for an empty stream: %v\n", err)
+	}
+
+	if err := WalkTarAutoReader(iotest.ErrReader(iotest.ErrTimeout), nil); err == nil {
+		t.Error("Failed to receive non-nil error for a failing reader.")
+	}
+}
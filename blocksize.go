@@ -0,0 +1,61 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Format strings for errors concerning the record size of tar data.
+const (
+	fmtErrBlockSize  = "archive: invalid tar block size %d: must be a positive multiple of 512"
+	fmtErrRecordSize = "archive: tar data of %d bytes does not end on a %d-byte record boundary, falling %d bytes short of a full record"
+)
+
+// WalkTarWithBlockSize walks the contents of the tar-family archive at path, whose type is
+// determined by DetermineType, as WalkTar and its compressed counterparts do, but reads the
+// tar data in records of blockSize bytes, as tape drives and some tar implementations
+// require. The block size, the product of a tar writer's blocking factor and the 512-byte
+// tar block, must be a positive multiple of 512; GNU tar, for example, writes 10240-byte
+// records by default.
+//
+// Headers and contents are laid out in 512-byte blocks whatever the record size, so any
+// tar data can be walked; the record size only determines how the end of the archive is
+// padded. Once every entry has been visited, the remainder of the data is therefore read
+// and checked to end on a record boundary. If it does not, an error is returned naming
+// the length of the data and how far short of a full record it falls, which helps
+// diagnose archives written by tar implementations with unexpected blocking factors, or
+// truncated partway through their final record. For a compressed archive, the
+// decompressed tar data is checked. Zip archives are not supported.
+func WalkTarWithBlockSize(path string, blockSize int, callback TarCallback) error {
+	if blockSize <= 0 || blockSize%tarBlockSize != 0 {
+		return fmt.Errorf(fmtErrBlockSize, blockSize)
+	}
+
+	typ, err := DetermineType(path)
+	if err != nil {
+		return err
+	}
+
+	stream, err := openTarStream(path, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	counter := &countingReader{reader: stream}
+	records := bufio.NewReaderSize(counter, blockSize)
+	if err := readTarWrapping(tar.NewReader(records), callback); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(io.Discard, records); err != nil {
+		return fmt.Errorf(fmtErrTarReadFailed, err)
+	}
+
+	if rem := counter.n % int64(blockSize); rem != 0 {
+		return fmt.Errorf(fmtErrRecordSize, counter.n, blockSize, int64(blockSize)-rem)
+	}
+	return nil
+}
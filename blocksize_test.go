@@ -0,0 +1,48 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalkTarWithBlockSize(t *testing.T) {
+	for _, blockSize := range []int{512, 1024, 10240} {
+		var names []string
+		err := WalkTarWithBlockSize("testdata/sample.tar.gz", blockSize, func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			return nil
+		})
+		if err != nil || len(names) != 3 {
+			t.Errorf("Expecting 3 entries, got %d (error: %v)\n", len(names), err)
+		}
+	}
+
+	// sample.tar ends after its end-of-archive marker, without padding to a full record.
+	if err := WalkTarWithBlockSize("testdata/sample.tar", 512, nil); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	err := WalkTarWithBlockSize("testdata/sample.tar", 10240, nil)
+	if err == nil || !strings.Contains(err.Error(), "3584 bytes") || !strings.Contains(err.Error(), "6656 bytes short") {
+		t.Errorf("Expecting a record size error, got '%v'\n", err)
+	}
+
+	for _, blockSize := range []int{0, -512, 1000} {
+		if err := WalkTarWithBlockSize("testdata/sample.tar", blockSize, nil); err == nil {
+			t.Errorf("Failed to receive non-nil error for block size %d.\n", blockSize)
+		}
+	}
+
+	errStop := errors.New("stop")
+	err = WalkTarWithBlockSize("testdata/sample.tar.xz", 10240, func(reader *tar.Reader, header *tar.Header) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	if err := WalkTarWithBlockSize("testdata/sample.zip", 512, nil); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+}
@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+)
+
+// fmtErrChunkSize is the format of the error returned by WalkTarChunked for a
+// chunk size that is not positive.
+const fmtErrChunkSize = "archive: invalid chunk size %d: must be positive"
+
+// ChunkCallback is the type of function called for each chunk of an entry's contents
+// visited by WalkTarChunked, along with the name of the entry and whether the chunk is
+// the entry's last.
+type ChunkCallback func(name string, chunk []byte, last bool) error
+
+// WalkTarChunked walks the contents of the tar-family archive at path, whose type is
+// determined by DetermineType, and streams the decompressed contents of every regular
+// file entry to the callback in chunks of chunkSize bytes, the final chunk of each entry,
+// which may be shorter, being flagged as last. This suits forwarding extracted content to
+// another system, as in a streaming upload, without buffering whole files. An empty
+// file is passed as a single empty chunk flagged as last, so that every file is seen.
+// Entries other than regular files, including hard links, are skipped. Zip archives
+// are not supported.
+//
+// A single buffer is reused for every chunk, so the chunk passed to the callback is
+// only valid until the callback returns; a callback that retains it must copy it.
+func WalkTarChunked(path string, chunkSize int, callback ChunkCallback) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf(fmtErrChunkSize, chunkSize)
+	}
+
+	typ, err := DetermineType(path)
+	if err != nil {
+		return err
+	}
+
+	stream, err := openTarStream(path, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	buf := make([]byte, chunkSize)
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		// Hard links, which header.FileInfo reports as regular files, have no
+		// content of their own.
+		if !header.FileInfo().Mode().IsRegular() || header.Typeflag == tar.TypeLink || callback == nil {
+			continue
+		}
+
+		if err := readChunks(header, reader, buf, callback); err != nil {
+			return err
+		}
+	}
+}
+
+// Invokes the callback for each chunk of the entry's contents read from reader
+// into buf, wrapping only the errors returned by the callback.
+func readChunks(header *tar.Header, reader io.Reader, buf []byte, callback ChunkCallback) error {
+	remaining := header.Size
+	for {
+		n := int(min(int64(len(buf)), remaining))
+		if _, err := io.ReadFull(reader, buf[:n]); err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+		remaining -= int64(n)
+
+		if err := callback(header.Name, buf[:n], remaining == 0); err != nil {
+			return fmt.Errorf(fmtErrTarCallbackFailed, err)
+		}
+		if remaining == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+var chunkedEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: strings.Repeat("0123456789", 10)},
+	{name: "dir/empty.txt"},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+	{name: "dir/hard", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+	{name: "dir/b.txt", body: "lorem"},
+}
+
+func TestWalkTarChunked(t *testing.T) {
+	path := writeTestArchive(t, "chunked.tar.gz", chunkedEntries)
+
+	contents := make(map[string]*bytes.Buffer)
+	var sizes []int
+	var lasts []bool
+	var first []byte
+	err := WalkTarChunked(path, 32, func(name string, chunk []byte, last bool) error {
+		if first == nil {
+			first = chunk
+		} else if len(chunk) > 0 && &chunk[0] != &first[0] {
+			t.Error("Expected the chunk buffer to be reused.")
+		}
+
+		if contents[name] == nil {
+			contents[name] = new(bytes.Buffer)
+		}
+		contents[name].Write(chunk)
+		sizes = append(sizes, len(chunk))
+		lasts = append(lasts, last)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	expectedSizes := []int{32, 32, 32, 4, 0, 5}
+	expectedLasts := []bool{false, false, false, true, true, true}
+	for i := range expectedSizes {
+		if i >= len(sizes) || sizes[i] != expectedSizes[i] || lasts[i] != expectedLasts[i] {
+			t.Fatalf("Expecting sizes '%v' and lasts '%v', got '%v' and '%v'\n", expectedSizes, expectedLasts, sizes, lasts)
+		}
+	}
+
+	for _, e := range chunkedEntries {
+		if e.typeflag != 0 || strings.HasSuffix(e.name, "/") {
+			if contents[e.name] != nil {
+				t.Errorf("Unexpected chunks for %s\n", e.name)
+			}
+			continue
+		}
+		if contents[e.name] == nil || contents[e.name].String() != e.body {
+			t.Errorf("%s: expecting '%s', got '%v'\n", e.name, e.body, contents[e.name])
+		}
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = WalkTarChunked(path, 32, func(name string, chunk []byte, last bool) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("Expecting '%s' after 1 call, got '%v' after %d\n", errStop, err, calls)
+	}
+
+	// Failures to read an entry's contents are reported as such, not as errors
+	// returned by the callback.
+	truncated := writeTestArchive(t, "truncated.tar", chunkedEntries)
+	if err := os.Truncate(truncated, 2*tarBlockSize+50); err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	err = WalkTarChunked(truncated, 32, func(name string, chunk []byte, last bool) error {
+		calls++
+		return nil
+	})
+	if err == nil || errors.Is(err, io.ErrUnexpectedEOF) || calls != 1 {
+		t.Errorf("Expecting a read error after 1 call, got '%v' after %d\n", err, calls)
+	}
+
+	if err := WalkTarChunked(path, 0, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a chunk size of zero.")
+	}
+
+	if err := WalkTarChunked("testdata/sample.zip", 32, nil); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+}
@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Format strings for compression-related errors
+const (
+	fmtErrDecompress string = "archive: failed to decompress: %v"
+	fmtErrReadMagic  string = "archive: failed to read magic bytes: %v"
+)
+
+// errUnknownCompression is returned when a file's compression cannot be
+// identified from its magic bytes.
+var errUnknownCompression = errors.New("archive: unable to determine compression")
+
+// compression identifies a stream compression format.
+type compression uint
+
+// Stream compression formats recognized by their magic bytes.
+const (
+	noCompression compression = iota
+	gzipCompression
+	bzip2Compression
+	xzCompression
+)
+
+// Magic bytes at the start of compressed streams.
+var (
+	gzipMagic  = []byte{gzipID1, gzipID2}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// magicLen is the number of leading bytes needed to recognize any supported compression.
+const magicLen = 6
+
+// Identifies the compression format from the leading bytes of a stream.
+func detectCompression(magic []byte) compression {
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzipCompression
+	case bytes.HasPrefix(magic, xzMagic):
+		return xzCompression
+	case len(magic) > len(bzip2Magic) && bytes.HasPrefix(magic, bzip2Magic) &&
+		magic[len(bzip2Magic)] >= '1' && magic[len(bzip2Magic)] <= '9':
+		// The magic is followed by the block size, as a digit from 1 to 9.
+		return bzip2Compression
+	}
+
+	return noCompression
+}
+
+// UncompressedSize returns the uncompressed size of the single gzip-, bzip2-, or
+// xz-compressed file at path, whose compression is identified by its magic bytes rather
+// than its extension. For gzip, the size is read cheaply from the ISIZE field of the
+// file's trailer; other formats carry no such field and are decompressed in full to
+// count their bytes.
+//
+// The gzip ISIZE field holds the uncompressed size modulo 2^32, so the result for gzip is
+// only accurate for data smaller than 4 GiB. For multi-member gzip files, only the size
+// of the final member is reported.
+func UncompressedSize(path string) (int64, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, magicLen)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return 0, fmt.Errorf(fmtErrReadMagic, err)
+	}
+
+	var reader io.Reader
+	switch detectCompression(magic) {
+	case gzipCompression:
+		return gzipISize(file)
+	case bzip2Compression:
+		reader = bzip2.NewReader(io.MultiReader(bytes.NewReader(magic), file))
+	case xzCompression:
+		if reader, err = xz.NewReader(io.MultiReader(bytes.NewReader(magic), file)); err != nil {
+			return 0, fmt.Errorf(fmtErrNewXzReader, err)
+		}
+	default:
+		return 0, errUnknownCompression
+	}
+
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrDecompress, err)
+	}
+	return n, nil
+}
+
+// Reads the ISIZE field from the trailer of a gzip file.
+func gzipISize(file *os.File) (int64, error) {
+	trailer := make([]byte, 4)
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	if _, err := file.ReadAt(trailer, info.Size()-int64(len(trailer))); err != nil {
+		return 0, fmt.Errorf(fmtErrDecompress, err)
+	}
+
+	return int64(binary.LittleEndian.Uint32(trailer)), nil
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"compress/gzip"
+	"testing"
+)
+
+type uncompressedSizeTest struct {
+	path     string
+	expected int64
+}
+
+var uncompressedSizes = []uncompressedSizeTest{
+	{"testdata/sample.tar.gz", 10240},
+	{"testdata/sample.tar.bz2", 10240},
+	{"testdata/sample.tar.xz", 10240},
+}
+
+func TestUncompressedSize(t *testing.T) {
+	for _, c := range uncompressedSizes {
+		result, err := UncompressedSize(c.path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", c.path, err)
+		}
+
+		if result != c.expected {
+			t.Errorf("Expecting '%d', got '%d'\n", c.expected, result)
+		}
+	}
+
+	path := writeGzipFile(t, gzip.DefaultCompression, make([]byte, 12345))
+	if result, err := UncompressedSize(path); err != nil || result != 12345 {
+		t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 12345, result, err)
+	}
+
+	for _, path := range []string{"testdata/sample.tar", "testdata/sample.zip"} {
+		if _, err := UncompressedSize(path); err != errUnknownCompression {
+			t.Errorf("Expecting '%s', got '%v'\n", errUnknownCompression, err)
+		}
+	}
+
+	if _, err := UncompressedSize("nonexistent.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+type compressionTest struct {
+	magic    []byte
+	expected compression
+}
+
+var compressions = []compressionTest{
+	{[]byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00}, gzipCompression},
+	{[]byte("BZh91AY"), bzip2Compression},
+	{[]byte("BZh0"), noCompression},
+	{[]byte("BZh"), noCompression},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, xzCompression},
+	{[]byte("PK\x03\x04"), noCompression},
+	{nil, noCompression},
+}
+
+func TestDetectCompression(t *testing.T) {
+	for _, c := range compressions {
+		if result := detectCompression(c.magic); result != c.expected {
+			t.Errorf("Expecting '%d', got '%d'\n", c.expected, result)
+		}
+	}
+}
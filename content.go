@@ -0,0 +1,295 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"crypto"
+	_ "crypto/sha256" // registers crypto.SHA256, used by Equal
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ContentReader returns a reader that streams the concatenated contents of every
+// regular file in the archive at archivePath, whose type is determined by DetermineType.
+// Contents are produced in archive order: storage order for tar-family archives and
+// central directory order for zip archives. Directories, symbolic links, and other
+// non-regular entries contribute nothing to the stream. The caller must close the
+// returned reader to release the underlying archive.
+func ContentReader(archivePath string) (io.ReadCloser, error) {
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contentReader{cursor: c}, nil
+}
+
+// Struct contentReader reads the regular file entries of an archive
+// one after another.
+type contentReader struct {
+	cursor  *cursor
+	current io.ReadCloser
+}
+
+// Read reads from the current regular file entry, advancing to the next one
+// once it is exhausted.
+func (r *contentReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			e, err := r.cursor.next()
+			if err != nil {
+				return 0, err
+			}
+			if !e.mode.IsRegular() {
+				continue
+			}
+			if r.current, err = e.Open(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			err = r.current.Close()
+			r.current = nil
+			if err == nil && n == 0 {
+				continue
+			}
+		}
+		return n, err
+	}
+}
+
+// Close closes the current entry, if any, and the underlying archive.
+func (r *contentReader) Close() error {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+	return r.cursor.Close()
+}
+
+// errHashUnavailable is returned by ContentHash for a hash function that is not
+// linked into the binary.
+var errHashUnavailable = errors.New("archive: hash function unavailable")
+
+// Kinds of entry distinguished by ContentHash.
+const (
+	contentKindFile    = 'f'
+	contentKindDir     = 'd'
+	contentKindSymlink = 'l'
+	contentKindOther   = 'o'
+)
+
+// Struct contentRecord describes a single entry hashed by ContentHash.
+type contentRecord struct {
+	kind   byte
+	name   string
+	size   int64
+	digest []byte
+}
+
+// ContentHash returns a digest of the contents of the archive at archivePath, whose type
+// is determined by DetermineType, computed with the hash function algo, which must be
+// linked into the binary, as by importing crypto/sha256 for crypto.SHA256. The digest
+// depends only on what the archive holds, not on its format, compression, or the order
+// of its entries, so two archives with identical contents, such as a .tar.xz and a .zip
+// made from the same directory, yield the same digest. This suits deduplication and
+// reproducibility checks across formats.
+//
+// Every entry contributes a record consisting of its kind, its cleaned name, its size,
+// and the digest, computed with algo, of its contents. Regular files are hashed by their
+// contents and hard links by the contents of their targets, so a hard link and a copy of
+// the same file are indistinguishable; symbolic links are hashed by their targets;
+// directories and other entries have no contents. The records are sorted by name and
+// hashed in a length-prefixed serialization, in the manner of a Merkle tree with a
+// single level. Modification times, permissions, and ownership are not included.
+func ContentHash(archivePath string, algo crypto.Hash) ([]byte, error) {
+	if !algo.Available() {
+		return nil, errHashUnavailable
+	}
+
+	var records []contentRecord
+	err := forEachContentRecord(archivePath, algo, func(record contentRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(records, func(a, b contentRecord) int {
+		return cmp.Or(strings.Compare(a.name, b.name), cmp.Compare(a.kind, b.kind),
+			cmp.Compare(a.size, b.size), bytes.Compare(a.digest, b.digest))
+	})
+
+	hash := algo.New()
+	buf := make([]byte, 0, binary.MaxVarintLen64+1)
+	for _, record := range records {
+		buf = append(buf[:0], record.kind)
+		buf = binary.AppendUvarint(buf, uint64(len(record.name)))
+		hash.Write(buf)
+		hash.Write([]byte(record.name))
+		hash.Write(binary.BigEndian.AppendUint64(buf[:0], uint64(record.size)))
+		hash.Write(record.digest)
+	}
+	return hash.Sum(nil), nil
+}
+
+// Checksums returns the digest, computed with the hash function algo, of the contents of
+// every regular file in the archive at archivePath, whose type is determined by
+// DetermineType, keyed by entry name, in a single pass over the archive. As with
+// ContentHash, algo must be linked into the binary. Hard links are included under their
+// own names with the digest of their targets' contents; directories, symbolic links, and
+// other entries are omitted. If more than one file has the same name, the digest of the
+// last is returned. The result is a manifest that can be compared directly against
+// another, such as one computed from a directory on disk, to verify an extraction.
+func Checksums(archivePath string, algo crypto.Hash) (map[string][]byte, error) {
+	if !algo.Available() {
+		return nil, errHashUnavailable
+	}
+
+	sums := make(map[string][]byte)
+	files := make(map[string][]byte) // digests by cleaned name, for resolving hard links
+	err := forEachEntry(archivePath, func(e *entry) error {
+		if e.header != nil && e.header.Typeflag == tar.TypeLink {
+			sum, ok := files[path.Clean(e.header.Linkname)]
+			if !ok {
+				return fmt.Errorf("%w: %q", errLinkTargetNotFound, e.header.Linkname)
+			}
+			sums[e.name] = sum
+			return nil
+		} else if !e.mode.IsRegular() {
+			return nil
+		}
+
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		hash := algo.New()
+		if _, err := io.Copy(hash, reader); err != nil {
+			return err
+		}
+		sums[e.name] = hash.Sum(nil)
+		files[path.Clean(e.name)] = sums[e.name]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sums, nil
+}
+
+// errRecordsDiffer stops the walk of the second archive compared by Equal at the
+// first entry not matched in the first.
+var errRecordsDiffer = errors.New("archive: archive contents differ")
+
+// Equal reports whether the archives at archiveA and archiveB, whose types are determined
+// by DetermineType, hold the same contents, regardless of their formats, compression, the
+// order of their entries, or metadata such as modification times and permissions, which
+// answers whether repacking an archive changed anything. Contents are compared as by
+// ContentHash, with each entry's kind, cleaned name, size, and SHA-256 digest of its
+// contents, so Equal reports true exactly when ContentHash would yield the same digest
+// for both archives.
+//
+// Every entry of archiveA is hashed first; archiveB is then read only as far as its
+// first entry that archiveA lacks or holds with different contents, so archives that
+// differ early are told apart without reading the rest of archiveB.
+func Equal(archiveA, archiveB string) (bool, error) {
+	remaining := make(map[string]int) // occurrences of each record of archiveA not yet matched
+	unmatched := 0
+	err := forEachContentRecord(archiveA, crypto.SHA256, func(record contentRecord) error {
+		remaining[record.key()]++
+		unmatched++
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	err = forEachContentRecord(archiveB, crypto.SHA256, func(record contentRecord) error {
+		key := record.key()
+		if remaining[key] == 0 {
+			return errRecordsDiffer
+		}
+		remaining[key]--
+		unmatched--
+		return nil
+	})
+	if err == errRecordsDiffer {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return unmatched == 0, nil
+}
+
+// Returns a string that identifies the record's contents.
+func (r contentRecord) key() string {
+	return fmt.Sprintf("%c%d:%s:%d:%x", r.kind, len(r.name), r.name, r.size, r.digest)
+}
+
+// Invokes fn with the record of each entry of the archive at archivePath, computed as
+// ContentHash describes, in archive order, stopping at the first error returned by fn.
+func forEachContentRecord(archivePath string, algo crypto.Hash, fn func(record contentRecord) error) error {
+	files := make(map[string]contentRecord)
+	return forEachEntry(archivePath, func(e *entry) error {
+		record := contentRecord{kind: contentKindOther, name: path.Clean(e.name)}
+
+		switch {
+		case e.header != nil && e.header.Typeflag == tar.TypeLink:
+			target, ok := files[path.Clean(e.header.Linkname)]
+			if !ok {
+				return fmt.Errorf("%w: %q", errLinkTargetNotFound, e.header.Linkname)
+			}
+			record.kind, record.size, record.digest = contentKindFile, target.size, target.digest
+		case e.mode.IsDir():
+			record.kind = contentKindDir
+		case e.mode&fs.ModeSymlink != 0:
+			linkname, err := symlinkTarget(e)
+			if err != nil {
+				return err
+			}
+			record.kind, record.size = contentKindSymlink, int64(len(linkname))
+			record.digest = digest(algo, []byte(linkname))
+		case e.mode.IsRegular():
+			reader, err := e.Open()
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			hash := algo.New()
+			n, err := io.Copy(hash, reader)
+			if err != nil {
+				return err
+			}
+			record.kind, record.size, record.digest = contentKindFile, n, hash.Sum(nil)
+			files[record.name] = record
+		}
+
+		if record.digest == nil {
+			record.digest = digest(algo, nil)
+		}
+		return fn(record)
+	})
+}
+
+// Returns the digest of data computed with algo.
+func digest(algo crypto.Hash, data []byte) []byte {
+	hash := algo.New()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
@@ -0,0 +1,217 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto"
+	"crypto/md5"
+	"crypto/sha256"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var contentEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "lorem "},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+	{name: "dir/empty.txt"},
+	{name: "b.txt", body: "ipsum "},
+	{name: "c.txt", body: "dolor"},
+}
+
+func TestContentReader(t *testing.T) {
+	for _, filename := range []string{"content.tar", "content.tar.xz", "content.zip"} {
+		path := writeTestArchive(t, filename, contentEntries)
+
+		reader, err := ContentReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Errorf("Unexpected error reading %s: %v\n", filename, err)
+		}
+		if string(content) != "lorem ipsum dolor" {
+			t.Errorf("Expecting '%s', got '%s'\n", "lorem ipsum dolor", content)
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Errorf("Unexpected error closing %s: %v\n", filename, err)
+		}
+	}
+}
+
+func TestContentReader_sample(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		reader, err := ContentReader(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := io.Copy(io.Discard, reader)
+		if err != nil {
+			t.Errorf("Unexpected error reading %s: %v\n", archivePath, err)
+		}
+		if n != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, n)
+		}
+		reader.Close()
+	}
+
+	reader, err := ContentReader("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Read(make([]byte, 10)); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("Unexpected error closing a partially read reader: %v\n", err)
+	}
+
+	if _, err := ContentReader("nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+
+	if _, err := ContentReader("foo.123"); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	sampleHash, err := ContentHash(sampleArchives[0], crypto.SHA256)
+	if err != nil || len(sampleHash) != sha256.Size {
+		t.Fatalf("Expecting a %d-byte digest, got %d bytes (error: %v)\n", sha256.Size, len(sampleHash), err)
+	}
+	for _, archivePath := range sampleArchives[1:] {
+		if hash, err := ContentHash(archivePath, crypto.SHA256); !bytes.Equal(hash, sampleHash) || err != nil {
+			t.Errorf("%s: expecting '%x', got '%x' (error: %v)\n", archivePath, sampleHash, hash, err)
+		}
+	}
+
+	reversed := slices.Clone(contentEntries)
+	slices.Reverse(reversed)
+	expected, err := ContentHash(writeTestArchive(t, "content.tar", contentEntries), crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{"content.tar.xz", "content.zip"} {
+		for _, entries := range [][]testEntry{contentEntries, reversed} {
+			path := writeTestArchive(t, filename, entries)
+			if hash, err := ContentHash(path, crypto.SHA256); !bytes.Equal(hash, expected) || err != nil {
+				t.Errorf("%s: expecting '%x', got '%x' (error: %v)\n", filename, expected, hash, err)
+			}
+		}
+	}
+
+	// A hard link hashes as a copy of its target.
+	linked := append(slices.Clone(contentEntries), testEntry{name: "d.txt", typeflag: tar.TypeLink, linkname: "dir/a.txt"})
+	copied := append(slices.Clone(contentEntries), testEntry{name: "d.txt", body: "lorem "})
+	linkedHash, _ := ContentHash(writeTestArchive(t, "linked.tar", linked), crypto.SHA256)
+	copiedHash, _ := ContentHash(writeTestArchive(t, "copied.zip", copied), crypto.SHA256)
+	if !bytes.Equal(linkedHash, copiedHash) || bytes.Equal(linkedHash, expected) {
+		t.Errorf("Expecting '%x' to equal '%x' and differ from '%x'\n", linkedHash, copiedHash, expected)
+	}
+
+	changed := slices.Clone(contentEntries)
+	changed[4].body = "IPSUM "
+	if hash, _ := ContentHash(writeTestArchive(t, "changed.tar", changed), crypto.SHA256); bytes.Equal(hash, expected) {
+		t.Error("Expected a change in content to change the digest.")
+	}
+
+	renamed := slices.Clone(contentEntries)
+	renamed[5].name = "d.txt"
+	if hash, _ := ContentHash(writeTestArchive(t, "renamed.tar", renamed), crypto.SHA256); bytes.Equal(hash, expected) {
+		t.Error("Expected a change in name to change the digest.")
+	}
+
+	if _, err := ContentHash(sampleArchives[0], crypto.Hash(0)); err != errHashUnavailable {
+		t.Errorf("Expecting '%s', got '%v'\n", errHashUnavailable, err)
+	}
+
+	if _, err := ContentHash("nonexistent.tar.gz", crypto.SHA256); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	sum := func(s string) []byte {
+		digest := md5.Sum([]byte(s))
+		return digest[:]
+	}
+	expected := map[string][]byte{
+		"dir/a.txt":     sum("lorem "),
+		"dir/empty.txt": sum(""),
+		"b.txt":         sum("ipsum "),
+		"c.txt":         sum("dolor"),
+	}
+
+	for _, filename := range []string{"checksums.tar.gz", "checksums.zip"} {
+		sums, err := Checksums(writeTestArchive(t, filename, contentEntries), crypto.MD5)
+		if !reflect.DeepEqual(sums, expected) || err != nil {
+			t.Errorf("%s: expecting '%x', got '%x' (error: %v)\n", filename, expected, sums, err)
+		}
+	}
+
+	// A hard link is listed with the digest of its target.
+	linked := append(slices.Clone(contentEntries), testEntry{name: "d.txt", typeflag: tar.TypeLink, linkname: "./dir/a.txt"})
+	expected["d.txt"] = sum("lorem ")
+	if sums, err := Checksums(writeTestArchive(t, "linked.tar", linked), crypto.MD5); !reflect.DeepEqual(sums, expected) || err != nil {
+		t.Errorf("Expecting '%x', got '%x' (error: %v)\n", expected, sums, err)
+	}
+
+	if _, err := Checksums(sampleArchives[0], crypto.Hash(0)); err != errHashUnavailable {
+		t.Errorf("Expecting '%s', got '%v'\n", errHashUnavailable, err)
+	}
+
+	if _, err := Checksums("nonexistent.tar.gz", crypto.MD5); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	reversed := slices.Clone(contentEntries)
+	slices.Reverse(reversed)
+	retimed := slices.Clone(contentEntries)
+	retimed[1].modTime = testModTime.AddDate(1, 0, 0)
+	changed := slices.Clone(contentEntries)
+	changed[4].body = "IPSUM "
+	missing := slices.Clone(contentEntries[:5])
+	extra := append(slices.Clone(contentEntries), testEntry{name: "d.txt", body: "sit"})
+
+	original := writeTestArchive(t, "original.tar.gz", contentEntries)
+	tests := []struct {
+		filename string
+		entries  []testEntry
+		expected bool
+	}{
+		{"same.tar", contentEntries, true},
+		{"reversed.zip", reversed, true},
+		{"retimed.tar.xz", retimed, true},
+		{"changed.zip", changed, false},
+		{"missing.tar", missing, false},
+		{"extra.tar", extra, false},
+	}
+	for _, test := range tests {
+		path := writeTestArchive(t, test.filename, test.entries)
+		if equal, err := Equal(original, path); equal != test.expected || err != nil {
+			t.Errorf("%s: expecting %t, got %t (error: %v)\n", test.filename, test.expected, equal, err)
+		}
+		if equal, err := Equal(path, original); equal != test.expected || err != nil {
+			t.Errorf("%s (swapped): expecting %t, got %t (error: %v)\n", test.filename, test.expected, equal, err)
+		}
+	}
+
+	for _, archivePath := range sampleArchives[1:] {
+		if equal, err := Equal(sampleArchives[0], archivePath); !equal || err != nil {
+			t.Errorf("%s: expecting true, got %t (error: %v)\n", archivePath, equal, err)
+		}
+	}
+
+	if _, err := Equal(original, "nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
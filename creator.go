@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Descriptions of the host systems recorded in the upper byte of a zip entry's
+// "version made by" field (APPNOTE.TXT, section 4.4.2).
+var zipHostSystems = map[uint8]string{
+	0: "MS-DOS", 1: "Amiga", 2: "OpenVMS", 3: "Unix", 4: "VM/CMS", 5: "Atari ST",
+	6: "OS/2", 7: "Macintosh", 8: "Z-System", 9: "CP/M", 10: "Windows NTFS", 11: "MVS",
+	12: "VSE", 13: "Acorn RISC OS", 14: "VFAT", 15: "MVS", 16: "BeOS", 17: "Tandem",
+	18: "OS/400", 19: "macOS",
+}
+
+// Descriptions of the operating systems recorded in a gzip header's OS byte (RFC 1952).
+var gzipOperatingSystems = map[byte]string{
+	0: "FAT", 1: "Amiga", 2: "VMS", 3: "Unix", 4: "VM/CMS", 5: "Atari TOS", 6: "HPFS",
+	7: "Macintosh", 8: "Z-System", 9: "CP/M", 10: "TOPS-20", 11: "NTFS", 12: "QDOS",
+	13: "Acorn RISC OS",
+}
+
+// Magic values identifying the variant of a tar header, found at offset 257.
+var (
+	gnuTarMagic   = []byte("ustar  \x00")
+	ustarTarMagic = []byte("ustar\x00")
+)
+
+// The description returned by CreatorInfo when an archive carries no hint of its creator.
+const unknownCreator = "unknown"
+
+// CreatorInfo returns a best-effort description of the tool or system that created the
+// archive at path, whose type is determined by DetermineType, as recorded in its metadata.
+// For a zip archive, the "version made by" field of the first entry yields the zip
+// specification version and host system, as in "Zip 2.0 (MS-DOS)"; archives whose
+// entries carry Info-ZIP's Unix owner extra fields are reported as "Info-ZIP", as in
+// "Info-ZIP 3.0 (Unix)". For a tar-family archive, the magic of the first header
+// distinguishes "GNU tar" from "POSIX ustar tar" and "POSIX pax tar", and the operating
+// system recorded in a gzip header is appended, as in "GNU tar; gzip (Unix)". The
+// description is "unknown" when the format carries no such hint, such as an empty zip
+// archive or a pre-POSIX tar archive. The metadata is easily forged, so the result
+// should inform forensic or compatibility analysis rather than security decisions.
+func CreatorInfo(path string) (string, error) {
+	typ, err := DetermineType(path)
+	if err != nil {
+		return "", err
+	}
+
+	if typ == Zip {
+		return zipCreatorInfo(path)
+	}
+	return tarCreatorInfo(path, typ)
+}
+
+// Returns the creator description of the zip archive at path.
+func zipCreatorInfo(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	if len(r.File) == 0 {
+		return unknownCreator, nil
+	}
+
+	f := r.File[0]
+	tool := "Zip"
+	eachExtraField(f.Extra, func(id uint16, _ []byte) bool {
+		if id == unixOwnerExtraID || id == infoZipUnixExtraID {
+			tool = "Info-ZIP"
+			return false
+		}
+		return true
+	})
+
+	version := f.CreatorVersion & 0xff
+	desc := fmt.Sprintf("%s %d.%d", tool, version/10, version%10)
+	if host, ok := zipHostSystems[uint8(f.CreatorVersion>>8)]; ok {
+		desc += fmt.Sprintf(" (%s)", host)
+	}
+	return desc, nil
+}
+
+// Returns the creator description of the tar-family archive of the given type at path.
+func tarCreatorInfo(path string, typ Type) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	var gzipOS string
+	var stream io.ReadCloser
+	if typ == TarGz {
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return "", fmt.Errorf(fmtErrNewGzReader, err)
+		}
+		gzipOS = gzipOperatingSystems[reader.OS]
+		stream = reader
+	} else if stream, err = newTarStream(file, typ); err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	block := make([]byte, tarBlockSize)
+	if _, err := io.ReadFull(stream, block); err != nil {
+		return "", fmt.Errorf(fmtErrTarReadFailed, err)
+	}
+
+	var desc string
+	switch magic := block[257:265]; {
+	case bytes.Equal(magic, gnuTarMagic):
+		desc = "GNU tar"
+	case bytes.HasPrefix(magic, ustarTarMagic):
+		desc = "POSIX ustar tar"
+		if flag := block[156]; flag == tar.TypeXHeader || flag == tar.TypeXGlobalHeader {
+			desc = "POSIX pax tar"
+		}
+	}
+
+	if gzipOS != "" {
+		if desc != "" {
+			desc += "; "
+		}
+		desc += fmt.Sprintf("gzip (%s)", gzipOS)
+	}
+
+	if desc == "" {
+		return unknownCreator, nil
+	}
+	return desc, nil
+}
+
+// MinZipVersion returns the highest "version needed to extract" recorded in the central
+// directory of the zip archive at path, which is the minimum version of the zip
+// specification that a reader must support to extract every entry. As in the field
+// itself, the version is multiplied by ten, so that 20 indicates version 2.0, required
+// for deflate compression and directories, 45 version 4.5, required for zip64, and 63
+// version 6.3, required for AES encryption and LZMA compression among others. Tools
+// distributing archives to older consumers can use it to confirm that the archive
+// requires no unsupported features. The upper byte of the field, which some tools set
+// to a host system, is ignored. An archive with no entries yields zero.
+func MinZipVersion(path string) (uint16, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	var version uint16
+	for _, f := range r.File {
+		version = max(version, f.ReaderVersion&0xff)
+	}
+	return version, nil
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var creatorInfoTests = []struct {
+	path     string
+	expected string
+}{
+	{"testdata/sample.zip", "Info-ZIP 3.0 (Unix)"},
+	{"testdata/sample.tar", "POSIX ustar tar"},
+	{"testdata/sample.tar.gz", "GNU tar; gzip (Unix)"},
+	{"testdata/sample.tar.bz2", "GNU tar"},
+	{"testdata/sample.tar.xz", "GNU tar"},
+}
+
+func TestCreatorInfo(t *testing.T) {
+	for _, test := range creatorInfoTests {
+		desc, err := CreatorInfo(test.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v\n", test.path, err)
+			continue
+		}
+		if desc != test.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", test.expected, desc)
+		}
+	}
+}
+
+func TestCreatorInfoFormats(t *testing.T) {
+	formats := []struct {
+		format   tar.Format
+		records  map[string]string
+		expected string
+	}{
+		{tar.FormatGNU, nil, "GNU tar"},
+		{tar.FormatPAX, map[string]string{"comment": "creator"}, "POSIX pax tar"},
+		{tar.FormatUSTAR, nil, "POSIX ustar tar"},
+	}
+
+	for _, test := range formats {
+		path := filepath.Join(t.TempDir(), "creator.tar")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tw := tar.NewWriter(file)
+		if err := tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0644, Format: test.format, PAXRecords: test.records}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		desc, err := CreatorInfo(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if desc != test.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", test.expected, desc)
+		}
+	}
+}
+
+func TestCreatorInfoUnknown(t *testing.T) {
+	emptyZip := writeTestArchive(t, "empty.zip", nil)
+	goGzip := writeTestArchive(t, "plain.tar.gz", []testEntry{{name: "a.txt", body: "a"}})
+
+	desc, err := CreatorInfo(emptyZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc != unknownCreator {
+		t.Errorf("Expecting '%s', got '%s'\n", unknownCreator, desc)
+	}
+
+	// The standard library's gzip writer records an unknown operating system.
+	desc, err = CreatorInfo(goGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc != "POSIX ustar tar" {
+		t.Errorf("Expecting '%s', got '%s'\n", "POSIX ustar tar", desc)
+	}
+
+	if _, err := CreatorInfo("testdata/sample.rar"); err == nil {
+		t.Error("Failed to receive non-nil error for an unsupported archive type.")
+	}
+}
+
+func TestMinZipVersion(t *testing.T) {
+	if version, err := MinZipVersion("testdata/sample.zip"); version != 20 || err != nil {
+		t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 20, version, err)
+	}
+
+	// Raise the version needed by the second entry's central directory record,
+	// setting the upper byte as some tools do.
+	path := writeTestArchive(t, "version.zip", findEntries)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := bytes.Split(data, []byte("PK\x01\x02"))
+	if len(records) < 3 {
+		t.Fatalf("Expecting at least 2 central directory records, got %d\n", len(records)-1)
+	}
+	binary.LittleEndian.PutUint16(records[2][2:], 0x0300|63)
+	if err := os.WriteFile(path, bytes.Join(records, []byte("PK\x01\x02")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := MinZipVersion(path); version != 63 || err != nil {
+		t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 63, version, err)
+	}
+
+	path = writeTestArchive(t, "empty.zip", nil)
+	if version, err := MinZipVersion(path); version != 0 || err != nil {
+		t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 0, version, err)
+	}
+
+	if _, err := MinZipVersion("testdata/sample.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a tar archive.")
+	}
+}
@@ -0,0 +1,114 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+)
+
+// Struct cursor iterates over the entries of an archive of any supported type in
+// storage order.
+type cursor struct {
+	tarReader *tar.Reader
+	zipFiles  []*zip.File
+	index     int
+	closer    io.Closer
+}
+
+// Opens the archive at archivePath, whose type is determined by DetermineType, and
+// returns a cursor positioned before its first entry.
+func openCursor(archivePath string) (*cursor, error) {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return openCursorOfType(archivePath, typ)
+}
+
+// Opens the archive of the given type at archivePath and returns a cursor positioned
+// before its first entry.
+func openCursorOfType(archivePath string, typ Type) (*cursor, error) {
+	if typ == Zip {
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+		}
+		return &cursor{zipFiles: r.File, closer: r}, nil
+	}
+
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor{tarReader: tar.NewReader(stream), closer: stream}, nil
+}
+
+// Advances to and returns the next entry, or io.EOF when no entries remain.
+func (c *cursor) next() (*entry, error) {
+	if c.tarReader == nil {
+		if c.index >= len(c.zipFiles) {
+			return nil, io.EOF
+		}
+
+		f := c.zipFiles[c.index]
+		c.index++
+		return newZipEntry(f), nil
+	}
+
+	header, err := c.tarReader.Next()
+	if err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf(fmtErrTarReadFailed, err)
+	}
+
+	return &entry{
+		name:    header.Name,
+		size:    header.Size,
+		modTime: header.ModTime,
+		mode:    header.FileInfo().Mode(),
+		header:  header,
+		reader:  c.tarReader,
+	}, nil
+}
+
+// Returns the entry for a file in a zip archive.
+func newZipEntry(f *zip.File) *entry {
+	return &entry{
+		name:    f.Name,
+		size:    int64(f.UncompressedSize64),
+		modTime: f.Modified,
+		mode:    zipFileMode(f),
+		file:    f,
+	}
+}
+
+// Close closes the underlying archive.
+func (c *cursor) Close() error {
+	return c.closer.Close()
+}
+
+// Opens the archive at archivePath and invokes fn for each of its entries in
+// storage order, stopping at the first error returned by fn.
+func forEachEntry(archivePath string, fn func(e *entry) error) error {
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
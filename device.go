@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Format string for errors creating device files
+const fmtErrCreateDevice string = "archive: failed to create device file: %v"
+
+// ErrDeviceSkipped is reported through ExtractOptions.Warn for each character or block
+// device entry that extraction skips. The reported error wraps ErrDeviceSkipped and
+// names the entry along with its device numbers.
+var ErrDeviceSkipped = errors.New("archive: device entry skipped")
+
+// errDevicesUnsupported is returned when asked to recreate device files on a platform
+// where they cannot be created.
+var errDevicesUnsupported = errors.New("archive: device files cannot be created on this platform")
+
+// DeviceNumbers returns the major and minor device numbers recorded in header, reporting
+// whether header describes a character or block device (tar.TypeChar or tar.TypeBlock).
+// Backing up and restoring a system requires these numbers to recreate device files such
+// as those beneath /dev, but tar records them in header fields that are otherwise easily
+// overlooked. For any other kind of entry, including a nil header, ok is false and the
+// numbers are zero.
+func DeviceNumbers(header *tar.Header) (major, minor int64, ok bool) {
+	if header == nil || (header.Typeflag != tar.TypeChar && header.Typeflag != tar.TypeBlock) {
+		return 0, 0, false
+	}
+	return header.Devmajor, header.Devminor, true
+}
+
+// Handles a device entry during extraction to target, recreating it if cfg permits
+// and otherwise reporting it as skipped.
+func extractDevice(e *entry, target string, cfg extractConfig) error {
+	major, minor, _ := DeviceNumbers(e.header)
+	if !cfg.recreateDevices {
+		if cfg.warn != nil {
+			cfg.warn(fmt.Errorf("%w: %q (%d, %d)", ErrDeviceSkipped, e.name, major, minor))
+		}
+		return nil
+	}
+
+	if err := makeDir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	// As with regular files, an existing file at the target is replaced.
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf(fmtErrCreateDevice, err)
+	}
+
+	return makeDevice(target, e.header.Typeflag == tar.TypeBlock, filePerm(e.mode), major, minor)
+}
@@ -0,0 +1,22 @@
+package archive
+
+import (
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// Creates a character or block device file at target with the given permission bits
+// and device numbers.
+func makeDevice(target string, block bool, perm fs.FileMode, major, minor int64) error {
+	mode := uint32(perm) | unix.S_IFCHR
+	if block {
+		mode = uint32(perm) | unix.S_IFBLK
+	}
+
+	if err := unix.Mknod(target, mode, int(unix.Mkdev(uint32(major), uint32(minor)))); err != nil {
+		return fmt.Errorf(fmtErrCreateDevice, err)
+	}
+	return nil
+}
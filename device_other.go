@@ -0,0 +1,10 @@
+//go:build !linux
+
+package archive
+
+import "io/fs"
+
+// Returns an error, as device files are only created on Linux.
+func makeDevice(target string, block bool, perm fs.FileMode, major, minor int64) error {
+	return errDevicesUnsupported
+}
@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var deviceNumbersTests = []struct {
+	header *tar.Header
+	major  int64
+	minor  int64
+	ok     bool
+}{
+	{&tar.Header{Typeflag: tar.TypeChar, Devmajor: 1, Devminor: 3}, 1, 3, true},
+	{&tar.Header{Typeflag: tar.TypeBlock, Devmajor: 8, Devminor: 1}, 8, 1, true},
+	{&tar.Header{Typeflag: tar.TypeReg, Devmajor: 8, Devminor: 1}, 0, 0, false},
+	{&tar.Header{Typeflag: tar.TypeFifo}, 0, 0, false},
+	{nil, 0, 0, false},
+}
+
+func TestDeviceNumbers(t *testing.T) {
+	for _, test := range deviceNumbersTests {
+		major, minor, ok := DeviceNumbers(test.header)
+		if major != test.major || minor != test.minor || ok != test.ok {
+			t.Errorf("Expecting (%d, %d, %t), got (%d, %d, %t)\n", test.major, test.minor, test.ok, major, minor, ok)
+		}
+	}
+}
+
+// Returns the path of a tar archive holding a regular file, a character device,
+// and a block device.
+func writeDeviceArchive(t *testing.T) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "devices.tar")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	headers := []*tar.Header{
+		{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3},
+		{Name: "dev/sda1", Typeflag: tar.TypeBlock, Mode: 0660, Devmajor: 8, Devminor: 1},
+		{Name: "readme.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+	}
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestExtractAllWithOptions_devices(t *testing.T) {
+	archivePath := writeDeviceArchive(t)
+
+	dest := t.TempDir()
+	var warnings []error
+	opts := ExtractOptions{Warn: func(err error) { warnings = append(warnings, err) }}
+	if err := ExtractAllWithOptions(archivePath, dest, opts); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expecting 2 warnings, got %v\n", warnings)
+	}
+	expected := `archive: device entry skipped: "dev/null" (1, 3)`
+	if !errors.Is(warnings[0], ErrDeviceSkipped) || warnings[0].Error() != expected {
+		t.Errorf("Expecting '%s', got '%v'\n", expected, warnings[0])
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "dev", "null")); !os.IsNotExist(err) {
+		t.Errorf("Expected device entry to be skipped, got error: %v\n", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dest, "readme.txt")); string(content) != "hello" || err != nil {
+		t.Errorf("Expecting 'hello', got '%s' (error: %v)\n", content, err)
+	}
+
+	if err := ExtractAll(archivePath, t.TempDir()); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func TestExtractAllWithOptions_recreateDevices(t *testing.T) {
+	archivePath := writeDeviceArchive(t)
+	dest := t.TempDir()
+	err := ExtractAllWithOptions(archivePath, dest, ExtractOptions{RecreateDevices: true})
+
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, errDevicesUnsupported) {
+			t.Errorf("Expecting '%s', got '%v'\n", errDevicesUnsupported, err)
+		}
+		return
+	} else if os.Geteuid() != 0 {
+		if err == nil {
+			t.Error("Failed to receive non-nil error when recreating devices without privilege")
+		}
+		return
+	} else if err != nil {
+		t.Skipf("Unable to create device files: %v\n", err)
+	}
+
+	for _, name := range []string{"null", "sda1"} {
+		info, err := os.Lstat(filepath.Join(dest, "dev", name))
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", name, err)
+		} else if info.Mode()&os.ModeDevice == 0 {
+			t.Errorf("Expecting %s to be a device, got mode %v\n", name, info.Mode())
+		}
+	}
+}
@@ -1,7 +1,7 @@
 /*
 Package archive is a convenience package for enumerating the contents of zip files,
 tar files, and compressed tar files. Supported archive types are: zip, tar,
-gzip-compressed tar, bzip2-compressed tar, and xz-compressed tar.
+gzip-compressed tar, bzip2-compressed tar, xz-compressed tar, and lzma-compressed tar.
 
 Usage
 
@@ -71,6 +71,8 @@ To determine the type of archive file:
             err = archive.WalkTarGz(archiveFilename, tarCallback)
         case archive.TarXz:
             err = archive.WalkTarXz(archiveFilename, tarCallback)
+        case archive.TarLzma:
+            err = archive.WalkTarLzma(archiveFilename, tarCallback)
         case archive.Zip:
             err = archive.WalkZip(archiveFilename, zipCallback)
         }
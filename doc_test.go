@@ -3,10 +3,14 @@ package archive
 import (
 	"archive/tar"
 	"archive/zip"
+	"embed"
 	"fmt"
 	"log"
 )
 
+//go:embed testdata/sample.tar.gz testdata/sample.zip
+var embeddedArchives embed.FS
+
 func ExampleDetermineType() {
 	filename := "sample.tar.gz"
 	typ, err := DetermineType(filename)
@@ -96,3 +100,41 @@ func ExampleWalkZip() {
 	// sample/text/
 	// sample/text/lorem.txt
 }
+
+func ExampleEntries() {
+	for entry, err := range Entries("testdata/sample.zip") {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %d\n", entry.Name(), entry.Size())
+	}
+	// Output:
+	// sample/ 0
+	// sample/text/ 0
+	// sample/text/lorem.txt 803
+}
+
+func ExampleWalkEmbedded() {
+	tarCallback := func(reader *tar.Reader, header *tar.Header) error {
+		fmt.Printf("%s\n", header.Name)
+		return nil
+	}
+	zipCallback := func(file *zip.File) error {
+		fmt.Printf("%s\n", file.Name)
+		return nil
+	}
+
+	for _, name := range []string{"testdata/sample.tar.gz", "testdata/sample.zip"} {
+		err := WalkEmbedded(embeddedArchives, name, tarCallback, zipCallback)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// Output:
+	// sample/
+	// sample/text/
+	// sample/text/lorem.txt
+	// sample/
+	// sample/text/
+	// sample/text/lorem.txt
+}
@@ -0,0 +1,51 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// WalkEmbedded walks the contents of the archive named name within fsys, invoking
+// tarCallback for each entry of a tar-family archive or zipCallback for each entry of a
+// zip archive. Since a file system need not be backed by the OS file system, the archive
+// type is identified from the file's leading bytes, as by DetermineTypeFromMagic, rather
+// than from its name. This lets applications ship archives inside their binaries using
+// go:embed and inspect them at runtime without writing them to disk, and equally walk
+// archives held in any other fs.FS, such as one returned by os.DirFS or fstest.MapFS.
+//
+// Tar-family archives are decompressed and walked as they are read. A zip archive is
+// read in place if the opened file implements io.ReaderAt, as the files of an
+// embed.FS do; otherwise it is read in its entirety before being walked, in memory or,
+// if larger than 64 MiB, in a temporary file, as by WalkStdin.
+func WalkEmbedded(fsys fs.FS, name string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	if readerAt, ok := file.(io.ReaderAt); ok {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf(fmtErrArchiveOpen, err)
+		}
+
+		magic := make([]byte, tarBlockSize)
+		n, err := readerAt.ReadAt(magic, 0)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf(fmtErrReadMagic, err)
+		}
+
+		if typ, err := typeFromMagic(magic[:n]); err == nil && typ == Zip {
+			zr, err := zip.NewReader(readerAt, info.Size())
+			if err != nil {
+				return fmt.Errorf(fmtErrArchiveOpen, err)
+			}
+			return readZipWrapping(zr.File, zipCallback)
+		}
+	}
+
+	return walkReader(file, tarCallback, zipCallback)
+}
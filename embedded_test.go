@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io/fs"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// Struct streamOnlyFS is a file system whose files implement only fs.File,
+// hiding any io.ReaderAt implementation.
+type streamOnlyFS struct {
+	fsys fs.FS
+}
+
+// Open opens the named file, wrapping it so that only the methods of fs.File are exposed.
+func (s streamOnlyFS) Open(name string) (fs.File, error) {
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{file}, nil
+}
+
+func TestWalkEmbedded(t *testing.T) {
+	mapFS := fstest.MapFS{}
+	for _, path := range sampleArchives {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapFS[path] = &fstest.MapFile{Data: data}
+	}
+
+	expected := []string{"sample/", "sample/text/", "sample/text/lorem.txt"}
+	for _, fsys := range []fs.FS{os.DirFS("."), mapFS, streamOnlyFS{mapFS}} {
+		for _, path := range sampleArchives {
+			names := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+				return WalkEmbedded(fsys, path, tarCb, zipCb)
+			})
+			if !reflect.DeepEqual(slices.Sorted(slices.Values(names)), expected) {
+				t.Errorf("%s: expecting '%v', got '%v'\n", path, expected, names)
+			}
+		}
+	}
+
+	if err := WalkEmbedded(mapFS, "nonexistent.zip", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+
+	mapFS["lorem.txt"] = &fstest.MapFile{Data: []byte("lorem ipsum")}
+	if err := WalkEmbedded(mapFS, "lorem.txt", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a file that is not an archive.")
+	}
+
+	// Callbacks of the other kind are never invoked.
+	err := WalkEmbedded(mapFS, "testdata/sample.zip", func(*tar.Reader, *tar.Header) error {
+		t.Error("Unexpected tar callback for a zip archive.")
+		return nil
+	}, func(*zip.File) error { return nil })
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+)
+
+// Entry is a format-independent view of a single file or directory entry in
+// an archive of any supported type.
+type Entry interface {
+	// Name returns the entry's full name within the archive.
+	Name() string
+
+	// Size returns the entry's uncompressed size in bytes.
+	Size() int64
+
+	// ModTime returns the entry's modification time.
+	ModTime() time.Time
+
+	// Mode returns the entry's file mode and permission bits.
+	Mode() fs.FileMode
+
+	// IsDir reports whether the entry is a directory.
+	IsDir() bool
+
+	// Open returns a reader over the entry's contents. For entries of tar-family
+	// archives, the reader is only valid until the walk advances to the next entry.
+	Open() (io.ReadCloser, error)
+}
+
+// EntryInfo is a snapshot of the details of a single archive entry, which, unlike an
+// Entry, remains valid after the archive has been closed.
+type EntryInfo struct {
+	Name    string      // full name within the archive
+	Size    int64       // uncompressed size in bytes
+	ModTime time.Time   // modification time
+	Mode    fs.FileMode // file mode and permission bits
+}
+
+// IsDir reports whether the entry is a directory.
+func (info EntryInfo) IsDir() bool {
+	return info.Mode.IsDir()
+}
+
+// Entries returns an iterator over the entries of the archive at archivePath, whose
+// type is determined by DetermineType, in archive order:
+//
+//	for entry, err := range archive.Entries(path) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(entry.Name())
+//	}
+//
+// The archive is opened when iteration begins and closed when it ends, including when
+// the loop is exited early. If an error occurs, it is yielded with a nil Entry and
+// iteration stops.
+func Entries(archivePath string) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		c, err := openCursor(archivePath)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer c.Close()
+
+		for {
+			e, err := c.next()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// WalkAny walks the contents of the archive at path, of any supported type, invoking the
+// callback for each entry in archive order. The type is determined as by
+// DetermineTypeSmart: from the extensions present in path or, failing that, from the
+// file's leading bytes, so misnamed archives and archives without an extension can be
+// walked too. Entries of zip archives and of every tar-family archive alike are passed
+// as an Entry, so a single callback serves all formats; this is the simplest way to walk
+// an archive without regard to its type. As with Entries, the reader returned by the
+// Open method of an entry of a tar-family archive is only valid until the callback
+// returns. The first error returned by the callback stops the walk and is returned.
+func WalkAny(path string, callback func(Entry) error) error {
+	typ, err := DetermineTypeSmart(path)
+	if err != nil {
+		return err
+	}
+
+	c, err := openCursorOfType(path, typ)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if callback != nil {
+			if err := callback(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// FileInfo returns an fs.FileInfo describing entry, allowing entries of any archive type
+// to be handled uniformly by code written against the standard library's file system
+// interfaces. As with os.Stat, the Name method of the result returns the base name of the
+// entry rather than its full name within the archive. For entries produced by this
+// package, Sys returns the entry's *tar.Header or *zip.FileHeader; otherwise it returns nil.
+func FileInfo(entry Entry) fs.FileInfo {
+	return entryFileInfo{entry}
+}
+
+// Struct entryFileInfo adapts an Entry to the fs.FileInfo interface.
+type entryFileInfo struct {
+	entry Entry
+}
+
+// Name returns the base name of the entry.
+func (fi entryFileInfo) Name() string {
+	return path.Base(strings.TrimSuffix(fi.entry.Name(), "/"))
+}
+
+// Size returns the entry's uncompressed size in bytes.
+func (fi entryFileInfo) Size() int64 {
+	return fi.entry.Size()
+}
+
+// Mode returns the entry's file mode and permission bits.
+func (fi entryFileInfo) Mode() fs.FileMode {
+	return fi.entry.Mode()
+}
+
+// ModTime returns the entry's modification time.
+func (fi entryFileInfo) ModTime() time.Time {
+	return fi.entry.ModTime()
+}
+
+// IsDir reports whether the entry is a directory.
+func (fi entryFileInfo) IsDir() bool {
+	return fi.entry.IsDir()
+}
+
+// Sys returns the entry's underlying tar or zip header, if any.
+func (fi entryFileInfo) Sys() any {
+	if e, ok := fi.entry.(*entry); ok {
+		if e.header != nil {
+			return e.header
+		}
+		if e.file != nil {
+			return &e.file.FileHeader
+		}
+	}
+	return nil
+}
+
+// Struct entry holds the format-independent details of a single archive entry.
+type entry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+	header  *tar.Header // set for tar-family entries
+	file    *zip.File   // set for zip entries
+	reader  io.Reader   // set for tar-family entries; valid until the cursor advances
+}
+
+// Name returns the entry's full name within the archive.
+func (e *entry) Name() string {
+	return e.name
+}
+
+// Size returns the entry's uncompressed size in bytes.
+func (e *entry) Size() int64 {
+	return e.size
+}
+
+// ModTime returns the entry's modification time.
+func (e *entry) ModTime() time.Time {
+	return e.modTime
+}
+
+// Mode returns the entry's file mode and permission bits.
+func (e *entry) Mode() fs.FileMode {
+	return e.mode
+}
+
+// IsDir reports whether the entry is a directory.
+func (e *entry) IsDir() bool {
+	return e.mode.IsDir()
+}
+
+// Returns a snapshot of the entry's details.
+func (e *entry) info() EntryInfo {
+	return EntryInfo{Name: e.name, Size: e.size, ModTime: e.modTime, Mode: e.mode}
+}
+
+// Open returns a reader over the entry's contents.
+func (e *entry) Open() (io.ReadCloser, error) {
+	if e.file == nil {
+		return io.NopCloser(e.reader), nil
+	}
+
+	rc, err := e.file.Open()
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,200 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type entryTest struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+var sampleEntries = []entryTest{
+	{"sample/", 0, true},
+	{"sample/text/", 0, true},
+	{sampleFileName, sampleFileSize, false},
+}
+
+func TestEntries(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		found := 0
+		for entry, err := range Entries(archivePath) {
+			if err != nil {
+				t.Fatalf("Unexpected error iterating %s: %v\n", archivePath, err)
+			}
+
+			for _, expected := range sampleEntries {
+				if entry.Name() != expected.name {
+					continue
+				}
+				found++
+
+				if entry.Size() != expected.size {
+					t.Errorf("Expecting '%d', got '%d'\n", expected.size, entry.Size())
+				}
+				if entry.IsDir() != expected.isDir || entry.Mode().IsDir() != expected.isDir {
+					t.Errorf("%s: expecting IsDir '%t', got '%t'\n", entry.Name(), expected.isDir, entry.IsDir())
+				}
+				if entry.ModTime().IsZero() {
+					t.Errorf("%s: unexpected zero modification time\n", entry.Name())
+				}
+
+				if !expected.isDir {
+					reader, err := entry.Open()
+					if err != nil {
+						t.Fatal(err)
+					}
+					n, err := io.Copy(io.Discard, reader)
+					reader.Close()
+					if err != nil || n != expected.size {
+						t.Errorf("Expecting '%d', got '%d' (error: %v)\n", expected.size, n, err)
+					}
+				}
+			}
+		}
+
+		if found != len(sampleEntries) {
+			t.Errorf("%s: expecting '%d', got '%d'\n", archivePath, len(sampleEntries), found)
+		}
+	}
+}
+
+func TestEntries_earlyExit(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		count := 0
+		for range Entries(archivePath) {
+			count++
+			break
+		}
+
+		if count != 1 {
+			t.Errorf("Expecting '%d', got '%d'\n", 1, count)
+		}
+	}
+}
+
+func TestEntries_errors(t *testing.T) {
+	for _, archivePath := range []string{"nonexistent.zip", "testdata/invalid.tar", "foo.123"} {
+		var lastErr error
+		for entry, err := range Entries(archivePath) {
+			if entry != nil {
+				continue
+			}
+			lastErr = err
+		}
+
+		if lastErr == nil {
+			t.Errorf("Failed to receive non-nil error when iterating %s.\n", archivePath)
+		}
+	}
+}
+
+func TestWalkAny(t *testing.T) {
+	dir := t.TempDir()
+	paths := slices.Clone(sampleArchives)
+	for _, archivePath := range sampleArchives {
+		// Archives without an extension are identified from their content.
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		misnamed := filepath.Join(dir, strings.ReplaceAll(filepath.Base(archivePath), ".", "-"))
+		if err := os.WriteFile(misnamed, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, misnamed)
+	}
+
+	for _, archivePath := range append(paths, "testdata/sample.tar.lzma") {
+		var visited []entryTest
+		err := WalkAny(archivePath, func(entry Entry) error {
+			size := entry.Size()
+			if !entry.IsDir() {
+				reader, err := entry.Open()
+				if err != nil {
+					return err
+				}
+				defer reader.Close()
+
+				if size, err = io.Copy(io.Discard, reader); err != nil {
+					return err
+				}
+			}
+			visited = append(visited, entryTest{entry.Name(), size, entry.IsDir()})
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error walking %s: %v\n", archivePath, err)
+		}
+
+		slices.SortFunc(visited, func(a, b entryTest) int { return strings.Compare(a.name, b.name) })
+		if !reflect.DeepEqual(visited, sampleEntries) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", archivePath, sampleEntries, visited)
+		}
+	}
+
+	errStop := errors.New("stop")
+	if err := WalkAny(sampleArchives[0], func(Entry) error { return errStop }); err != errStop {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	for _, archivePath := range []string{"nonexistent.zip", "testdata/invalid.tar", "testdata/lorem.txt.bz2"} {
+		if err := WalkAny(archivePath, nil); err == nil {
+			t.Errorf("Failed to receive non-nil error when walking %s.\n", archivePath)
+		}
+	}
+}
+
+func TestFileInfo(t *testing.T) {
+	expectedNames := map[string]string{
+		"sample/":      "sample",
+		"sample/text/": "text",
+		sampleFileName: "lorem.txt",
+	}
+
+	for _, archivePath := range sampleArchives {
+		for e, err := range Entries(archivePath) {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info := FileInfo(e)
+			if info.Name() != expectedNames[e.Name()] {
+				t.Errorf("Expecting '%s', got '%s'\n", expectedNames[e.Name()], info.Name())
+			}
+			if info.Size() != e.Size() || info.Mode() != e.Mode() || info.IsDir() != e.IsDir() ||
+				!info.ModTime().Equal(e.ModTime()) {
+				t.Errorf("%s: file info does not match entry %s\n", archivePath, e.Name())
+			}
+
+			switch sys := info.Sys().(type) {
+			case *tar.Header:
+				if sys.Name != e.Name() || archivePath == "testdata/sample.zip" {
+					t.Errorf("%s: unexpected tar header for %s\n", archivePath, e.Name())
+				}
+			case *zip.FileHeader:
+				if sys.Name != e.Name() || archivePath != "testdata/sample.zip" {
+					t.Errorf("%s: unexpected zip header for %s\n", archivePath, e.Name())
+				}
+			default:
+				t.Errorf("%s: unexpected Sys value %T\n", archivePath, sys)
+			}
+		}
+	}
+
+	var _ fs.FileInfo = FileInfo(&entry{name: "foo"})
+	if sys := FileInfo(&entry{name: "foo"}).Sys(); sys != nil {
+		t.Errorf("Expecting nil, got '%v'\n", sys)
+	}
+}
@@ -0,0 +1,1063 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Format strings for extraction errors
+const (
+	fmtErrCreateDir   string = "archive: failed to create directory: %v"
+	fmtErrCreateFile  string = "archive: failed to create file: %v"
+	fmtErrCreateLink  string = "archive: failed to create link: %v"
+	fmtErrReadFile    string = "archive: failed to read existing file: %v"
+	fmtErrWriteFile   string = "archive: failed to write file: %w"
+	fmtErrZipOpenFile string = "archive: failed to open zip entry: %v"
+)
+
+// ErrUnsafePath is returned when an entry's name would resolve to a location
+// outside of the destination directory (a "zip slip").
+var ErrUnsafePath = errors.New("archive: entry path escapes destination directory")
+
+// ErrInvalidEntryName is returned when an entry's name is empty or consists only of
+// whitespace, as in some malformed zip archives. Such a name does not identify a location
+// beneath the destination directory, and joining it naively would address the directory
+// itself. The returned error wraps ErrInvalidEntryName and quotes the offending name.
+var ErrInvalidEntryName = errors.New("archive: invalid entry name")
+
+// ErrEntryNotFound is returned when a named entry is not present in an archive.
+var ErrEntryNotFound = errors.New("archive: entry not found")
+
+// ErrNameCollision is returned by ExtractFlat when two entries would be written
+// to the same file.
+var ErrNameCollision = errors.New("archive: entries collide when flattened")
+
+// ErrLimitExceeded is returned when extraction would write more than the permitted
+// number of bytes.
+var ErrLimitExceeded = errors.New("archive: extraction size limit exceeded")
+
+// ErrTargetExists is returned by extraction with the Error collision policy when an
+// entry's target already exists.
+var ErrTargetExists = errors.New("archive: extraction target already exists")
+
+// errUnknownCollisionPolicy is returned by ExtractAllWithOptions for an unsupported
+// collision policy.
+var errUnknownCollisionPolicy = errors.New("archive: unknown collision policy")
+
+// errLinkTargetNotFound is returned when the target of a hard link entry is
+// not extracted from the archive.
+var errLinkTargetNotFound = errors.New("archive: hard link target not found")
+
+// errUnsupportedEntry is returned when asked to extract an entry that is
+// neither a regular file nor a directory.
+var errUnsupportedEntry = errors.New("archive: unsupported entry type for extraction")
+
+// Permissions used when creating files and directories during extraction.
+const (
+	extractDirPerm  fs.FileMode = 0750
+	extractFilePerm fs.FileMode = 0600
+)
+
+// ExtractOne extracts the single entry named entryName from the archive at archivePath
+// into destDir, preserving the entry's relative path and creating any parent directories.
+// The path of the written file or directory is returned. If the entry's name would resolve
+// to a location outside of destDir, or lies beneath a symbolic link within destDir, an
+// error wrapping ErrUnsafePath is returned and nothing is written. If the archive
+// contains no such entry, ErrEntryNotFound is returned.
+func ExtractOne(archivePath, entryName, destDir string) (string, error) {
+	target, err := SafeJoin(destDir, entryName)
+	if err != nil {
+		return "", err
+	}
+	if err := checkLinkedParents(destDir, target); err != nil {
+		return "", err
+	}
+
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	if typ == Zip {
+		err = extractOneZip(archivePath, entryName, target)
+	} else {
+		err = extractOneTar(archivePath, typ, entryName, target)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+// ExtractLargest writes the contents of the largest regular file in the archive at
+// archivePath, whose type is determined by DetermineType, to w, returning the file's
+// name and the number of bytes written. This suits pulling the main payload, such as a
+// disk image or installer, out of a bundle without naming it. Sizes are compared as by
+// LargestEntry, and if several files share the largest size, the first in archive order
+// is written. If the archive holds no regular files, ErrEntryNotFound is returned.
+//
+// A zip archive's central directory gives every entry's size up front, so the largest
+// file is found without reading any contents and then opened directly. A tar-family
+// archive, however, can only be read from start to finish: its headers are read in one
+// pass to find the largest file, and a second pass decompresses the archive again as
+// far as that file to stream it to w, which roughly doubles the cost of reading the
+// archive when the file lies near its end.
+func ExtractLargest(archivePath string, w io.Writer) (name string, n int64, err error) {
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer c.Close()
+
+	var largest *entry
+	index := -1
+	for i := 0; ; i++ {
+		e, err := c.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", 0, err
+		}
+
+		if e.mode.IsRegular() && (largest == nil || e.size > largest.size) {
+			largest, index = e, i
+		}
+	}
+	if largest == nil {
+		return "", 0, ErrEntryNotFound
+	}
+
+	if largest.file == nil {
+		// The entry's contents have been read past, so the tar stream is read again.
+		second, err := openCursor(archivePath)
+		if err != nil {
+			return "", 0, err
+		}
+		defer second.Close()
+
+		for i := 0; i <= index; i++ {
+			if largest, err = second.next(); err == io.EOF {
+				return "", 0, fmt.Errorf(fmtErrTarReadFailed, io.ErrUnexpectedEOF)
+			} else if err != nil {
+				return "", 0, err
+			}
+		}
+	}
+
+	reader, err := largest.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	n, err = io.Copy(w, reader)
+	if err != nil {
+		return "", n, err
+	}
+	return largest.name, n, nil
+}
+
+// Extracts the named entry from a zip archive to target.
+func extractOneZip(archivePath, entryName, target string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != entryName {
+			continue
+		}
+
+		mode := zipFileMode(f)
+		if mode.IsDir() {
+			return makeDir(target)
+		} else if !mode.IsRegular() {
+			return errUnsupportedEntry
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf(fmtErrZipOpenFile, err)
+		}
+		defer rc.Close()
+
+		return writeFile(target, rc, mode)
+	}
+
+	return ErrEntryNotFound
+}
+
+// Extracts the named entry from a tar-family archive to target.
+func extractOneTar(archivePath string, typ Type, entryName, target string) error {
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return ErrEntryNotFound
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if header.Name != entryName {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			return makeDir(target)
+		case tar.TypeReg:
+			return writeFile(target, reader, header.FileInfo().Mode())
+		}
+
+		return errUnsupportedEntry
+	}
+}
+
+// ExtractAll extracts every entry of the archive at archivePath, whose type is determined
+// by DetermineType, into dest, preserving each entry's relative path. Directories and
+// regular files are created with the permission bits recorded in the archive, limited
+// to those that extraction would grant to a new file. Every directory entry is created,
+// so empty directories are restored along with those implied by the paths of files.
+// Symbolic links are recreated provided that their targets resolve to locations within
+// dest. Other special files, such as devices and named pipes, are skipped.
+//
+// Hard link entries of tar-family archives are recreated as hard links to the previously
+// extracted file named by the entry's link name. If that file has not yet been extracted
+// when the link is encountered, creation of the link is deferred until the rest of the
+// archive has been extracted. If the file system does not support hard links, the
+// target's contents are copied instead.
+//
+// If any entry's name, or the target of any link, would resolve to a location outside
+// of dest, ErrUnsafePath is returned. Names are checked lexically, which cannot reveal
+// where symbolic links already on disk lead, so nothing is ever written through one:
+// an entry whose parent directory within dest is a symbolic link, whether extracted
+// from the archive or present beforehand, causes an error wrapping ErrUnsafePath to
+// be returned, and a symbolic link occupying the name of a file entry is replaced
+// rather than followed. Archives that create files beneath their own symbolic links
+// should be extracted with ExtractAllSecure, which follows links as far as they remain
+// within dest.
+func ExtractAll(archivePath, dest string) error {
+	_, err := extractAll(archivePath, dest, extractConfig{})
+	return err
+}
+
+// ExtractAllSkipExisting extracts the archive at archivePath into dest as ExtractAll does,
+// except that a regular file entry is not written if an identical file already exists at
+// its target, making repeated extraction into the same directory inexpensive. An existing
+// file is considered identical if its size matches the entry's and, for zip archives, its
+// CRC-32 checksum matches the one recorded in the archive or, for tar-family archives,
+// its modification time matches the entry's. The files it writes are given the entry's
+// modification time so that a subsequent extraction skips them. The numbers of regular
+// files written and skipped are returned.
+func ExtractAllSkipExisting(archivePath, dest string) (written, skipped int, err error) {
+	stats, err := extractAll(archivePath, dest, extractConfig{skipExisting: true})
+	return stats.written, stats.skipped, err
+}
+
+// ExtractAllWithBudget extracts the archive at archivePath into dest as ExtractAll does,
+// but writes no more than maxTotalBytes bytes of file contents in total, bounding the
+// disk space that extraction can consume. Should an entry exceed what remains of the
+// budget, whether according to its recorded size or while its contents are written,
+// extraction stops, the entry's partially-written file is removed, and an error wrapping
+// ErrLimitExceeded is returned. Files extracted before that point are left in place. The
+// total size of the regular files written is returned.
+func ExtractAllWithBudget(path, dest string, maxTotalBytes int64) (int64, error) {
+	remaining := maxTotalBytes
+	stats, err := extractAll(path, dest, extractConfig{budget: &remaining})
+	return stats.bytes, err
+}
+
+// CollisionPolicy defines what extraction does when the target of an entry other than
+// a directory already exists.
+type CollisionPolicy uint
+
+// Valid collision policies.
+const (
+	// Overwrite replaces the existing file.
+	Overwrite CollisionPolicy = iota + 1
+
+	// Skip leaves the existing file in place and does not extract the entry.
+	Skip
+
+	// Rename extracts the entry alongside the existing file under a new name formed
+	// by inserting " (1)", " (2)", and so on before the extension.
+	Rename
+
+	// Error stops extraction with an error wrapping ErrTargetExists.
+	Error
+)
+
+// String returns a string representation of the collision policy.
+func (p CollisionPolicy) String() (result string) {
+	switch p {
+	case Overwrite:
+		result = "Overwrite"
+	case Skip:
+		result = "Skip"
+	case Rename:
+		result = "Rename"
+	case Error:
+		result = "Error"
+	}
+	return
+}
+
+// maxCollisionRenames bounds the number of names tried by the Rename collision policy.
+const maxCollisionRenames = 10000
+
+// ExtractOptions configures the behavior of ExtractAllWithOptions.
+type ExtractOptions struct {
+	// OnCollision determines what happens when the target of a file or link entry
+	// already exists, whether it was present before extraction began or was written
+	// by an earlier entry of the same name. Existing directories are always merged
+	// into. The zero value behaves as Overwrite, as ExtractAll does.
+	OnCollision CollisionPolicy
+
+	// Atomic, if true, causes each regular file to be written to a temporary file in
+	// the same directory as its target, flushed to stable storage, and renamed into
+	// place only once its contents have been written in full, so that neither a crash
+	// nor a failed extraction leaves a partially-written file under an entry's name.
+	// Renaming replaces any existing file at once, so other processes observe either
+	// the old file or the new one. Temporary files are named after their targets with
+	// a leading dot and a random suffix, and are removed if writing fails; only a crash
+	// can leave one behind.
+	Atomic bool
+
+	// RecreateDevices, if true, causes character and block device entries of tar-family
+	// archives to be recreated as device files with the device numbers reported by
+	// DeviceNumbers, as restoring a system backup requires. Creating device files
+	// requires privilege, typically running as root, and is supported only on Linux;
+	// elsewhere, or without privilege, extraction fails at the first device entry.
+	// Otherwise, device entries are skipped, as ExtractAll skips them.
+	RecreateDevices bool
+
+	// Warn, if non-nil, is called for each entry that extraction skips for a reason the
+	// caller may wish to report, rather than failing. Currently, each device entry that
+	// is skipped because RecreateDevices is false is reported with an error wrapping
+	// ErrDeviceSkipped.
+	Warn func(err error)
+
+	// CleanNames, if true, replaces the name of each entry with its canonical form, as
+	// the CleanNames option of WalkOptions does, before the entry is extracted. Targets
+	// are cleaned by SafeJoin in any case, so the option does not change where entries
+	// are written, but the errors and warnings extraction reports then name entries by
+	// their cleaned names, matching those produced by ListWithOptions and the walks
+	// with the same option, rather than as they appear in the archive.
+	CleanNames bool
+}
+
+// ExtractAllWithOptions extracts the archive at archivePath into dest as ExtractAll
+// does, configured by opts. With the Rename collision policy, the first free name of
+// the form "name (n).ext", for n from 1 to 10000, is used, mirroring desktop unzip
+// tools; hard links to a renamed file are created against its new name. If no free
+// name is found, an error wrapping ErrTargetExists is returned.
+func ExtractAllWithOptions(archivePath, dest string, opts ExtractOptions) error {
+	if opts.OnCollision > Error {
+		return errUnknownCollisionPolicy
+	}
+
+	cfg := extractConfig{
+		onCollision:     opts.OnCollision,
+		atomic:          opts.Atomic,
+		recreateDevices: opts.RecreateDevices,
+		warn:            opts.Warn,
+		cleanNames:      opts.CleanNames,
+	}
+	_, err := extractAll(archivePath, dest, cfg)
+	return err
+}
+
+// Struct extractConfig holds the settings of an extraction.
+type extractConfig struct {
+	skipExisting    bool            // skip regular files identical to ones already present
+	budget          *int64          // bytes of file contents that remain to be written; nil means unlimited
+	onCollision     CollisionPolicy // what to do when a target exists; zero means Overwrite
+	atomic          bool            // write each file to a temporary file and rename it into place
+	recreateDevices bool            // create device files for device entries rather than skipping them
+	warn            func(error)     // called for each entry skipped with a warning; may be nil
+	cleanNames      bool            // replace entry names with their canonical forms
+}
+
+// Struct extractStats counts the regular files handled by an extraction.
+type extractStats struct {
+	written int   // files written
+	skipped int   // files skipped as identical to ones already present
+	bytes   int64 // total size of the files written
+}
+
+// Extracts every entry of an archive into dest according to cfg.
+func extractAll(archivePath, dest string, cfg extractConfig) (stats extractStats, err error) {
+	var pending []hardLink
+	renamed := make(map[string]string)
+
+	err = forEachEntry(archivePath, func(e *entry) error {
+		if cfg.cleanNames {
+			e.name = cleanName(e.name)
+		}
+
+		target, err := SafeJoin(dest, e.name)
+		if err != nil {
+			return err
+		}
+
+		if !e.mode.IsDir() {
+			resolved, err := resolveCollision(target, cfg.onCollision)
+			if err != nil {
+				return err
+			} else if resolved == "" {
+				stats.skipped++
+				return nil
+			} else if resolved != target {
+				renamed[target] = resolved
+				target = resolved
+			}
+		}
+
+		if e.header != nil && e.header.Typeflag == tar.TypeLink {
+			link, err := newHardLink(dest, target, e.header.Linkname)
+			if err != nil {
+				return err
+			}
+			if oldname, ok := renamed[link.oldname]; ok {
+				link.oldname = oldname
+			}
+
+			if _, err := os.Lstat(link.oldname); err != nil {
+				pending = append(pending, link)
+				return nil
+			}
+			return link.create()
+		}
+
+		if !e.mode.IsRegular() {
+			return extractEntry(e, dest, target, cfg)
+		}
+
+		if cfg.skipExisting {
+			identical, err := isIdentical(e, target)
+			if err != nil {
+				return err
+			}
+			if identical {
+				stats.skipped++
+				return nil
+			}
+		}
+
+		if cfg.budget != nil && e.size > *cfg.budget {
+			return fmt.Errorf("%w: writing %q", ErrLimitExceeded, e.name)
+		}
+
+		if err := extractEntry(e, dest, target, cfg); err != nil {
+			if errors.Is(err, ErrLimitExceeded) {
+				return fmt.Errorf("%w: writing %q", ErrLimitExceeded, e.name)
+			}
+			return err
+		}
+		stats.written++
+		stats.bytes += e.size
+
+		if cfg.skipExisting {
+			if err := os.Chtimes(target, e.modTime, e.modTime); err != nil {
+				return fmt.Errorf(fmtErrWriteFile, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	for _, link := range pending {
+		if err := link.create(); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// Applies the collision policy to the target of an entry other than a directory,
+// returning the path to which the entry should be extracted, or an empty string
+// if it should be skipped.
+func resolveCollision(target string, policy CollisionPolicy) (string, error) {
+	if policy == 0 || policy == Overwrite {
+		return target, nil
+	}
+
+	if _, err := os.Lstat(target); err != nil {
+		return target, nil
+	}
+
+	switch policy {
+	case Skip:
+		return "", nil
+	case Rename:
+		return renameTarget(target)
+	}
+	return "", fmt.Errorf("%w: %q", ErrTargetExists, target)
+}
+
+// Returns the first path of the form "name (n).ext" alongside target at which
+// nothing exists.
+func renameTarget(target string) (string, error) {
+	dir, base := filepath.Split(target)
+	for n := 1; n <= maxCollisionRenames; n++ {
+		candidate := filepath.Join(dir, numberedName(base, n))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no free name for %q", ErrTargetExists, target)
+}
+
+// Returns the base name base with " (n)" inserted before its extension.
+func numberedName(base string, n int) string {
+	ext := path.Ext(base)
+	if ext == base {
+		// A name such as ".profile" has no extension.
+		ext = ""
+	}
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), n, ext)
+}
+
+// Reports whether the regular file at target is identical to the given regular
+// file entry, judged by size and either CRC-32 checksum or modification time.
+func isIdentical(e *entry, target string) (bool, error) {
+	info, err := os.Lstat(target)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != e.size {
+		return false, nil
+	}
+
+	if e.file == nil {
+		return info.ModTime().Equal(e.modTime), nil
+	}
+
+	file, err := os.Open(filepath.Clean(target))
+	if err != nil {
+		return false, fmt.Errorf(fmtErrReadFile, err)
+	}
+	defer file.Close()
+
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false, fmt.Errorf(fmtErrReadFile, err)
+	}
+	return hash.Sum32() == e.file.CRC32, nil
+}
+
+// Extracts a single entry other than a hard link to target within dest according
+// to cfg. If cfg.budget is non-nil, the entry's contents are deducted from it as they
+// are written.
+func extractEntry(e *entry, dest, target string, cfg extractConfig) error {
+	if err := checkLinkedParents(dest, target); err != nil {
+		return err
+	}
+
+	switch {
+	case e.mode.IsDir():
+		return makeDir(target)
+	case e.mode&fs.ModeSymlink != 0:
+		return extractSymlink(e, dest, target)
+	case e.header != nil && (e.header.Typeflag == tar.TypeChar || e.header.Typeflag == tar.TypeBlock):
+		return extractDevice(e, target, cfg)
+	case !e.mode.IsRegular():
+		return nil
+	}
+
+	reader, err := e.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var contents io.Reader = reader
+	if cfg.budget != nil {
+		contents = &budgetReader{reader: reader, remaining: cfg.budget}
+	}
+
+	if cfg.atomic {
+		return writeFileAtomic(target, contents, e.mode)
+	}
+	return writeFile(target, contents, e.mode)
+}
+
+// Struct budgetReader deducts the bytes read through it from a budget, failing
+// with ErrLimitExceeded once the budget is exhausted.
+type budgetReader struct {
+	reader    io.Reader
+	remaining *int64
+}
+
+// Read reads from the underlying reader, deducting the bytes read from the budget.
+func (b *budgetReader) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	*b.remaining -= int64(n)
+	if *b.remaining < 0 {
+		return n, ErrLimitExceeded
+	}
+	return n, err
+}
+
+// Creates a symbolic link at target for the given entry, provided that the
+// link's destination resolves to a location within dest.
+func extractSymlink(e *entry, dest, target string) error {
+	linkname, err := symlinkTarget(e)
+	if err != nil {
+		return err
+	}
+
+	if linkname == "" || filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") ||
+		!isWithin(dest, filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))) {
+		return ErrUnsafePath
+	}
+
+	if err := makeDir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	// A link left by a previous extraction is kept if it is unchanged.
+	if existing, err := os.Readlink(target); err == nil && existing == filepath.FromSlash(linkname) {
+		return nil
+	}
+
+	if err := os.Symlink(filepath.FromSlash(linkname), target); err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	return nil
+}
+
+// Returns the destination of a symbolic link entry, which is recorded in the
+// header for tar-family archives and as the entry's contents for zip archives.
+func symlinkTarget(e *entry) (string, error) {
+	if e.header != nil {
+		return e.header.Linkname, nil
+	}
+
+	reader, err := e.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	linkname, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	return string(linkname), nil
+}
+
+// Struct hardLink describes a hard link to be created during extraction.
+type hardLink struct {
+	oldname string
+	newname string
+	dest    string // the destination directory, within which both names lie
+}
+
+// Returns a hardLink for the link entry extracted to target whose link name
+// is linkname, validating that the link's target lies within dest.
+func newHardLink(dest, target, linkname string) (hardLink, error) {
+	oldname, err := SafeJoin(dest, linkname)
+	if err != nil {
+		return hardLink{}, err
+	}
+
+	return hardLink{oldname: oldname, newname: target, dest: dest}, nil
+}
+
+// Creates the hard link, copying the target's contents if the link cannot
+// be created.
+func (l hardLink) create() error {
+	for _, name := range []string{l.oldname, l.newname} {
+		if err := checkLinkedParents(l.dest, name); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Lstat(l.oldname)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errLinkTargetNotFound, err)
+	} else if info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %q is a symbolic link", ErrUnsafePath, l.oldname)
+	}
+
+	if err := makeDir(filepath.Dir(l.newname)); err != nil {
+		return err
+	}
+
+	if err := os.Remove(l.newname); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+
+	if os.Link(l.oldname, l.newname) == nil {
+		return nil
+	}
+
+	source, err := os.Open(l.oldname)
+	if err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	defer source.Close()
+
+	return writeFile(l.newname, source, info.Mode())
+}
+
+// ExtractFlat extracts every regular file in the archive at archivePath, whose type
+// is determined by DetermineType, directly into dest using only the base name of each
+// entry and ignoring the archive's directory structure. Directories, symbolic and hard
+// links, and other non-regular entries are skipped.
+//
+// Before anything is written, the entry names are scanned in archive order; if two
+// entries share a base name, an error wrapping ErrNameCollision and naming the first
+// such pair is returned and dest is left untouched. For tar-family archives, the scan
+// requires an additional pass over the archive.
+func ExtractFlat(archivePath, dest string) error {
+	if err := checkFlatCollisions(archivePath); err != nil {
+		return err
+	}
+
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Hard links, which header.FileInfo reports as regular files, have no
+		// content of their own.
+		if !e.mode.IsRegular() || (e.header != nil && e.header.Typeflag == tar.TypeLink) {
+			continue
+		}
+
+		target, err := SafeJoin(dest, path.Base(e.name))
+		if err != nil {
+			return err
+		}
+
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(target, reader, e.mode)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Scans the regular file entries of an archive, other than hard links, for base
+// names that collide, reporting the first collision in archive order.
+func checkFlatCollisions(archivePath string) error {
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	seen := make(map[string]string)
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !e.mode.IsRegular() || (e.header != nil && e.header.Typeflag == tar.TypeLink) {
+			continue
+		}
+
+		base := path.Base(e.name)
+		if first, ok := seen[base]; ok {
+			return fmt.Errorf("%w: %q and %q", ErrNameCollision, first, e.name)
+		}
+		seen[base] = e.name
+	}
+}
+
+// SafeJoin joins the entry name to the destination directory dest, returning the cleaned
+// result only if it lies within dest. Names use forward slashes, as in archives, and are
+// converted to the platform's separator. ErrUnsafePath is returned for absolute names and
+// for names whose ".." components would lead outside of dest, the "zip slip" that a
+// naive filepath.Join permits; an error wrapping ErrInvalidEntryName is returned for
+// names that are empty or consist only of whitespace. A name such as "." or "./", as
+// found in archives created with "tar -C dir .", resolves to dest itself, which callers
+// should treat as the destination directory rather than as a file.
+//
+// SafeJoin is purely lexical: it does not resolve symbolic links, so it cannot see
+// where a link already on disk beneath dest, whether planted beforehand or extracted
+// from the same archive, actually leads, and a path it accepts may still resolve to a
+// location outside of dest. Custom extraction callbacks may use it to compute the path
+// of each entry they write, but those that also create symbolic links must refuse to
+// write beneath them, as ExtractAll does, or resolve each path relative to dest as
+// ExtractAllSecure does.
+func SafeJoin(dest, name string) (string, error) {
+	if isBlankName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEntryName, name)
+	}
+
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", ErrUnsafePath
+	}
+
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if !isWithin(dest, target) {
+		return "", ErrUnsafePath
+	}
+
+	return target, nil
+}
+
+// Reports whether an entry name is empty or consists only of whitespace.
+func isBlankName(name string) bool {
+	return strings.TrimSpace(name) == ""
+}
+
+// Reports whether the cleaned form of target lies within dest.
+func isWithin(dest, target string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dest), filepath.Clean(target))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Returns an error wrapping ErrUnsafePath if any existing directory between dest and
+// target, exclusive of both, is a symbolic link, through which target could resolve to
+// a location outside of dest however its name reads.
+func checkLinkedParents(dest, target string) error {
+	dest = filepath.Clean(dest)
+	rel, err := filepath.Rel(dest, filepath.Dir(filepath.Clean(target)))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+
+	dir := dest
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, elem)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			// Nothing beneath a missing directory exists yet.
+			return nil
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q lies beneath a symbolic link", ErrUnsafePath, target)
+		}
+	}
+	return nil
+}
+
+// Creates the directory at target along with any missing parents.
+func makeDir(target string) error {
+	if err := os.MkdirAll(target, extractDirPerm); err != nil {
+		return fmt.Errorf(fmtErrCreateDir, err)
+	}
+	return nil
+}
+
+// Returns the permission bits with which to create a file for an entry of the
+// given mode.
+func filePerm(mode fs.FileMode) fs.FileMode {
+	if perm := mode.Perm(); perm != 0 {
+		return perm
+	}
+	return extractFilePerm
+}
+
+// Writes the contents of reader to a new file at target, creating any missing
+// parent directories. The file receives the permission bits of mode. A symbolic link
+// occupying target is replaced rather than followed. If the contents cannot be written
+// in full, the partially-written file is removed.
+func writeFile(target string, reader io.Reader, mode fs.FileMode) error {
+	if err := makeDir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	// A symbolic link occupying the target is replaced rather than written through.
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf(fmtErrCreateFile, err)
+		}
+	}
+
+	file, err := os.OpenFile(filepath.Clean(target), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm(mode))
+	if err != nil {
+		return fmt.Errorf(fmtErrCreateFile, err)
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(target)
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	return nil
+}
+
+// Writes the contents of reader to a temporary file in the same directory as target,
+// creating any missing parent directories, and renames it to target once it has been
+// written in full and flushed to stable storage. The file receives the permission bits
+// of mode. If anything fails, the temporary file is removed.
+func writeFileAtomic(target string, reader io.Reader, mode fs.FileMode) (err error) {
+	dir, base := filepath.Split(filepath.Clean(target))
+	if err := makeDir(dir); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return fmt.Errorf(fmtErrCreateFile, err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	if err := file.Chmod(filePerm(mode)); err != nil {
+		return fmt.Errorf(fmtErrCreateFile, err)
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	if err := os.Rename(file.Name(), target); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+	return nil
+}
+
+// ExtractSelected extracts the entries of the archive at archivePath, whose type is
+// determined by DetermineType, whose names exactly match those in names into dest,
+// preserving each entry's relative path, and returns the paths written in the order
+// of names. For zip archives, each entry is located directly from the central directory,
+// so the cost is independent of the number of other entries; tar-family archives are
+// read once from start to end. Hard link entries are recreated as by ExtractAll, but
+// their targets must precede them in the archive and be selected as well.
+//
+// If any name would resolve to a location outside of dest, ErrUnsafePath is returned
+// and nothing is extracted. As with ExtractAll, nothing is written through a symbolic
+// link: an entry lying beneath one within dest causes an error wrapping ErrUnsafePath
+// to be returned once it is reached. If any names are not present in the archive, the entries
+// that are present are still extracted, and their paths are returned along with an
+// error wrapping ErrEntryNotFound that lists the missing names.
+func ExtractSelected(archivePath string, names []string, dest string) ([]string, error) {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(names))
+	for _, name := range names {
+		target, err := SafeJoin(dest, name)
+		if err != nil {
+			return nil, err
+		}
+		targets[name] = target
+	}
+
+	found := make(map[string]bool, len(names))
+	extract := func(e *entry) error {
+		target, ok := targets[e.name]
+		if !ok || found[e.name] {
+			return nil
+		}
+		found[e.name] = true
+
+		if e.header != nil && e.header.Typeflag == tar.TypeLink {
+			link, err := newHardLink(dest, target, e.header.Linkname)
+			if err != nil {
+				return err
+			}
+			return link.create()
+		}
+		return extractEntry(e, dest, target, extractConfig{})
+	}
+
+	if typ == Zip {
+		err = extractSelectedZip(archivePath, names, extract)
+	} else {
+		err = forEachEntry(archivePath, extract)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var written, missing []string
+	for _, name := range names {
+		if found[name] {
+			written = append(written, targets[name])
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return written, fmt.Errorf("%w: %q", ErrEntryNotFound, missing)
+	}
+	return written, nil
+}
+
+// Invokes extract for each of the named entries present in a zip archive,
+// locating them by way of the central directory.
+func extractSelectedZip(archivePath string, names []string, extract func(e *entry) error) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	index := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		if _, ok := index[f.Name]; !ok {
+			index[f.Name] = f
+		}
+	}
+
+	for _, name := range names {
+		if f, ok := index[name]; ok {
+			if err := extract(newZipEntry(f)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
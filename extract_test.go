@@ -0,0 +1,764 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var sampleArchives = []string{
+	"testdata/sample.tar",
+	"testdata/sample.tar.bz2",
+	"testdata/sample.tar.gz",
+	"testdata/sample.tar.xz",
+	"testdata/sample.zip",
+}
+
+const (
+	sampleFileName = "sample/text/lorem.txt"
+	sampleFileSize = 803
+)
+
+func TestExtractOne(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		dest := t.TempDir()
+
+		result, err := ExtractOne(archivePath, sampleFileName, dest)
+		if err != nil {
+			t.Errorf("Unexpected error extracting from %s: %v\n", archivePath, err)
+			continue
+		}
+
+		expected := filepath.Join(dest, "sample", "text", "lorem.txt")
+		if result != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, result)
+		}
+
+		info, err := os.Stat(result)
+		if err != nil {
+			t.Errorf("Failed to stat extracted file: %v\n", err)
+		} else if info.Size() != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, info.Size())
+		}
+
+		result, err = ExtractOne(archivePath, "sample/text/", dest)
+		if err != nil {
+			t.Errorf("Unexpected error extracting directory from %s: %v\n", archivePath, err)
+		} else if info, err := os.Stat(result); err != nil || !info.IsDir() {
+			t.Errorf("Failed to extract directory entry from %s.\n", archivePath)
+		}
+
+		if _, err := ExtractOne(archivePath, "sample/missing.txt", dest); err != ErrEntryNotFound {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+		}
+
+		if _, err := ExtractOne(archivePath, "../lorem.txt", dest); err != ErrUnsafePath {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+		}
+	}
+
+	if _, err := ExtractOne("foo.123", sampleFileName, t.TempDir()); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+type safeJoinTest struct {
+	name     string
+	expected string
+	err      error
+}
+
+var safeJoins = []safeJoinTest{
+	{"foo.txt", "dest/foo.txt", nil},
+	{"a/b/c.txt", "dest/a/b/c.txt", nil},
+	{"a/../b.txt", "dest/b.txt", nil},
+	{"foo//bar/./baz", "dest/foo/bar/baz", nil},
+	{"../foo.txt", "", ErrUnsafePath},
+	{"a/../../foo.txt", "", ErrUnsafePath},
+	{"/etc/passwd", "", ErrUnsafePath},
+	{"", "", ErrInvalidEntryName},
+	{" \t ", "", ErrInvalidEntryName},
+	{"..", "", ErrUnsafePath},
+	{".", "dest", nil},
+	{"./", "dest", nil},
+}
+
+func TestSafeJoin(t *testing.T) {
+	for _, c := range safeJoins {
+		result, err := SafeJoin("dest", c.name)
+
+		if result != filepath.FromSlash(c.expected) {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expected, result)
+		}
+
+		if !errors.Is(err, c.err) || (c.err == nil) != (err == nil) {
+			t.Errorf("Expecting '%v', got '%v'\n", c.err, err)
+		}
+	}
+}
+
+func TestExtractAll_invalidName(t *testing.T) {
+	paths := []string{
+		"testdata/emptyname.zip",
+		writeTestArchive(t, "blank.tar", []testEntry{{name: "readme.txt", body: "lorem"}, {name: "   ", body: "ipsum"}}),
+	}
+
+	for _, path := range paths {
+		dest := t.TempDir()
+		if err := ExtractAll(path, dest); !errors.Is(err, ErrInvalidEntryName) {
+			t.Errorf("%s: expecting '%s', got '%v'\n", path, ErrInvalidEntryName, err)
+		}
+
+		if entries, _ := os.ReadDir(dest); len(entries) != 1 || entries[0].Name() != "readme.txt" {
+			t.Errorf("%s: expected only readme.txt to be extracted, got %v\n", path, entries)
+		}
+	}
+}
+
+var flatEntries = []testEntry{
+	{name: "a/"},
+	{name: "a/one.txt", body: "one"},
+	{name: "a/b/two.txt", body: "two"},
+	{name: "three.txt", body: "three"},
+}
+
+func TestExtractFlat(t *testing.T) {
+	for _, filename := range []string{"flat.tar.gz", "flat.zip"} {
+		path := writeTestArchive(t, filename, flatEntries)
+		dest := t.TempDir()
+
+		if err := ExtractFlat(path, dest); err != nil {
+			t.Errorf("Unexpected error extracting %s: %v\n", filename, err)
+		}
+
+		for _, name := range []string{"one.txt", "two.txt", "three.txt"} {
+			if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+				t.Errorf("%s: expected %s to be extracted: %v\n", filename, name, err)
+			}
+		}
+
+		entries, err := os.ReadDir(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Errorf("Expecting '%d', got '%d'\n", 3, len(entries))
+		}
+	}
+
+	collisions := append(flatEntries[:len(flatEntries):len(flatEntries)],
+		testEntry{name: "c/two.txt", body: "two again"},
+		testEntry{name: "d/three.txt", body: "three again"},
+	)
+	for _, filename := range []string{"collide.tar", "collide.zip"} {
+		path := writeTestArchive(t, filename, collisions)
+		dest := t.TempDir()
+
+		err := ExtractFlat(path, dest)
+		if !errors.Is(err, ErrNameCollision) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrNameCollision, err)
+		}
+
+		expected := `archive: entries collide when flattened: "a/b/two.txt" and "c/two.txt"`
+		if err != nil && err.Error() != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, err)
+		}
+
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("Expected no files to be written, got %d\n", len(entries))
+		}
+	}
+}
+
+func TestExtractFlat_hardLinks(t *testing.T) {
+	entries := append(flatEntries[:len(flatEntries):len(flatEntries)],
+		testEntry{name: "c/one.txt", typeflag: tar.TypeLink, linkname: "a/one.txt"},
+		testEntry{name: "link.txt", typeflag: tar.TypeLink, linkname: "three.txt"},
+	)
+	path := writeTestArchive(t, "links.tar", entries)
+	dest := t.TempDir()
+
+	if err := ExtractFlat(path, dest); err != nil {
+		t.Fatalf("Unexpected error extracting %s: %v\n", path, err)
+	}
+
+	files, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("Expecting '%d', got '%d'\n", 3, len(files))
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dest, "one.txt")); err != nil || string(data) != "one" {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "one", data, err)
+	}
+}
+
+func TestExtractAllWithOptions_cleanNames(t *testing.T) {
+	path := writeTestArchive(t, "clean.tar", []testEntry{
+		{name: "./foo/"},
+		{name: "foo//bar/./baz.txt", body: "lorem"},
+		{name: "foo/./null", typeflag: tar.TypeChar},
+	})
+
+	for _, clean := range []bool{false, true} {
+		dest := t.TempDir()
+		var warnings []string
+		opts := ExtractOptions{CleanNames: clean, Warn: func(err error) { warnings = append(warnings, err.Error()) }}
+		if err := ExtractAllWithOptions(path, dest, opts); err != nil {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+
+		if data, err := os.ReadFile(filepath.Join(dest, "foo", "bar", "baz.txt")); err != nil || string(data) != "lorem" {
+			t.Errorf("%t: expecting '%s', got '%s' (error: %v)\n", clean, "lorem", data, err)
+		}
+
+		name := "foo/./null"
+		if clean {
+			name = "foo/null"
+		}
+		expected := []string{`archive: device entry skipped: "` + name + `" (0, 0)`}
+		if !reflect.DeepEqual(warnings, expected) {
+			t.Errorf("%t: expecting '%v', got '%v'\n", clean, expected, warnings)
+		}
+	}
+}
+
+func TestExtractAll(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		dest := t.TempDir()
+
+		if err := ExtractAll(archivePath, dest); err != nil {
+			t.Errorf("Unexpected error extracting %s: %v\n", archivePath, err)
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(sampleFileName)))
+		if err != nil {
+			t.Errorf("Failed to stat extracted file: %v\n", err)
+		} else if info.Size() != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, info.Size())
+		}
+	}
+}
+
+func TestExtractAll_emptyDirectories(t *testing.T) {
+	paths := []string{
+		"testdata/emptydir.tar",
+		writeTestArchive(t, "emptydir.zip", []testEntry{{name: "emptydir/empty/"}, {name: "emptydir/nested/deeper/empty2/"}}),
+	}
+	extractors := map[string]func(string, string) error{"ExtractAll": ExtractAll, "ExtractAllSecure": ExtractAllSecure}
+
+	for _, path := range paths {
+		for name, extract := range extractors {
+			dest := t.TempDir()
+			if err := extract(path, dest); err != nil {
+				t.Errorf("%s: unexpected error extracting %s: %v\n", name, path, err)
+				continue
+			}
+
+			for _, dir := range []string{"emptydir/empty", "emptydir/nested/deeper/empty2"} {
+				info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(dir)))
+				if err != nil || !info.IsDir() {
+					t.Errorf("%s: expecting %s from %s to be a directory (error: %v)\n", name, dir, path, err)
+					continue
+				}
+				if entries, _ := os.ReadDir(filepath.Join(dest, filepath.FromSlash(dir))); len(entries) != 0 {
+					t.Errorf("%s: expecting %s to be empty, got %v\n", name, dir, entries)
+				}
+			}
+		}
+	}
+}
+
+var hardLinkEntries = []testEntry{
+	{name: "dir/"},
+	{name: "early.txt", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+	{name: "dir/a.txt", body: "lorem ipsum"},
+	{name: "dir/b.txt", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+	{name: "dir/c", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+}
+
+func TestExtractAll_links(t *testing.T) {
+	path := writeTestArchive(t, "links.tar", hardLinkEntries)
+	dest := t.TempDir()
+
+	if err := ExtractAll(path, dest); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	original, err := os.Stat(filepath.Join(dest, "dir", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"early.txt", "dir/b.txt"} {
+		linked, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("Failed to stat hard link %s: %v\n", name, err)
+		} else if !os.SameFile(original, linked) {
+			t.Errorf("Expected %s to be a hard link to dir/a.txt.\n", name)
+		}
+	}
+
+	linkname, err := os.Readlink(filepath.Join(dest, "dir", "c"))
+	if err != nil || linkname != "a.txt" {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "a.txt", linkname, err)
+	}
+}
+
+var unsafeLinkTests = [][]testEntry{
+	{{name: "evil", typeflag: tar.TypeSymlink, linkname: "../outside"}},
+	{{name: "evil", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+	{{name: "dir/evil", typeflag: tar.TypeSymlink, linkname: "../../outside"}},
+	{{name: "evil", typeflag: tar.TypeLink, linkname: "../outside"}},
+	{{name: "../evil.txt", body: "lorem"}},
+}
+
+func TestExtractAll_errors(t *testing.T) {
+	for _, entries := range unsafeLinkTests {
+		for _, filename := range []string{"unsafe.tar", "unsafe.zip"} {
+			if filename == "unsafe.zip" && entries[0].typeflag == tar.TypeLink {
+				continue
+			}
+
+			path := writeTestArchive(t, filename, entries)
+			if err := ExtractAll(path, t.TempDir()); err != ErrUnsafePath {
+				t.Errorf("%s %s: expecting '%s', got '%v'\n", filename, entries[0].name, ErrUnsafePath, err)
+			}
+		}
+	}
+
+	path := writeTestArchive(t, "missing.tar", []testEntry{
+		{name: "link.txt", typeflag: tar.TypeLink, linkname: "missing.txt"},
+	})
+	if err := ExtractAll(path, t.TempDir()); !errors.Is(err, errLinkTargetNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", errLinkTargetNotFound, err)
+	}
+
+	if err := ExtractAll("testdata/invalid.tar", t.TempDir()); err == nil {
+		t.Error("Failed to receive non-nil error when extracting an invalid tar file.")
+	}
+}
+
+// Entries whose symbolic links each lead within the destination but which together
+// lead "b" to its parent, through which the final entry would be written.
+var escapingLinkEntries = []testEntry{
+	{name: "a", typeflag: tar.TypeSymlink, linkname: "."},
+	{name: "a/b", typeflag: tar.TypeSymlink, linkname: ".."},
+	{name: "b/escaped.txt", body: "lorem"},
+}
+
+// Returns a destination directory within a fresh parent directory, in which the
+// symbolic link "b" leads to the parent if planted is true.
+func escapeDest(t *testing.T, planted bool) (parent, dest string) {
+	t.Helper()
+
+	parent = t.TempDir()
+	dest = filepath.Join(parent, "dest")
+	if err := os.Mkdir(dest, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if planted {
+		if err := os.Symlink("..", filepath.Join(dest, "b")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parent, dest
+}
+
+func TestExtractAll_escapingLinks(t *testing.T) {
+	extractors := map[string]func(path, dest string) error{
+		"ExtractAll": ExtractAll,
+		"ExtractAllSkipExisting": func(path, dest string) error {
+			_, _, err := ExtractAllSkipExisting(path, dest)
+			return err
+		},
+		"ExtractAllWithBudget": func(path, dest string) error {
+			_, err := ExtractAllWithBudget(path, dest, 1<<20)
+			return err
+		},
+		"ExtractSelected": func(path, dest string) error {
+			_, err := ExtractSelected(path, []string{"a", "a/b", "b/escaped.txt"}, dest)
+			return err
+		},
+		"ExtractAllWithOptions": func(path, dest string) error {
+			return ExtractAllWithOptions(path, dest, ExtractOptions{OnCollision: Rename, Atomic: true})
+		},
+	}
+
+	for _, filename := range []string{"escaping.tar", "escaping.zip"} {
+		path := writeTestArchive(t, filename, escapingLinkEntries)
+
+		for name, extract := range extractors {
+			for _, planted := range []bool{false, true} {
+				parent, dest := escapeDest(t, planted)
+				if err := extract(path, dest); !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("%s %s: expecting '%s', got '%v'\n", name, filename, ErrUnsafePath, err)
+				}
+				if _, err := os.Lstat(filepath.Join(parent, "escaped.txt")); err == nil {
+					t.Errorf("%s %s: wrote a file outside of the destination.\n", name, filename)
+				}
+			}
+		}
+	}
+
+	path := writeTestArchive(t, "escaping.tar", escapingLinkEntries)
+	parent, dest := escapeDest(t, true)
+	if _, err := ExtractOne(path, "b/escaped.txt", dest); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "escaped.txt")); err == nil {
+		t.Error("ExtractOne wrote a file outside of the destination.")
+	}
+
+	path = writeTestArchive(t, "device.tar", []testEntry{{name: "b/null", typeflag: tar.TypeChar}})
+	parent, dest = escapeDest(t, true)
+	if err := ExtractAllWithOptions(path, dest, ExtractOptions{RecreateDevices: true}); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "null")); err == nil {
+		t.Error("ExtractAllWithOptions created a device outside of the destination.")
+	}
+
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path = writeTestArchive(t, "hardlink.tar", []testEntry{{name: "c", typeflag: tar.TypeLink, linkname: "b/secret.txt"}})
+	if err := ExtractAll(path, dest); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "c")); err == nil {
+		t.Error("ExtractAll linked a file from outside of the destination.")
+	}
+}
+
+func TestWriteFile_symlink(t *testing.T) {
+	parent, dest := escapeDest(t, false)
+	if err := os.Symlink(filepath.Join(parent, "outside.txt"), filepath.Join(dest, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(filepath.Join(dest, "file.txt"), strings.NewReader("lorem"), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "outside.txt")); err == nil {
+		t.Error("Wrote through a symbolic link to a file outside of the destination.")
+	}
+	if info, err := os.Lstat(filepath.Join(dest, "file.txt")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Expected the symbolic link to be replaced by a regular file, got '%v' (error: %v)\n", info, err)
+	}
+}
+
+func TestExtractAllSkipExisting(t *testing.T) {
+	for _, filename := range []string{"skip.tar.gz", "skip.zip"} {
+		path := writeTestArchive(t, filename, contentEntries)
+		dest := t.TempDir()
+
+		written, skipped, err := ExtractAllSkipExisting(path, dest)
+		if written != 4 || skipped != 0 || err != nil {
+			t.Errorf("%s: expecting 4 written and 0 skipped, got %d and %d (error: %v)\n", filename, written, skipped, err)
+		}
+
+		written, skipped, err = ExtractAllSkipExisting(path, dest)
+		if written != 0 || skipped != 4 || err != nil {
+			t.Errorf("%s: expecting 0 written and 4 skipped, got %d and %d (error: %v)\n", filename, written, skipped, err)
+		}
+
+		// Same size but different content or time; and different size.
+		changed := filepath.Join(dest, "b.txt")
+		if err := os.WriteFile(changed, []byte("IPSUM "), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dest, "c.txt"), []byte("dolor sit amet"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(filepath.Join(dest, "dir", "a.txt")); err != nil {
+			t.Fatal(err)
+		}
+
+		written, skipped, err = ExtractAllSkipExisting(path, dest)
+		if written != 3 || skipped != 1 || err != nil {
+			t.Errorf("%s: expecting 3 written and 1 skipped, got %d and %d (error: %v)\n", filename, written, skipped, err)
+		}
+
+		if content, _ := os.ReadFile(changed); string(content) != "ipsum " {
+			t.Errorf("Expecting '%s', got '%s'\n", "ipsum ", content)
+		}
+	}
+
+	if _, _, err := ExtractAllSkipExisting("testdata/invalid.tar", t.TempDir()); err == nil {
+		t.Error("Failed to receive non-nil error when extracting an invalid tar file.")
+	}
+}
+
+var budgetEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: strings.Repeat("a", 100)},
+	{name: "dir/b.txt", body: strings.Repeat("b", 200)},
+	{name: "dir/c.txt", body: strings.Repeat("c", 300)},
+}
+
+func TestExtractAllWithBudget(t *testing.T) {
+	for _, filename := range []string{"budget.tar.gz", "budget.zip"} {
+		path := writeTestArchive(t, filename, budgetEntries)
+
+		dest := t.TempDir()
+		n, err := ExtractAllWithBudget(path, dest, 600)
+		if n != 600 || err != nil {
+			t.Errorf("%s: expecting '%d', got '%d' (error: %v)\n", filename, 600, n, err)
+		}
+
+		dest = t.TempDir()
+		n, err = ExtractAllWithBudget(path, dest, 450)
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrLimitExceeded, err)
+		}
+		if n != 300 {
+			t.Errorf("%s: expecting '%d', got '%d'\n", filename, 300, n)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "dir", "b.txt")); err != nil {
+			t.Errorf("Expected dir/b.txt to be extracted: %v\n", err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "dir", "c.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected dir/c.txt not to be extracted: %v\n", err)
+		}
+	}
+}
+
+func TestWriteFile_budget(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "partial.txt")
+	remaining := int64(10)
+
+	err := writeFile(target, &budgetReader{reader: strings.NewReader(strings.Repeat("x", 100)), remaining: &remaining}, 0600)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrLimitExceeded, err)
+	}
+
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected partially-written file to be removed: %v\n", err)
+	}
+}
+
+var collisionEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "first"},
+	{name: "dir/a.txt", body: "second"},
+	{name: "dir/b.txt", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+	{name: ".profile", body: "profile"},
+}
+
+var collisionTests = []struct {
+	policy CollisionPolicy
+	files  map[string]string
+}{
+	{0, map[string]string{"dir/a.txt": "second", "dir/b.txt": "second", ".profile": "profile"}},
+	{Overwrite, map[string]string{"dir/a.txt": "second", "dir/b.txt": "second", ".profile": "profile"}},
+	{Skip, map[string]string{"dir/a.txt": "first", "dir/b.txt": "first", ".profile": "existing"}},
+	{Rename, map[string]string{
+		"dir/a.txt": "first", "dir/a (1).txt": "second", "dir/b.txt": "second",
+		".profile": "existing", ".profile (1)": "profile",
+	}},
+}
+
+func TestExtractAllWithOptions(t *testing.T) {
+	path := writeTestArchive(t, "collision.tar", collisionEntries)
+
+	for _, test := range collisionTests {
+		dest := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dest, ".profile"), []byte("existing"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ExtractAllWithOptions(path, dest, ExtractOptions{OnCollision: test.policy}); err != nil {
+			t.Errorf("%s: unexpected error: %v\n", test.policy, err)
+			continue
+		}
+
+		for name, expected := range test.files {
+			content, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+			if string(content) != expected {
+				t.Errorf("%s: %s: expecting '%s', got '%s' (error: %v)\n", test.policy, name, expected, content, err)
+			}
+		}
+	}
+
+	err := ExtractAllWithOptions(path, t.TempDir(), ExtractOptions{OnCollision: Error})
+	if !errors.Is(err, ErrTargetExists) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTargetExists, err)
+	}
+
+	if err := ExtractAllWithOptions(path, t.TempDir(), ExtractOptions{OnCollision: Error + 1}); err != errUnknownCollisionPolicy {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownCollisionPolicy, err)
+	}
+}
+
+func TestExtractAllWithOptions_atomic(t *testing.T) {
+	for _, filename := range []string{"atomic.tar.gz", "atomic.zip"} {
+		path := writeTestArchive(t, filename, budgetEntries)
+		dest := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dest, "existing.txt"), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ExtractAllWithOptions(path, dest, ExtractOptions{Atomic: true}); err != nil {
+			t.Fatalf("%s: unexpected error: %v\n", filename, err)
+		}
+
+		for _, e := range budgetEntries[1:] {
+			content, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(e.name)))
+			if string(content) != e.body || err != nil {
+				t.Errorf("%s: %s: expecting %d bytes, got %d (error: %v)\n", filename, e.name, len(e.body), len(content), err)
+			}
+		}
+
+		if entries, _ := os.ReadDir(filepath.Join(dest, "dir")); len(entries) != 3 {
+			t.Errorf("%s: expected no temporary files to remain, got %v\n", filename, entries)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(target, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := int64(10)
+	err := writeFileAtomic(target, &budgetReader{reader: strings.NewReader(strings.Repeat("x", 100)), remaining: &remaining}, 0600)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrLimitExceeded, err)
+	}
+
+	if content, _ := os.ReadFile(target); string(content) != "original" {
+		t.Errorf("Expecting '%s', got '%s'\n", "original", content)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v\n", entries)
+	}
+
+	if err := writeFileAtomic(target, strings.NewReader("replaced"), 0640); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expecting '%s', got '%v' (error: %v)\n", fs.FileMode(0640), info.Mode().Perm(), err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "replaced" {
+		t.Errorf("Expecting '%s', got '%s'\n", "replaced", content)
+	}
+}
+
+func TestRenameTarget(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.tar.gz", "a.tar (1).gz", "README"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, expected := range map[string]string{"a.tar.gz": "a.tar (2).gz", "README": "README (1)"} {
+		renamed, err := renameTarget(filepath.Join(dir, name))
+		if renamed != filepath.Join(dir, expected) || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", expected, filepath.Base(renamed), err)
+		}
+	}
+}
+
+func TestExtractSelected(t *testing.T) {
+	for _, filename := range []string{"selected.tar.xz", "selected.zip"} {
+		path := writeTestArchive(t, filename, flatEntries)
+		dest := t.TempDir()
+
+		written, err := ExtractSelected(path, []string{"three.txt", "a/b/two.txt"}, dest)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+
+		expected := []string{filepath.Join(dest, "three.txt"), filepath.Join(dest, "a", "b", "two.txt")}
+		if !reflect.DeepEqual(written, expected) {
+			t.Errorf("Expecting '%v', got '%v'\n", expected, written)
+		}
+
+		if content, err := os.ReadFile(expected[1]); string(content) != "two" || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "two", content, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dest, "a", "one.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected a/one.txt not to be extracted: %v\n", err)
+		}
+
+		dest = t.TempDir()
+		written, err = ExtractSelected(path, []string{"missing.txt", "a/one.txt", "b/gone.txt"}, dest)
+		if !errors.Is(err, ErrEntryNotFound) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+		}
+
+		expectedErr := `archive: entry not found: ["missing.txt" "b/gone.txt"]`
+		if err != nil && err.Error() != expectedErr {
+			t.Errorf("Expecting '%s', got '%s'\n", expectedErr, err)
+		}
+
+		if len(written) != 1 || written[0] != filepath.Join(dest, "a", "one.txt") {
+			t.Errorf("Unexpected paths written: %v\n", written)
+		}
+
+		if _, err := ExtractSelected(path, []string{"three.txt", "../evil.txt"}, t.TempDir()); err != ErrUnsafePath {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+		}
+	}
+
+	path := writeTestArchive(t, "links.tar", hardLinkEntries)
+	dest := t.TempDir()
+	if _, err := ExtractSelected(path, []string{"dir/a.txt", "dir/b.txt"}, dest); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	original, _ := os.Stat(filepath.Join(dest, "dir", "a.txt"))
+	linked, _ := os.Stat(filepath.Join(dest, "dir", "b.txt"))
+	if original == nil || linked == nil || !os.SameFile(original, linked) {
+		t.Error("Expected dir/b.txt to be a hard link to dir/a.txt.")
+	}
+
+	if _, err := ExtractSelected("foo.123", nil, t.TempDir()); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestExtractLargest(t *testing.T) {
+	entries := []testEntry{
+		{name: "dir/"},
+		{name: "dir/small.txt", body: "lorem"},
+		{name: "dir/payload.bin", body: strings.Repeat("x", 4096)},
+		{name: "dir/link", typeflag: tar.TypeSymlink, linkname: strings.Repeat("y", 8192)},
+		{name: "tie.bin", body: strings.Repeat("z", 4096)},
+		{name: "last.txt", body: "ipsum"},
+	}
+
+	for _, filename := range []string{"largest.tar.gz", "largest.zip"} {
+		var buf bytes.Buffer
+		name, n, err := ExtractLargest(writeTestArchive(t, filename, entries), &buf)
+		if name != "dir/payload.bin" || n != 4096 || buf.String() != entries[2].body || err != nil {
+			t.Errorf("%s: expecting dir/payload.bin (4096 bytes), got %s (%d bytes, %d written, error: %v)\n", filename, name, n, buf.Len(), err)
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if name, n, err := ExtractLargest(archivePath, io.Discard); name != sampleFileName || n != sampleFileSize || err != nil {
+			t.Errorf("Expecting '%s' (%d), got '%s' (%d) (error: %v)\n", sampleFileName, sampleFileSize, name, n, err)
+		}
+	}
+
+	empty := writeTestArchive(t, "dirs.tar", []testEntry{{name: "dir/"}})
+	if _, _, err := ExtractLargest(empty, io.Discard); err != ErrEntryNotFound {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+	}
+}
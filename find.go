@@ -0,0 +1,25 @@
+package archive
+
+import (
+	"regexp"
+)
+
+// Find returns the details of every entry of the archive at archivePath, whose type is
+// determined by DetermineType, whose full name is matched by pattern. Matches are
+// returned in archive order, and the archive is read only once. Patterns are matched
+// against any part of the name unless anchored, so `\.go$` matches every name ending
+// in ".go". A nil pattern matches every entry.
+func Find(archivePath string, pattern *regexp.Regexp) ([]EntryInfo, error) {
+	var matches []EntryInfo
+	err := forEachEntry(archivePath, func(e *entry) error {
+		if pattern == nil || pattern.MatchString(e.name) {
+			matches = append(matches, e.info())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,66 @@
+package archive
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var findEntries = []testEntry{
+	{name: "src/"},
+	{name: "src/main.go", body: "package main"},
+	{name: "src/main_test.go", body: "package main"},
+	{name: "src/util/strings.go", body: "package util"},
+	{name: "docs/readme.md", body: "# readme"},
+	{name: "go.mod", body: "module example"},
+}
+
+type findTest struct {
+	pattern  *regexp.Regexp
+	expected []string
+}
+
+var finds = []findTest{
+	{regexp.MustCompile(`\.go$`), []string{"src/main.go", "src/main_test.go", "src/util/strings.go"}},
+	{regexp.MustCompile(`^src/[^/]+\.go$`), []string{"src/main.go", "src/main_test.go"}},
+	{regexp.MustCompile(`(?i)README`), []string{"docs/readme.md"}},
+	{regexp.MustCompile(`/$`), []string{"src/"}},
+	{regexp.MustCompile(`\.rs$`), nil},
+	{nil, []string{"src/", "src/main.go", "src/main_test.go", "src/util/strings.go", "docs/readme.md", "go.mod"}},
+}
+
+func TestFind(t *testing.T) {
+	for _, filename := range []string{"find.tar.gz", "find.zip"} {
+		path := writeTestArchive(t, filename, findEntries)
+
+		for _, c := range finds {
+			matches, err := Find(path, c.pattern)
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", filename, err)
+			}
+
+			var names []string
+			for _, m := range matches {
+				names = append(names, m.Name)
+			}
+			if !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("%s %v: expecting '%v', got '%v'\n", filename, c.pattern, c.expected, names)
+			}
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		matches, err := Find(archivePath, regexp.MustCompile(`lorem`))
+		if err != nil || len(matches) != 1 {
+			t.Errorf("Expecting 1 match, got %d (error: %v)\n", len(matches), err)
+			continue
+		}
+		if m := matches[0]; m.Name != sampleFileName || m.Size != sampleFileSize || m.IsDir() || m.ModTime.IsZero() {
+			t.Errorf("Unexpected match: %+v\n", m)
+		}
+	}
+
+	if _, err := Find("foo.123", nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
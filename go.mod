@@ -1,7 +1,11 @@
 module github.com/kristinjeanna/archive
 
-go 1.17
+go 1.23
 
 require github.com/ulikunitz/xz v0.5.10
 
 require go.uber.org/goleak v1.2.0
+
+require golang.org/x/sys v0.30.0
+
+require golang.org/x/text v0.22.0
@@ -0,0 +1,381 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Format strings for gzip-related errors
+const (
+	fmtErrGzipHeaderRead string = "archive: failed to read gzip header: %v"
+	fmtErrGzipChecksum   string = "archive: gzip trailer does not match the %d bytes of decompressed data: %w"
+	fmtErrGzipTruncated  string = "archive: gzip stream truncated after %d bytes of decompressed data: %w"
+	fmtErrGzipTrailing   string = "archive: invalid data follows gzip stream after %d bytes of decompressed data: %w"
+	fmtErrGzipHeaderCRC  string = "%w: header records %#04x but its contents give %#04x"
+	fmtErrGzipHeaderBad  string = "archive: invalid gzip header: %s: %w"
+)
+
+// ErrGzipHeaderChecksum is returned by ValidateGzipHeader when the CRC-16 recorded in a
+// gzip header does not match the header's contents.
+var ErrGzipHeaderChecksum = errors.New("archive: gzip header checksum mismatch")
+
+// errNotGzip is returned when a file expected to be gzip-compressed does not
+// begin with the gzip magic bytes.
+var errNotGzip = errors.New("archive: not a gzip file")
+
+// Offsets and values within the fixed-length portion of a gzip member header
+// (RFC 1952, section 2.3).
+const (
+	gzipHeaderLen    = 10
+	gzipID1          = 0x1f
+	gzipID2          = 0x8b
+	gzipCMOffset     = 2
+	gzipFlgOffset    = 3
+	gzipXflOffset    = 8
+	gzipXflBest      = 2
+	gzipXflFastest   = 4
+	gzipUnknownLevel = -1
+)
+
+// Compression method and flags of a gzip member header (RFC 1952, section 2.3.1).
+const (
+	gzipMethodDeflate = 8
+	gzipFlagHCRC      = 1 << 1
+	gzipFlagExtra     = 1 << 2
+	gzipFlagName      = 1 << 3
+	gzipFlagComment   = 1 << 4
+	gzipFlagReserved  = 0xe0
+)
+
+// GzipCompressionLevel infers the approximate compression level used to create the
+// gzip file at path from the XFL (extra flags) byte of its header. An XFL value of 2
+// yields gzip.BestCompression and a value of 4 yields gzip.BestSpeed. Any other value
+// carries no level information and yields -1. A non-nil error is returned if the file
+// cannot be read or is not gzip-compressed.
+func GzipCompressionLevel(path string) (int, error) {
+	header, err := readGzipHeader(path)
+	if err != nil {
+		return gzipUnknownLevel, err
+	}
+
+	switch header[gzipXflOffset] {
+	case gzipXflBest:
+		return gzip.BestCompression, nil
+	case gzipXflFastest:
+		return gzip.BestSpeed, nil
+	}
+
+	return gzipUnknownLevel, nil
+}
+
+// Reads and validates the fixed-length gzip header at the start of the file.
+func readGzipHeader(path string) ([]byte, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, gzipHeaderLen)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, fmt.Errorf(fmtErrGzipHeaderRead, err)
+	}
+
+	if header[0] != gzipID1 || header[1] != gzipID2 {
+		return nil, errNotGzip
+	}
+
+	return header, nil
+}
+
+// GzipMembers returns the byte offsets at which each member of the gzip file at path
+// begins. A gzip file may consist of several members concatenated together, as produced
+// by parallel compressors or by appending to an existing file, and each member can be
+// decompressed independently of the others. Every member is decompressed in full to
+// find where it ends and the next begins, so the first offset is always zero and a
+// single-member file yields a single offset. A non-nil error is returned if the file is
+// not gzip-compressed, if any member is corrupt, or if the last member is followed by
+// anything other than another member.
+func GzipMembers(path string) ([]int64, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	// The gzip reader reads no further than it must when given an io.ByteReader, so
+	// the count of bytes consumed locates the end of each member exactly.
+	buffered := bufio.NewReader(file)
+	counter := &countingByteReader{reader: buffered}
+
+	reader, err := gzip.NewReader(counter)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrNewGzReader, err)
+	}
+	defer reader.Close()
+
+	offsets := []int64{0}
+	for {
+		reader.Multistream(false)
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return nil, fmt.Errorf(fmtErrDecompress, err)
+		}
+
+		if _, err := buffered.Peek(1); err == io.EOF {
+			return offsets, nil
+		}
+
+		offset := counter.n
+		if err := reader.Reset(counter); err != nil {
+			return nil, fmt.Errorf(fmtErrNewGzReader, err)
+		}
+		offsets = append(offsets, offset)
+	}
+}
+
+// VerifyGzipIntegrity decompresses the gzip file at path in its entirety, returning nil
+// if every member of the file decompresses cleanly and ends with a trailer whose CRC-32
+// checksum and size match the decompressed data. Truncated and corrupt files, which would
+// otherwise fail partway through a walk with a less helpful error, are reported with an
+// error that describes the problem and how much data was decompressed before it was
+// found: a trailer mismatch wraps gzip.ErrChecksum, a truncated file wraps
+// io.ErrUnexpectedEOF, and data following the last member that is not another member
+// wraps gzip.ErrHeader. A non-nil error is also returned if the file cannot be read or
+// is not gzip-compressed.
+func VerifyGzipIntegrity(path string) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf(fmtErrNewGzReader, err)
+	}
+	defer reader.Close()
+
+	n, err := io.Copy(io.Discard, reader)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gzip.ErrChecksum):
+		return fmt.Errorf(fmtErrGzipChecksum, n, err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf(fmtErrGzipTruncated, n, err)
+	case errors.Is(err, gzip.ErrHeader):
+		return fmt.Errorf(fmtErrGzipTrailing, n, err)
+	}
+
+	return fmt.Errorf(fmtErrDecompress, err)
+}
+
+// ValidateGzipHeader reads the header of the first member of the gzip file at path,
+// including any optional extra field, file name, and comment, and checks it without
+// decompressing any data. When the header's FHCRC flag is set, the CRC-16 it records,
+// the low 16 bits of the CRC-32 of the header's preceding bytes, is verified, and a
+// mismatch yields an error wrapping ErrGzipHeaderChecksum that gives both values. The
+// gzip reader of the standard library also verifies the header CRC, but reports a
+// mismatch as gzip.ErrHeader, indistinguishable from any other malformed header, which
+// makes a damaged header hard to tell from damaged data; this reports the header's
+// condition alone, leaving the data to VerifyGzipIntegrity. A header with an unknown
+// compression method or reserved flags set yields an error wrapping gzip.ErrHeader.
+// A non-nil error is also returned if the file cannot be read, is not
+// gzip-compressed, or ends within the header.
+func ValidateGzipHeader(path string) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	// The header's bytes are accumulated in raw, over which the CRC is computed.
+	reader := bufio.NewReader(file)
+	raw := make([]byte, gzipHeaderLen)
+	if _, err := io.ReadFull(reader, raw); err != nil {
+		return fmt.Errorf(fmtErrGzipHeaderRead, err)
+	}
+	if raw[0] != gzipID1 || raw[1] != gzipID2 {
+		return errNotGzip
+	}
+	if raw[gzipCMOffset] != gzipMethodDeflate {
+		return fmt.Errorf(fmtErrGzipHeaderBad, fmt.Sprintf("unknown compression method %d", raw[gzipCMOffset]), gzip.ErrHeader)
+	}
+	flags := raw[gzipFlgOffset]
+	if flags&gzipFlagReserved != 0 {
+		return fmt.Errorf(fmtErrGzipHeaderBad, fmt.Sprintf("reserved flags %#02x set", flags&gzipFlagReserved), gzip.ErrHeader)
+	}
+
+	readN := func(n int) error {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return fmt.Errorf(fmtErrGzipHeaderRead, err)
+		}
+		raw = append(raw, buf...)
+		return nil
+	}
+
+	if flags&gzipFlagExtra != 0 {
+		if err := readN(2); err != nil {
+			return err
+		}
+		if err := readN(int(binary.LittleEndian.Uint16(raw[len(raw)-2:]))); err != nil {
+			return err
+		}
+	}
+	for _, flag := range []byte{gzipFlagName, gzipFlagComment} {
+		if flags&flag == 0 {
+			continue
+		}
+		field, err := reader.ReadBytes(0)
+		if err != nil {
+			return fmt.Errorf(fmtErrGzipHeaderRead, err)
+		}
+		raw = append(raw, field...)
+	}
+
+	if flags&gzipFlagHCRC == 0 {
+		return nil
+	}
+
+	recorded := make([]byte, 2)
+	if _, err := io.ReadFull(reader, recorded); err != nil {
+		return fmt.Errorf(fmtErrGzipHeaderRead, err)
+	}
+	if want, got := binary.LittleEndian.Uint16(recorded), uint16(crc32.ChecksumIEEE(raw)); want != got {
+		return fmt.Errorf(fmtErrGzipHeaderCRC, ErrGzipHeaderChecksum, want, got)
+	}
+	return nil
+}
+
+// Struct countingByteReader counts the bytes read through a buffered reader.
+type countingByteReader struct {
+	reader *bufio.Reader
+	n      int64
+}
+
+// Read reads from the underlying reader, adding to the count of bytes read.
+func (c *countingByteReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadByte reads a single byte from the underlying reader, adding to the count
+// of bytes read.
+func (c *countingByteReader) ReadByte() (byte, error) {
+	b, err := c.reader.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Bounds on how far WalkTarGzPipelined decompresses ahead of the walk: up to
+// pipelineChunks chunks of pipelineChunkSize bytes are held awaiting the walk.
+const (
+	pipelineChunkSize = 256 << 10
+	pipelineChunks    = 16
+)
+
+// WalkTarGzPipelined walks the contents of a gzip-compressed tar file and invokes the
+// callback function for each entry, as WalkTarGz does, but decompresses the archive in a
+// separate goroutine that runs up to 4 MiB ahead of the walk, handing the decompressed
+// data over in 256 KiB chunks through a buffered channel. Decompression of later entries
+// can then proceed while the callback is busy with the current one, which can improve
+// throughput when the callback does significant work of its own, such as hashing or
+// writing each entry. The decompressing goroutine has always exited by the time
+// WalkTarGzPipelined returns. A decompression error is returned as a failure to read the
+// tar contents once the walk reaches the data preceding it.
+func WalkTarGzPipelined(archivePath string, callback TarCallback) error {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf(fmtErrNewGzReader, err)
+	}
+	defer reader.Close()
+
+	chunks := make(chan pipelineChunk, pipelineChunks)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		decompressAhead(reader, chunks, stop)
+	}()
+
+	err = readTarWrapping(tar.NewReader(&pipelineReader{chunks: chunks}), callback)
+
+	// Stopping the decompressor releases it should the walk have stopped early, whether
+	// due to an error or the end-of-archive marker preceding the end of the compressed
+	// stream.
+	close(stop)
+	<-done
+
+	return err
+}
+
+// Struct pipelineChunk holds data decompressed ahead of the walk by
+// WalkTarGzPipelined, along with the error, if any, that followed it.
+type pipelineChunk struct {
+	data []byte
+	err  error
+}
+
+// Reads reader in chunks, sending each to chunks, until an error, including io.EOF,
+// has been sent or stop is closed.
+func decompressAhead(reader io.Reader, chunks chan<- pipelineChunk, stop <-chan struct{}) {
+	for {
+		chunk := pipelineChunk{data: make([]byte, pipelineChunkSize)}
+		n := 0
+		for n < len(chunk.data) && chunk.err == nil {
+			var k int
+			k, chunk.err = reader.Read(chunk.data[n:])
+			n += k
+		}
+		chunk.data = chunk.data[:n]
+
+		select {
+		case chunks <- chunk:
+		case <-stop:
+			return
+		}
+		if chunk.err != nil {
+			return
+		}
+	}
+}
+
+// Struct pipelineReader reads the chunks decompressed ahead of it by WalkTarGzPipelined.
+type pipelineReader struct {
+	chunks <-chan pipelineChunk
+	data   []byte // the unread remainder of the current chunk
+	err    error  // the error following the current chunk
+}
+
+// Read reads from the current chunk, receiving the next once it is exhausted.
+func (r *pipelineReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk := <-r.chunks
+		r.data, r.err = chunk.data, chunk.err
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
@@ -0,0 +1,414 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes a gzip file containing data at the given compression level and returns its path.
+func writeGzipFile(t *testing.T, level int, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer, err := gzip.NewWriterLevel(file, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+type gzipLevelTest struct {
+	level    int
+	expected int
+}
+
+var gzipLevels = []gzipLevelTest{
+	{gzip.BestCompression, gzip.BestCompression},
+	{gzip.BestSpeed, gzip.BestSpeed},
+	{gzip.DefaultCompression, -1},
+	{5, -1},
+}
+
+func TestGzipCompressionLevel(t *testing.T) {
+	for _, c := range gzipLevels {
+		path := writeGzipFile(t, c.level, []byte("lorem ipsum"))
+
+		result, err := GzipCompressionLevel(path)
+		if err != nil {
+			t.Errorf("Unexpected error for level %d: %v\n", c.level, err)
+		}
+
+		if result != c.expected {
+			t.Errorf("Expecting '%d', got '%d'\n", c.expected, result)
+		}
+	}
+
+	result, err := GzipCompressionLevel("testdata/sample.tar.gz")
+	if err != nil || result != -1 {
+		t.Errorf("Expecting '-1', got '%d' (error: %v)\n", result, err)
+	}
+
+	if _, err := GzipCompressionLevel("testdata/sample.tar.xz"); err != errNotGzip {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotGzip, err)
+	}
+
+	if _, err := GzipCompressionLevel("nonexistent.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent gzip file.")
+	}
+}
+
+// Compresses each of the given parts as a separate gzip member and returns the
+// concatenated members along with the offset of each.
+func gzipMembers(t *testing.T, parts ...string) ([]byte, []int64) {
+	t.Helper()
+
+	var data bytes.Buffer
+	var offsets []int64
+	for _, part := range parts {
+		offsets = append(offsets, int64(data.Len()))
+		writer := gzip.NewWriter(&data)
+		writer.Name = "part"
+		if _, err := writer.Write([]byte(part)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return data.Bytes(), offsets
+}
+
+func TestGzipMembers(t *testing.T) {
+	data, expected := gzipMembers(t, strings.Repeat("lorem ipsum ", 1000), "", "dolor sit amet", strings.Repeat("z", 70000))
+	path := filepath.Join(t.TempDir(), "members.gz")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err := GzipMembers(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, offsets)
+	}
+
+	if offsets, err := GzipMembers("testdata/sample.tar.gz"); !reflect.DeepEqual(offsets, []int64{0}) || err != nil {
+		t.Errorf("Expecting '%v', got '%v' (error: %v)\n", []int64{0}, offsets, err)
+	}
+
+	if err := os.WriteFile(path, append(data, "trailing garbage"...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GzipMembers(path); err == nil {
+		t.Error("Failed to receive non-nil error for trailing garbage.")
+	}
+
+	if _, err := GzipMembers("testdata/sample.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a file that is not gzip-compressed.")
+	}
+
+	if _, err := GzipMembers("nonexistent.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestVerifyGzipIntegrity(t *testing.T) {
+	data, offsets := gzipMembers(t, strings.Repeat("lorem ipsum ", 1000), "dolor sit amet")
+	dir := t.TempDir()
+
+	// Returns the path of a file holding a copy of data modified by fn.
+	corrupt := func(name string, fn func(data []byte) []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, fn(bytes.Clone(data)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	valid := corrupt("valid.gz", func(data []byte) []byte { return data })
+	for _, path := range []string{valid, "testdata/sample.tar.gz"} {
+		if err := VerifyGzipIntegrity(path); err != nil {
+			t.Errorf("%s: unexpected error: %v\n", path, err)
+		}
+	}
+
+	corruptions := []struct {
+		name     string
+		fn       func(data []byte) []byte
+		expected error
+	}{
+		{"crc.gz", func(data []byte) []byte { data[len(data)-8] ^= 0xff; return data }, gzip.ErrChecksum},
+		{"size.gz", func(data []byte) []byte { data[len(data)-1] ^= 0xff; return data }, gzip.ErrChecksum},
+		{"truncated.gz", func(data []byte) []byte { return data[:len(data)-4] }, io.ErrUnexpectedEOF},
+		{"trailing.gz", func(data []byte) []byte { return append(data, "trailing garbage"...) }, gzip.ErrHeader},
+	}
+	for _, c := range corruptions {
+		if err := VerifyGzipIntegrity(corrupt(c.name, c.fn)); !errors.Is(err, c.expected) {
+			t.Errorf("%s: expecting '%s', got '%v'\n", c.name, c.expected, err)
+		}
+	}
+
+	body := corrupt("body.gz", func(data []byte) []byte { data[offsets[1]/2] ^= 0xff; return data })
+	if err := VerifyGzipIntegrity(body); err == nil {
+		t.Error("Failed to receive non-nil error for a corrupt gzip file.")
+	}
+
+	if err := VerifyGzipIntegrity("testdata/sample.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a file that is not gzip-compressed.")
+	}
+
+	if err := VerifyGzipIntegrity("nonexistent.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+// Returns gzip data compressing body whose header carries an extra field, a file name,
+// and a header CRC.
+func gzipWithHeaderCRC(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header := append([]byte(nil), buf.Bytes()[:gzipHeaderLen]...)
+	header[gzipFlgOffset] = gzipFlagHCRC | gzipFlagExtra | gzipFlagName
+	header = append(header, 4, 0, 'A', 'B', 0, 0)
+	header = append(header, "lorem.txt\x00"...)
+	header = binary.LittleEndian.AppendUint16(header, uint16(crc32.ChecksumIEEE(header)))
+	return append(header, buf.Bytes()[gzipHeaderLen:]...)
+}
+
+func TestValidateGzipHeader(t *testing.T) {
+	dir := t.TempDir()
+	data := gzipWithHeaderCRC(t, "lorem ipsum dolor sit amet")
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	valid := write("valid.gz", data)
+	if reader, err := gzip.NewReader(bytes.NewReader(data)); err != nil || reader.Name != "lorem.txt" {
+		t.Fatalf("Failed to read the test gzip header (error: %v)\n", err)
+	}
+	for _, path := range []string{valid, "testdata/sample.tar.gz"} {
+		if err := ValidateGzipHeader(path); err != nil {
+			t.Errorf("%s: unexpected error: %v\n", path, err)
+		}
+	}
+
+	corruptions := []struct {
+		name     string
+		offset   int
+		expected error
+	}{
+		{"name.gz", 18, ErrGzipHeaderChecksum},
+		{"crc.gz", 26, ErrGzipHeaderChecksum},
+		{"mtime.gz", 4, ErrGzipHeaderChecksum},
+		{"method.gz", gzipCMOffset, gzip.ErrHeader},
+	}
+	for _, c := range corruptions {
+		corrupted := bytes.Clone(data)
+		corrupted[c.offset] ^= 0xff
+		if err := ValidateGzipHeader(write(c.name, corrupted)); !errors.Is(err, c.expected) {
+			t.Errorf("%s: expecting '%s', got '%v'\n", c.name, c.expected, err)
+		}
+	}
+
+	// Damage to the compressed data does not affect the header.
+	body := bytes.Clone(data)
+	body[len(body)-10] ^= 0xff
+	if err := ValidateGzipHeader(write("body.gz", body)); err != nil {
+		t.Errorf("Unexpected error for a gzip file with a damaged body: %v\n", err)
+	}
+
+	if err := ValidateGzipHeader(write("truncated.gz", data[:20])); err == nil || errors.Is(err, ErrGzipHeaderChecksum) {
+		t.Errorf("Expecting a read error for a truncated header, got '%v'\n", err)
+	}
+	if err := ValidateGzipHeader("testdata/sample.tar"); err != errNotGzip {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotGzip, err)
+	}
+	if err := ValidateGzipHeader("nonexistent.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestWalkTarGzPipelined(t *testing.T) {
+	expected := digestWalk(t, func(callback TarCallback) error {
+		return WalkTarGz("testdata/sample.tar.gz", callback)
+	})
+	result := digestWalk(t, func(callback TarCallback) error {
+		return WalkTarGzPipelined("testdata/sample.tar.gz", callback)
+	})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, result)
+	}
+
+	// Stopping after the first entry of an archive larger than the decompressor may run
+	// ahead leaves it blocked sending a chunk, from which it must be released.
+	errStop := errors.New("stop")
+	large := writeBenchTarGz(t, 2*pipelineChunks, pipelineChunkSize)
+	for _, archivePath := range []string{"testdata/sample.tar.gz", large} {
+		err := WalkTarGzPipelined(archivePath, func(reader *tar.Reader, header *tar.Header) error {
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("%s: expecting '%s', got '%v'\n", archivePath, errStop, err)
+		}
+	}
+
+	expected = digestWalk(t, func(callback TarCallback) error { return WalkTarGz(large, callback) })
+	result = digestWalk(t, func(callback TarCallback) error { return WalkTarGzPipelined(large, callback) })
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, result)
+	}
+
+	data, err := os.ReadFile("testdata/sample.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "truncated.tar.gz")
+	if err := os.WriteFile(truncated, data[:len(data)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkTarGzPipelined(truncated, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a truncated tar.gz file.")
+	}
+
+	if err := WalkTarGzPipelined("testdata/sample.tar.xz", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a tar.xz file in WalkTarGzPipelined.")
+	}
+
+	if err := WalkTarGzPipelined("nonexistent.tar.gz", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent tar.gz file.")
+	}
+}
+
+// Writes a tar.gz file of count entries, each holding size bytes of pseudo-random
+// text, and returns its path.
+func writeBenchTarGz(b testing.TB, count, size int) string {
+	b.Helper()
+
+	path := filepath.Join(b.TempDir(), "bench.tar.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit"}
+	for i := 0; i < count; i++ {
+		body := make([]byte, 0, size)
+		for len(body) < size-16 {
+			body = append(body, words[rng.Intn(len(words))]...)
+			body = append(body, fmt.Sprintf(" %d\n", rng.Intn(1000000))...)
+		}
+
+		if err := tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("file%03d.txt", i), Mode: 0644, Size: int64(len(body))}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		b.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		b.Fatal(err)
+	}
+
+	return path
+}
+
+// Hashing each entry several times stands in for a callback doing significant
+// work of its own on the CPU.
+func hashingCallback(reader *tar.Reader, header *tar.Header) error {
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 4; i++ {
+		sha256.Sum256(body)
+	}
+	return nil
+}
+
+// Pausing after each entry stands in for a callback waiting on slow storage or the
+// network, during which WalkTarGzPipelined decompresses ahead. Given more than one
+// CPU, it should take little longer than decompression alone.
+func waitingCallback(reader *tar.Reader, header *tar.Header) error {
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond)
+	return nil
+}
+
+func benchmarkWalkTarGz(b *testing.B, walk func(path string, callback TarCallback) error, callback TarCallback) {
+	path := writeBenchTarGz(b, 16, 256<<10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := walk(path, callback); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWalkTarGz(b *testing.B) {
+	benchmarkWalkTarGz(b, WalkTarGz, hashingCallback)
+}
+
+func BenchmarkWalkTarGzPipelined(b *testing.B) {
+	benchmarkWalkTarGz(b, WalkTarGzPipelined, hashingCallback)
+}
+
+func BenchmarkWalkTarGz_waiting(b *testing.B) {
+	benchmarkWalkTarGz(b, WalkTarGz, waitingCallback)
+}
+
+func BenchmarkWalkTarGzPipelined_waiting(b *testing.B) {
+	benchmarkWalkTarGz(b, WalkTarGzPipelined, waitingCallback)
+}
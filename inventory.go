@@ -0,0 +1,101 @@
+package archive
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// InventoryFormat defines the output formats supported by WriteInventory.
+type InventoryFormat uint
+
+// Valid inventory formats.
+const (
+	// CSV writes a header row followed by one comma-separated row per entry.
+	CSV InventoryFormat = iota + 1
+
+	// JSONL writes one JSON object per line per entry.
+	JSONL
+)
+
+// String returns a string representation of the inventory format.
+func (f InventoryFormat) String() (result string) {
+	switch f {
+	case CSV:
+		result = "CSV"
+	case JSONL:
+		result = "JSONL"
+	}
+	return
+}
+
+// Format strings for inventory errors
+const (
+	fmtErrInventoryWrite string = "archive: failed to write inventory: %v"
+)
+
+// errUnknownInventoryFormat is returned by WriteInventory for an unsupported format.
+var errUnknownInventoryFormat = errors.New("archive: unknown inventory format")
+
+// inventoryHeader holds the column names of a CSV inventory.
+var inventoryHeader = []string{"name", "size", "mtime", "is_dir", "crc32"}
+
+// Struct inventoryRecord describes one entry of an archive inventory.
+type inventoryRecord struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	ModTime string `json:"mtime"`
+	IsDir   bool   `json:"is_dir"`
+	CRC32   string `json:"crc32,omitempty"`
+}
+
+// WriteInventory walks the archive at archivePath, whose type is determined by
+// DetermineType, and writes one record per entry to w in the given format. Each record
+// holds the entry's name, uncompressed size, UTC modification time in RFC 3339 format,
+// whether it is a directory, and, for zip archives only, its CRC-32 as eight hexadecimal
+// digits. For tar-family archives, which record no checksum, the CRC-32 field is empty in
+// CSV output and omitted from JSONL output.
+func WriteInventory(archivePath string, w io.Writer, format InventoryFormat) error {
+	var write func(record inventoryRecord) error
+
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(inventoryHeader); err != nil {
+			return fmt.Errorf(fmtErrInventoryWrite, err)
+		}
+		defer cw.Flush()
+
+		write = func(r inventoryRecord) error {
+			return cw.Write([]string{r.Name, strconv.FormatInt(r.Size, 10), r.ModTime, strconv.FormatBool(r.IsDir), r.CRC32})
+		}
+	case JSONL:
+		encoder := json.NewEncoder(w)
+		write = func(r inventoryRecord) error {
+			return encoder.Encode(r)
+		}
+	default:
+		return errUnknownInventoryFormat
+	}
+
+	return forEachEntry(archivePath, func(e *entry) error {
+		record := inventoryRecord{
+			Name:    e.name,
+			Size:    e.size,
+			ModTime: e.modTime.UTC().Format(time.RFC3339),
+			IsDir:   e.mode.IsDir(),
+		}
+		if e.file != nil {
+			record.CRC32 = fmt.Sprintf("%08x", e.file.CRC32)
+		}
+
+		if err := write(record); err != nil {
+			return fmt.Errorf(fmtErrInventoryWrite, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+)
+
+type inventoryTest struct {
+	archivePath string
+	format      InventoryFormat
+	expected    string
+}
+
+var inventories = []inventoryTest{
+	{
+		"testdata/sample.zip",
+		CSV,
+		"name,size,mtime,is_dir,crc32\n" +
+			"sample/,0,2016-05-12T15:08:37Z,true,00000000\n" +
+			"sample/text/,0,2016-05-12T15:08:41Z,true,00000000\n" +
+			"sample/text/lorem.txt,803,2016-05-12T15:07:31Z,false,ef848518\n",
+	},
+	{
+		"testdata/sample.zip",
+		JSONL,
+		`{"name":"sample/","size":0,"mtime":"2016-05-12T15:08:37Z","is_dir":true,"crc32":"00000000"}` + "\n" +
+			`{"name":"sample/text/","size":0,"mtime":"2016-05-12T15:08:41Z","is_dir":true,"crc32":"00000000"}` + "\n" +
+			`{"name":"sample/text/lorem.txt","size":803,"mtime":"2016-05-12T15:07:31Z","is_dir":false,"crc32":"ef848518"}` + "\n",
+	},
+}
+
+func TestWriteInventory(t *testing.T) {
+	for _, c := range inventories {
+		var buf bytes.Buffer
+		if err := WriteInventory(c.archivePath, &buf, c.format); err != nil {
+			t.Errorf("Unexpected error writing %s inventory: %v\n", c.format, err)
+		}
+
+		if buf.String() != c.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expected, buf.String())
+		}
+	}
+
+	path := writeTestArchive(t, "inventory.tar.gz", []testEntry{{name: "dir/"}, {name: "dir/a.txt", body: "lorem"}})
+
+	var buf bytes.Buffer
+	if err := WriteInventory(path, &buf, CSV); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	expected := "name,size,mtime,is_dir,crc32\n" +
+		"dir/,0,2016-05-12T15:07:00Z,true,\n" +
+		"dir/a.txt,5,2016-05-12T15:07:00Z,false,\n"
+	if buf.String() != expected {
+		t.Errorf("Expecting '%s', got '%s'\n", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteInventory(path, &buf, JSONL); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	expected = `{"name":"dir/","size":0,"mtime":"2016-05-12T15:07:00Z","is_dir":true}` + "\n" +
+		`{"name":"dir/a.txt","size":5,"mtime":"2016-05-12T15:07:00Z","is_dir":false}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expecting '%s', got '%s'\n", expected, buf.String())
+	}
+
+	if err := WriteInventory(path, &buf, 0); err != errUnknownInventoryFormat {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownInventoryFormat, err)
+	}
+}
+
+func TestInventoryFormat_String(t *testing.T) {
+	for format, expected := range map[InventoryFormat]string{CSV: "CSV", JSONL: "JSONL", 0: ""} {
+		if result := format.String(); result != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, result)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"archive/tar"
+	"io/fs"
+)
+
+// Kind defines the kinds of entry an archive can hold.
+type Kind uint
+
+// Valid entry kinds.
+const (
+	// Regular is a regular file.
+	Regular Kind = iota + 1
+
+	// Dir is a directory.
+	Dir
+
+	// Symlink is a symbolic link.
+	Symlink
+
+	// HardLink is a hard link to another entry of a tar-family archive.
+	HardLink
+
+	// CharDevice is a character device.
+	CharDevice
+
+	// BlockDevice is a block device.
+	BlockDevice
+
+	// Fifo is a named pipe.
+	Fifo
+
+	// Other is any other kind of entry, such as a socket.
+	Other
+)
+
+// String returns a string representation of the entry kind.
+func (k Kind) String() (result string) {
+	switch k {
+	case Regular:
+		result = "Regular"
+	case Dir:
+		result = "Dir"
+	case Symlink:
+		result = "Symlink"
+	case HardLink:
+		result = "HardLink"
+	case CharDevice:
+		result = "CharDevice"
+	case BlockDevice:
+		result = "BlockDevice"
+	case Fifo:
+		result = "Fifo"
+	case Other:
+		result = "Other"
+	}
+	return
+}
+
+// Returns the kind of the entry e. The kind of a zip entry is decoded from the file
+// type recorded in its external attributes, as reported by File.Mode.
+func entryKind(e *entry) Kind {
+	if e.header != nil && e.header.Typeflag == tar.TypeLink {
+		return HardLink
+	}
+
+	switch mode := e.mode; {
+	case mode.IsRegular():
+		return Regular
+	case mode.IsDir():
+		return Dir
+	case mode&fs.ModeSymlink != 0:
+		return Symlink
+	case mode&fs.ModeCharDevice != 0:
+		return CharDevice
+	case mode&fs.ModeDevice != 0:
+		return BlockDevice
+	case mode&fs.ModeNamedPipe != 0:
+		return Fifo
+	}
+	return Other
+}
@@ -0,0 +1,26 @@
+package archive
+
+import "testing"
+
+var kindStrings = []struct {
+	kind     Kind
+	expected string
+}{
+	{Regular, "Regular"},
+	{Dir, "Dir"},
+	{Symlink, "Symlink"},
+	{HardLink, "HardLink"},
+	{CharDevice, "CharDevice"},
+	{BlockDevice, "BlockDevice"},
+	{Fifo, "Fifo"},
+	{Other, "Other"},
+	{Kind(0), ""},
+}
+
+func TestKind_String(t *testing.T) {
+	for _, k := range kindStrings {
+		if result := k.kind.String(); result != k.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", k.expected, result)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Number of leading bytes of an entry examined to decide whether it holds text, and
+// the size of the buffer used when reading its lines.
+const (
+	textSniffLen   = 8000
+	lineReaderSize = 8192
+)
+
+// LineCallback is the type of function called for each line of text visited by
+// WalkTarLines, along with the name of the entry containing it.
+type LineCallback func(name, line string) error
+
+// WalkTarLines walks the contents of the tar-family archive at archivePath, whose type is
+// determined by DetermineType, and invokes the callback function once for each line of
+// every regular file entry that holds text. Lines are passed without their terminating
+// "\n" or "\r\n" and may be of any length. Entries are treated as binary and skipped if
+// their first 8000 bytes contain a NUL byte or are not valid UTF-8. Zip archives are not
+// supported.
+func WalkTarLines(archivePath string, callback LineCallback) error {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return err
+	}
+
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	reader := tar.NewReader(stream)
+	lines := bufio.NewReaderSize(reader, lineReaderSize)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		lines.Reset(reader)
+		if err := readLines(header.Name, lines, callback); err != nil {
+			return fmt.Errorf(fmtErrTarCallbackFailed, err)
+		}
+	}
+}
+
+// Invokes the callback for each line read from the named entry, provided that
+// the entry holds text.
+func readLines(name string, lines *bufio.Reader, callback LineCallback) error {
+	sniff, err := lines.Peek(textSniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if !isText(sniff, len(sniff) == textSniffLen) {
+		return nil
+	}
+
+	for {
+		line, err := lines.ReadString('\n')
+		if len(line) > 0 && callback != nil {
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+			if err := callback(name, line); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Reports whether the leading bytes of an entry appear to be text. If the bytes
+// are truncated, a partial character at their end is ignored.
+func isText(data []byte, truncated bool) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+
+	if truncated {
+		for i := 0; i < utf8.UTFMax && i < len(data); i++ {
+			if utf8.RuneStart(data[len(data)-1-i]) {
+				if !utf8.FullRune(data[len(data)-1-i:]) {
+					data = data[:len(data)-1-i]
+				}
+				break
+			}
+		}
+	}
+
+	return utf8.Valid(data)
+}
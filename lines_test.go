@@ -0,0 +1,107 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var lineEntries = []testEntry{
+	{name: "logs/"},
+	{name: "logs/a.log", body: "first\nsecond\r\n\nlast without newline"},
+	{name: "logs/binary.dat", body: "MZ\x00\x01\x02\nnot text\n"},
+	{name: "logs/latin1.txt", body: "caf\xe9\n"},
+	{name: "logs/link", typeflag: tar.TypeSymlink, linkname: "a.log"},
+	{name: "logs/empty.log"},
+	{name: "logs/long.log", body: strings.Repeat("é", textSniffLen) + "\nend\n"},
+}
+
+type lineResult struct {
+	name string
+	line string
+}
+
+var expectedLines = []lineResult{
+	{"logs/a.log", "first"},
+	{"logs/a.log", "second"},
+	{"logs/a.log", ""},
+	{"logs/a.log", "last without newline"},
+	{"logs/long.log", strings.Repeat("é", textSniffLen)},
+	{"logs/long.log", "end"},
+}
+
+func TestWalkTarLines(t *testing.T) {
+	for _, filename := range []string{"lines.tar", "lines.tar.gz", "lines.tar.xz"} {
+		path := writeTestArchive(t, filename, lineEntries)
+
+		var results []lineResult
+		err := WalkTarLines(path, func(name, line string) error {
+			results = append(results, lineResult{name, line})
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if !reflect.DeepEqual(results, expectedLines) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, expectedLines, results)
+		}
+	}
+
+	var count int
+	err := WalkTarLines("testdata/sample.tar.bz2", func(name, line string) error {
+		count++
+		return nil
+	})
+	if err != nil || count == 0 {
+		t.Errorf("Failed to walk lines of sample archive: %d (error: %v)\n", count, err)
+	}
+}
+
+func TestWalkTarLines_errors(t *testing.T) {
+	path := writeTestArchive(t, "lines.tar", lineEntries)
+
+	errStop := errors.New("stop")
+	if err := WalkTarLines(path, func(name, line string) error { return errStop }); !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	if err := WalkTarLines("testdata/sample.zip", nil); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+
+	if err := WalkTarLines("foo.123", nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	if err := WalkTarLines("testdata/invalid.tar", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
+
+type isTextTest struct {
+	data      string
+	truncated bool
+	expected  bool
+}
+
+var isTexts = []isTextTest{
+	{"hello", false, true},
+	{"", false, true},
+	{"a\x00b", false, false},
+	{"caf\xe9", false, false},
+	{"caf\xc3", false, false},
+	{"caf\xc3", true, true},
+	{"\xe2\x82", true, true},
+	{"\xe2\x82\xac", true, true},
+	{"\xff\xfe", true, false},
+}
+
+func TestIsText(t *testing.T) {
+	for _, c := range isTexts {
+		if result := isText([]byte(c.data), c.truncated); result != c.expected {
+			t.Errorf("%q: expecting '%t', got '%t'\n", c.data, c.expected, result)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// SortKey defines the orders in which ListSorted can return entries.
+type SortKey uint
+
+// Valid sort keys.
+const (
+	// ByName orders entries by name in ascending byte order.
+	ByName SortKey = iota + 1
+
+	// BySize orders entries by uncompressed size, largest first.
+	BySize
+
+	// ByModTime orders entries by modification time, most recent first.
+	ByModTime
+)
+
+// String returns a string representation of the sort key.
+func (k SortKey) String() (result string) {
+	switch k {
+	case ByName:
+		result = "ByName"
+	case BySize:
+		result = "BySize"
+	case ByModTime:
+		result = "ByModTime"
+	}
+	return
+}
+
+// errUnknownSortKey is returned by ListSorted for an unsupported sort key.
+var errUnknownSortKey = errors.New("archive: unknown sort key")
+
+// ListOptions configures the behavior of ListWithOptions.
+type ListOptions struct {
+	// SortBy, if non-zero, sorts the entries as ListSorted does; otherwise, they are
+	// returned in archive order.
+	SortBy SortKey
+
+	// CleanNames, if true, replaces the name of each entry with its canonical form, as
+	// the CleanNames option of WalkOptions does, before the entries are sorted. Cleaning
+	// is opt-in because consumers of archives that intentionally rely on unusual names
+	// may depend on seeing them as they are; two differently named entries may share
+	// the same cleaned name.
+	CleanNames bool
+}
+
+// List returns the details of every entry of the archive at archivePath, whose type is
+// determined by DetermineType, in archive order.
+func List(archivePath string) ([]EntryInfo, error) {
+	return ListWithOptions(archivePath, ListOptions{})
+}
+
+// ListSorted returns the details of every entry of the archive at archivePath, as List
+// does, sorted according to by: ByName sorts by name in ascending order, BySize sorts
+// by uncompressed size with the largest entries first, and ByModTime sorts by
+// modification time with the most recently modified entries first. Entries that
+// compare equal under BySize or ByModTime are ordered by name, and entries sharing a
+// name remain in archive order, so the result is fully determined by the archive.
+func ListSorted(archivePath string, by SortKey) ([]EntryInfo, error) {
+	if by == 0 {
+		return nil, errUnknownSortKey
+	}
+	return ListWithOptions(archivePath, ListOptions{SortBy: by})
+}
+
+// ListWithOptions returns the details of every entry of the archive at archivePath, as
+// List does, configured by opts.
+func ListWithOptions(archivePath string, opts ListOptions) ([]EntryInfo, error) {
+	var compare func(a, b EntryInfo) int
+	switch opts.SortBy {
+	case 0:
+	case ByName:
+		compare = func(a, b EntryInfo) int { return 0 }
+	case BySize:
+		compare = func(a, b EntryInfo) int { return cmp.Compare(b.Size, a.Size) }
+	case ByModTime:
+		compare = func(a, b EntryInfo) int { return b.ModTime.Compare(a.ModTime) }
+	default:
+		return nil, errUnknownSortKey
+	}
+
+	entries, err := Find(archivePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CleanNames {
+		for i := range entries {
+			entries[i].Name = cleanName(entries[i].Name)
+		}
+	}
+
+	if compare != nil {
+		slices.SortStableFunc(entries, func(a, b EntryInfo) int {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+	return entries, nil
+}
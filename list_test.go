@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var listEntries = []testEntry{
+	{name: "b.txt", body: "lorem ipsum", modTime: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "dir/", modTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "dir/c.txt", body: "lorem", modTime: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "a.txt", body: "lorem", modTime: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "big.txt", body: "lorem ipsum dolor sit amet", modTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+type listSortedTest struct {
+	by       SortKey
+	expected []string
+}
+
+var listSortedTests = []listSortedTest{
+	{ByName, []string{"a.txt", "b.txt", "big.txt", "dir/", "dir/c.txt"}},
+	{BySize, []string{"big.txt", "b.txt", "a.txt", "dir/c.txt", "dir/"}},
+	{ByModTime, []string{"dir/c.txt", "a.txt", "b.txt", "dir/", "big.txt"}},
+}
+
+// Returns the names of the given entries.
+func entryInfoNames(entries []EntryInfo) []string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestList(t *testing.T) {
+	for _, filename := range []string{"list.tar", "list.zip"} {
+		path := writeTestArchive(t, filename, listEntries)
+
+		entries, err := List(path)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\n", filename, err)
+		}
+
+		expected := []string{"b.txt", "dir/", "dir/c.txt", "a.txt", "big.txt"}
+		if names := entryInfoNames(entries); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+		}
+	}
+
+	if _, err := List("nonexistent.zip"); err == nil {
+		t.Error("Failed to receive non-nil error when listing a nonexistent zip file.")
+	}
+}
+
+func TestListSorted(t *testing.T) {
+	for _, filename := range []string{"list.tar", "list.zip"} {
+		path := writeTestArchive(t, filename, listEntries)
+
+		for _, c := range listSortedTests {
+			entries, err := ListSorted(path, c.by)
+			if err != nil {
+				t.Fatalf("Unexpected error for %s: %v\n", filename, err)
+			}
+			if names := entryInfoNames(entries); !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("%s %s: expecting '%v', got '%v'\n", filename, c.by, c.expected, names)
+			}
+		}
+	}
+
+	if _, err := ListSorted("testdata/sample.zip", 0); err != errUnknownSortKey {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownSortKey, err)
+	}
+
+	if _, err := ListSorted("nonexistent.zip", ByName); err == nil {
+		t.Error("Failed to receive non-nil error when listing a nonexistent zip file.")
+	}
+}
+
+func TestSortKey_String(t *testing.T) {
+	for key, expected := range map[SortKey]string{ByName: "ByName", BySize: "BySize", ByModTime: "ByModTime", 0: ""} {
+		if result := key.String(); result != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, result)
+		}
+	}
+}
+
+func TestListWithOptions(t *testing.T) {
+	for _, filename := range []string{"clean.tar", "clean.zip"} {
+		path := writeTestArchive(t, filename, cleanNameEntries)
+
+		entries, err := ListWithOptions(path, ListOptions{CleanNames: true})
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if names := entryInfoNames(entries); !reflect.DeepEqual(names, cleanNameExpected) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, cleanNameExpected, names)
+		}
+
+		entries, err = ListWithOptions(path, ListOptions{SortBy: ByName, CleanNames: true})
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\n", filename, err)
+		}
+		expected := []string{"foo/", "foo/bar/baz", "foo/plain.txt", "foo/quux.txt"}
+		if names := entryInfoNames(entries); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, expected, names)
+		}
+
+		entries, err = ListWithOptions(path, ListOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if names := entryInfoNames(entries); names[1] != "foo//bar/./baz" {
+			t.Errorf("%s: expecting '%s', got '%s'\n", filename, "foo//bar/./baz", names[1])
+		}
+	}
+
+	if _, err := ListWithOptions("testdata/sample.zip", ListOptions{SortBy: ByModTime + 1}); err != errUnknownSortKey {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownSortKey, err)
+	}
+}
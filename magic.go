@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/bzip2"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Magic bytes identifying zip and tar archives.
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	ustarMagic    = []byte("ustar")
+)
+
+// errBzip2NotTar is returned by DetermineTypeFromMagic for a bzip2-compressed file
+// whose decompressed contents are not a tar archive.
+var errBzip2NotTar = errors.New("archive: bzip2-compressed file is not a tar archive")
+
+// ErrTypeMismatch is returned by VerifyTypeMatchesContent when an archive's
+// extension and contents indicate different archive types.
+var ErrTypeMismatch = errors.New("archive: file extension does not match content")
+
+// Location of the magic within the first header block of a POSIX (ustar) or GNU tar archive.
+const (
+	tarBlockSize   = 512
+	tarMagicOffset = 257
+)
+
+// DetermineTypeFromMagic identifies the archive file type by examining the leading
+// bytes of the file at path, ignoring its name. Files beginning with the gzip, bzip2,
+// or xz magic are identified as TarGz, TarBz2, or TarXz respectively, files beginning
+// with a zip local file header or end of central directory record are identified as
+// Zip, and files whose first block carries the ustar magic are identified as Tar.
+// Anything else returns 0 and a non-nil error.
+//
+// Unlike gzip and xz, which are only common as tar compressors, bzip2 is frequently
+// used to compress individual files, and its framing gives no indication of what it
+// contains. A file with the bzip2 magic, "BZh" followed by a block size digit, is
+// therefore only identified as TarBz2 once the start of its decompressed contents has
+// been read successfully as a tar header; otherwise a non-nil error is returned.
+func DetermineTypeFromMagic(path string) (Type, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, tarBlockSize)
+	n, err := io.ReadFull(file, magic)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, fmt.Errorf(fmtErrReadMagic, err)
+	}
+
+	typ, err := typeFromMagic(magic[:n])
+	if err != nil || typ != TarBz2 {
+		return typ, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf(fmtErrReadMagic, err)
+	}
+	if err := confirmTar(bzip2.NewReader(file)); err != nil {
+		return 0, errBzip2NotTar
+	}
+	return typ, nil
+}
+
+// Confirms that the stream begins with a valid tar header, or is an empty tar archive.
+func confirmTar(stream io.Reader) error {
+	if _, err := tar.NewReader(stream).Next(); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// DetermineTypeSmart identifies the archive file type using the extensions present in
+// path, as DetermineType does, falling back to DetermineTypeFromMagic when the
+// extensions do not identify a supported type. This is inexpensive when the extension
+// can be trusted yet still identifies archives that are misnamed or lack an extension.
+func DetermineTypeSmart(path string) (Type, error) {
+	typ, err := DetermineType(path)
+	if err != errUnknownType {
+		return typ, err
+	}
+
+	return DetermineTypeFromMagic(path)
+}
+
+// VerifyTypeMatchesContent checks that the archive type indicated by the extensions
+// present in path, as determined by DetermineType, agrees with the type indicated by
+// the file's leading bytes, as determined by DetermineTypeFromMagic. When they disagree,
+// as for a file named .tar.gz that is actually xz-compressed, an error wrapping
+// ErrTypeMismatch and naming both types is returned. Errors from either method of
+// identification are returned as-is.
+//
+// The legacy lzma_alone format of TarLzma archives begins with no magic number, so
+// DetermineTypeFromMagic cannot identify it. For a file whose extensions indicate
+// TarLzma and whose leading bytes match no other type, the content is instead checked
+// by decompressing the start of the archive: an lzip-compressed file, which shares the
+// ".tlz" extension, yields the error WalkTarLzma returns for it, and content that is not
+// an lzma-compressed tar archive yields an error wrapping ErrTypeMismatch.
+func VerifyTypeMatchesContent(path string) error {
+	claimed, err := DetermineType(path)
+	if err != nil {
+		return err
+	}
+
+	actual, err := DetermineTypeFromMagic(path)
+	if err == errUnknownType && claimed == TarLzma {
+		return verifyLzmaContent(path)
+	} else if err != nil {
+		return err
+	}
+
+	if claimed != actual {
+		return fmt.Errorf("%w: %s has extension of type %s but content of type %s",
+			ErrTypeMismatch, filepath.Base(path), claimed, actual)
+	}
+	return nil
+}
+
+// Checks that the file at path holds an lzma-compressed tar archive by decompressing
+// its first header.
+func verifyLzmaContent(path string) error {
+	stream, err := openTarStream(path, TarLzma)
+	if errors.Is(err, errLzip) {
+		return err
+	} else if err == nil {
+		defer stream.Close()
+		err = confirmTar(stream)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s has extension of type %s but content that is not lzma-compressed tar",
+			ErrTypeMismatch, filepath.Base(path), TarLzma)
+	}
+	return nil
+}
+
+// Identifies the archive type from the leading bytes of an archive file.
+func typeFromMagic(magic []byte) (Type, error) {
+	if typ, ok := tarTypeFromCompression(detectCompression(magic)); ok {
+		return typ, nil
+	}
+
+	if bytes.HasPrefix(magic, zipMagic) || bytes.HasPrefix(magic, zipEmptyMagic) {
+		return Zip, nil
+	}
+
+	if len(magic) >= tarMagicOffset+len(ustarMagic) &&
+		bytes.Equal(magic[tarMagicOffset:tarMagicOffset+len(ustarMagic)], ustarMagic) {
+		return Tar, nil
+	}
+
+	return 0, errUnknownType
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Copies the file at src into a temporary directory under the name filename
+// and returns the copy's path.
+func copyToTemp(t *testing.T, src, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), filename)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+var magicTypes = []typeTest{
+	{"testdata/sample.tar", Tar, nil},
+	{"testdata/sample.tar.bz2", TarBz2, nil},
+	{"testdata/sample.tar.gz", TarGz, nil},
+	{"testdata/sample.tar.xz", TarXz, nil},
+	{"testdata/sample.zip", Zip, nil},
+}
+
+func TestDetermineTypeFromMagic(t *testing.T) {
+	for _, c := range magicTypes {
+		path := copyToTemp(t, c.filename, "noextension")
+
+		resultType, resultErr := DetermineTypeFromMagic(path)
+
+		if resultType != c.expectedType {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expectedType, resultType)
+		}
+
+		if resultErr != c.expectedError {
+			t.Errorf("Expecting '%v', got '%v'\n", c.expectedError, resultErr)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "short")
+	if err := os.WriteFile(path, []byte("lorem ipsum"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetermineTypeFromMagic(path); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	// A plain bzip2-compressed file carries the bzip2 magic but does not contain a tar archive.
+	path = copyToTemp(t, "testdata/lorem.txt.bz2", "noextension")
+	if _, err := DetermineTypeFromMagic(path); err != errBzip2NotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errBzip2NotTar, err)
+	}
+
+	if _, err := DetermineTypeFromMagic("nonexistent"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestDetermineTypeSmart(t *testing.T) {
+	for _, c := range magicTypes {
+		path := copyToTemp(t, c.filename, "download.bin")
+
+		if resultType, err := DetermineTypeSmart(path); resultType != c.expectedType || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", c.expectedType, resultType, err)
+		}
+	}
+
+	// The extension is trusted without opening the file.
+	if resultType, err := DetermineTypeSmart("nonexistent.tar.gz"); resultType != TarGz || err != nil {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", TarGz, resultType, err)
+	}
+
+	if _, err := DetermineTypeSmart("nonexistent.bin"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestVerifyTypeMatchesContent(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		if err := VerifyTypeMatchesContent(archivePath); err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+	}
+
+	path := copyToTemp(t, "testdata/sample.tar.xz", "mislabeled.tar.gz")
+	err := VerifyTypeMatchesContent(path)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTypeMismatch, err)
+	}
+
+	expected := "archive: file extension does not match content: mislabeled.tar.gz has extension of type TarGz but content of type TarXz"
+	if err != nil && err.Error() != expected {
+		t.Errorf("Expecting '%s', got '%s'\n", expected, err)
+	}
+
+	path = copyToTemp(t, "testdata/sample.zip", "mislabeled.tar")
+	if err := VerifyTypeMatchesContent(path); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTypeMismatch, err)
+	}
+
+	// An lzma_alone archive has no magic number, so its content is decoded instead.
+	if err := VerifyTypeMatchesContent("testdata/sample.tar.lzma"); err != nil {
+		t.Errorf("Unexpected error for %s: %v\n", "testdata/sample.tar.lzma", err)
+	}
+
+	dir := t.TempDir()
+	lzip := filepath.Join(dir, "lzip.tlz")
+	if err := os.WriteFile(lzip, append([]byte("LZIP\x01"), make([]byte, 100)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTypeMatchesContent(lzip); !errors.Is(err, errLzip) {
+		t.Errorf("Expecting '%s', got '%v'\n", errLzip, err)
+	}
+
+	garbage := filepath.Join(dir, "garbage.tar.lzma")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("lorem ipsum ", 100)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTypeMatchesContent(garbage); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTypeMismatch, err)
+	}
+
+	path = copyToTemp(t, "testdata/sample.tar.gz", "mislabeled.tar.lzma")
+	if err := VerifyTypeMatchesContent(path); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTypeMismatch, err)
+	}
+
+	if err := VerifyTypeMatchesContent("foo.123"); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	if err := VerifyTypeMatchesContent("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
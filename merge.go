@@ -0,0 +1,238 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ErrDuplicateEntry is returned by merging with the Error collision policy when more than
+// one source holds an entry of the same name. The returned error wraps ErrDuplicateEntry
+// and quotes the entry's name.
+var ErrDuplicateEntry = errors.New("archive: duplicate entry")
+
+// MergeOptions configures the behavior of MergeWithOptions.
+type MergeOptions struct {
+	// OnDuplicate determines what happens to an entry other than a directory whose name,
+	// once cleaned, matches that of an entry already merged. Overwrite keeps only the
+	// last such entry, so the merged archive extracts as extracting each source in turn
+	// would; Skip keeps only the first; Rename keeps every entry, renaming each later one
+	// as the Rename policy of ExtractAllWithOptions does; and Error stops the merge with
+	// an error wrapping ErrDuplicateEntry. The zero value behaves as Overwrite.
+	OnDuplicate CollisionPolicy
+}
+
+// Merge writes a single archive at destPath holding the entries of each of the tar-family
+// archives at srcPaths in turn, as MergeWithOptions does with the zero MergeOptions. It
+// suits bundling tools and reverses Split.
+func Merge(srcPaths []string, destPath string) error {
+	return MergeWithOptions(srcPaths, destPath, MergeOptions{})
+}
+
+// MergeWithOptions writes a single archive at destPath holding the entries of each of the
+// tar-family archives at srcPaths in turn, configured by opts. The type of each source and
+// of the destination is determined by DetermineType; each source is decompressed as its
+// type requires, and the destination, which may be Tar, TarGz, or TarXz, is compressed as
+// its own type requires. Entries are copied with their headers intact.
+//
+// A directory that appears in more than one source is written only once, as its first
+// appearance. Duplicates of any other entry are resolved according to opts.OnDuplicate;
+// keeping the last of them requires every source to be read twice, first to find the
+// last appearance of each name. A hard link whose target was renamed is updated to refer
+// to the target's new name. If an error occurs, the partially-written destination file
+// is removed.
+func MergeWithOptions(srcPaths []string, destPath string, opts MergeOptions) (err error) {
+	if opts.OnDuplicate > Error {
+		return errUnknownCollisionPolicy
+	}
+
+	dstType, err := DetermineType(destPath)
+	if err != nil {
+		return err
+	}
+	if !isCompressible(dstType) {
+		return errUnsupportedWriteType
+	}
+
+	for _, srcPath := range srcPaths {
+		typ, err := DetermineType(srcPath)
+		if err != nil {
+			return err
+		} else if typ == Zip {
+			return errNotTar
+		}
+	}
+
+	var last map[string]mergePosition
+	if opts.OnDuplicate == 0 || opts.OnDuplicate == Overwrite {
+		if last, err = lastPositions(srcPaths); err != nil {
+			return err
+		}
+	}
+
+	dst, err := os.Create(filepath.Clean(destPath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveCreate, err)
+	}
+	defer func() {
+		if cerr := dst.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf(fmtErrArchiveCreate, cerr)
+		}
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	compressor, err := newCompressWriter(dst, dstType)
+	if err != nil {
+		return err
+	}
+
+	m := &merger{
+		writer: tar.NewWriter(compressor),
+		policy: opts.OnDuplicate,
+		last:   last,
+		seen:   make(map[string]bool),
+		dirs:   make(map[string]bool),
+	}
+	for i, srcPath := range srcPaths {
+		if err := m.mergeSource(i, srcPath); err != nil {
+			compressor.Close()
+			return err
+		}
+	}
+
+	if err := m.writer.Close(); err != nil {
+		compressor.Close()
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
+
+// Struct mergePosition identifies an entry by the index of its source and its
+// position within that source.
+type mergePosition struct {
+	source int
+	index  int
+}
+
+// Returns the position of the last appearance of each entry name, once cleaned,
+// among the entries of the archives at srcPaths.
+func lastPositions(srcPaths []string) (map[string]mergePosition, error) {
+	last := make(map[string]mergePosition)
+	for i, srcPath := range srcPaths {
+		index := 0
+		err := forEachEntry(srcPath, func(e *entry) error {
+			last[path.Clean(e.name)] = mergePosition{source: i, index: index}
+			index++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return last, nil
+}
+
+// Struct merger writes the entries of successive sources to a single tar stream,
+// resolving duplicate names.
+type merger struct {
+	writer *tar.Writer
+	policy CollisionPolicy
+	last   map[string]mergePosition // last appearance of each name; set only to keep the last
+	seen   map[string]bool          // cleaned names of the entries other than directories written
+	dirs   map[string]bool          // cleaned names of the directories written
+}
+
+// Writes the entries of the archive at srcPath, the source at index source, to the
+// merged stream.
+func (m *merger) mergeSource(source int, srcPath string) error {
+	typ, err := DetermineType(srcPath)
+	if err != nil {
+		return err
+	}
+
+	stream, err := openTarStream(srcPath, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	renamed := make(map[string]string)
+	reader := tar.NewReader(stream)
+	for index := 0; ; index++ {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		name := path.Clean(header.Name)
+		if header.Typeflag == tar.TypeDir {
+			if m.dirs[name] {
+				continue
+			}
+			m.dirs[name] = true
+		} else {
+			keep, err := m.resolve(header, name, mergePosition{source: source, index: index}, renamed)
+			if err != nil {
+				return err
+			} else if !keep {
+				continue
+			}
+		}
+
+		if header.Typeflag == tar.TypeLink {
+			if target, ok := renamed[path.Clean(header.Linkname)]; ok {
+				header.Linkname = target
+			}
+		}
+
+		if err := m.writer.WriteHeader(header); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		if _, err := io.Copy(m.writer, reader); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+	}
+}
+
+// Applies the duplicate policy to an entry other than a directory whose cleaned name
+// is name and which appears at pos, reporting whether it should be written. An entry
+// that is renamed has its header updated, and its new name recorded in renamed.
+func (m *merger) resolve(header *tar.Header, name string, pos mergePosition, renamed map[string]string) (bool, error) {
+	if m.last != nil {
+		return m.last[name] == pos, nil
+	}
+
+	if !m.seen[name] {
+		m.seen[name] = true
+		return true, nil
+	}
+
+	switch m.policy {
+	case Skip:
+		return false, nil
+	case Rename:
+		dir, base := path.Split(name)
+		for n := 1; n <= maxCollisionRenames; n++ {
+			candidate := dir + numberedName(base, n)
+			if !m.seen[candidate] && !m.dirs[candidate] {
+				m.seen[candidate] = true
+				renamed[name] = candidate
+				header.Name = candidate
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("%w: no free name for %q", ErrDuplicateEntry, header.Name)
+	}
+	return false, fmt.Errorf("%w: %q", ErrDuplicateEntry, header.Name)
+}
@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var mergeSources = [][]testEntry{
+	{{name: "a/"}, {name: "a/one.txt", body: "first"}, {name: "a/two.txt", body: "2"}},
+	{{name: "a/"}, {name: "a/one.txt", body: "second"}, {name: "./a/link", typeflag: tar.TypeLink, linkname: "a/one.txt"}},
+}
+
+var mergeTests = []struct {
+	policy   CollisionPolicy
+	expected map[string]string // entry names mapped to their contents or link names
+}{
+	{0, map[string]string{"a/": "", "a/two.txt": "2", "a/one.txt": "second", "./a/link": "a/one.txt"}},
+	{Overwrite, map[string]string{"a/": "", "a/two.txt": "2", "a/one.txt": "second", "./a/link": "a/one.txt"}},
+	{Skip, map[string]string{"a/": "", "a/one.txt": "first", "a/two.txt": "2", "./a/link": "a/one.txt"}},
+	{Rename, map[string]string{"a/": "", "a/one.txt": "first", "a/two.txt": "2", "a/one (1).txt": "second", "./a/link": "a/one (1).txt"}},
+}
+
+// Returns the entries of the tar-family archive at path mapped to their contents
+// or, for links, their link names.
+func mergedEntries(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	entries := make(map[string]string)
+	err := forEachEntry(path, func(e *entry) error {
+		if e.header.Typeflag == tar.TypeLink {
+			entries[e.name] = e.header.Linkname
+			return nil
+		}
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		body, err := io.ReadAll(reader)
+		entries[e.name] = string(body)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestMergeWithOptions(t *testing.T) {
+	srcPaths := []string{
+		writeTestArchive(t, "first.tar.gz", mergeSources[0]),
+		writeTestArchive(t, "second.tar.xz", mergeSources[1]),
+	}
+
+	for _, test := range mergeTests {
+		for _, filename := range []string{"merged.tar", "merged.tar.gz"} {
+			destPath := filepath.Join(t.TempDir(), filename)
+			if err := MergeWithOptions(srcPaths, destPath, MergeOptions{OnDuplicate: test.policy}); err != nil {
+				t.Errorf("%s: unexpected error: %v\n", test.policy, err)
+				continue
+			}
+
+			if entries := mergedEntries(t, destPath); !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("%s: expecting '%v', got '%v'\n", test.policy, test.expected, entries)
+			}
+		}
+	}
+
+	destPath := filepath.Join(t.TempDir(), "merged.tar")
+	if err := MergeWithOptions(srcPaths, destPath, MergeOptions{OnDuplicate: Error}); !errors.Is(err, ErrDuplicateEntry) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrDuplicateEntry, err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Error("Failed to remove the destination after a failed merge.")
+	}
+
+	if err := MergeWithOptions(srcPaths, destPath, MergeOptions{OnDuplicate: Error + 1}); err != errUnknownCollisionPolicy {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownCollisionPolicy, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := writeTestArchive(t, "src.tar", splitEntries)
+	parts, err := Split(srcPath, filepath.Join(dir, "part"), 3584)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destPath := filepath.Join(dir, "merged.tar.xz")
+	if err := Merge(parts, destPath); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if expected, names := tarEntryNames(t, srcPath), tarEntryNames(t, destPath); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+	}
+
+	if err := Merge([]string{srcPath, "testdata/sample.zip"}, destPath); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+	if err := Merge([]string{srcPath}, filepath.Join(dir, "merged.tar.bz2")); err != errUnsupportedWriteType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedWriteType, err)
+	}
+}
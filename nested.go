@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+)
+
+// WalkNested walks the contents of an archive stored as the entry named innerEntry
+// within the archive at outerPath, whose type is determined by DetermineType, invoking
+// tarCallback for each entry of a tar-family inner archive or zipCallback for each entry
+// of a zip inner archive. This serves the common case of, for example, a jar carrying a
+// tar.gz of configuration files, without extracting the inner archive to disk. The inner
+// archive's type is identified from its leading bytes, as by DetermineTypeFromMagic, and
+// its entry in the outer archive may be stored or compressed. A tar-family inner archive
+// is decompressed and walked as it is read, while a zip inner archive, whose central
+// directory is at its end, is read in its entirety before being walked, in memory or,
+// if larger than 64 MiB, in a temporary file, as by WalkStdin. If the outer archive
+// contains no such entry, ErrEntryNotFound is returned.
+func WalkNested(outerPath, innerEntry string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	c, err := openCursor(outerPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return fmt.Errorf("%w: %q", ErrEntryNotFound, innerEntry)
+		} else if err != nil {
+			return err
+		}
+
+		if e.name != innerEntry || !e.mode.IsRegular() {
+			continue
+		}
+
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		return walkReader(reader, tarCallback, zipCallback)
+	}
+}
@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Writes a zip archive holding each of the sample archives twice, once stored
+// under "stored/" and once deflated under "deflated/", and returns its path.
+func writeNestingZip(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "outer.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for _, archivePath := range sampleArchives {
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for dir, method := range map[string]uint16{"stored/": zip.Store, "deflated/": zip.Deflate} {
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: dir + filepath.Base(archivePath), Method: method})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fw.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Walks with the given walk function and returns the names of the entries visited.
+func walkNames(t *testing.T, walk func(TarCallback, ZipCallback) error) []string {
+	t.Helper()
+
+	var names []string
+	err := walk(
+		func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			return nil
+		},
+		func(f *zip.File) error {
+			names = append(names, f.Name)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestWalkNested(t *testing.T) {
+	outerZip := writeNestingZip(t)
+
+	data, err := os.ReadFile("testdata/sample.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outerTar := writeTestArchive(t, "outer.tar", []testEntry{
+		{name: "configs/"},
+		{name: "configs/sample.tar.gz", body: string(data)},
+	})
+
+	for _, archivePath := range sampleArchives {
+		expected := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+			return walkArchive(archivePath, tarCb, zipCb)
+		})
+
+		for _, dir := range []string{"stored/", "deflated/"} {
+			inner := dir + filepath.Base(archivePath)
+			names := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+				return WalkNested(outerZip, inner, tarCb, zipCb)
+			})
+			if !reflect.DeepEqual(names, expected) {
+				t.Errorf("%s: expecting '%v', got '%v'\n", inner, expected, names)
+			}
+		}
+	}
+
+	expected := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+		return WalkTarGz("testdata/sample.tar.gz", tarCb)
+	})
+	names := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+		return WalkNested(outerTar, "configs/sample.tar.gz", tarCb, zipCb)
+	})
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+	}
+}
+
+func TestWalkNested_errors(t *testing.T) {
+	outer := writeTestArchive(t, "outer.zip", []testEntry{
+		{name: "dir/"},
+		{name: "notes.txt", body: "lorem ipsum"},
+	})
+
+	if err := WalkNested(outer, "missing.tar.gz", nil, nil); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+	}
+
+	if err := WalkNested(outer, "dir/", nil, nil); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+	}
+
+	if err := WalkNested(outer, "notes.txt", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an entry that is not an archive.")
+	}
+
+	if err := WalkNested("nonexistent.zip", "inner.tar", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent zip file.")
+	}
+}
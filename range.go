@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errInvalidRange is returned by OpenRange when given a negative offset or length.
+var errInvalidRange = errors.New("archive: invalid byte range")
+
+// OpenRange returns a reader over at most length bytes of the regular file entry named
+// entryName in the archive at archivePath, whose type is determined by DetermineType,
+// beginning offset bytes into the entry's contents. A range extending past the end of the
+// entry is truncated, and one starting past the end yields no data. If the archive contains
+// no such entry, ErrEntryNotFound is returned. The caller must close the returned reader.
+//
+// How quickly the start of the range is reached depends on how the entry is stored:
+//
+//   - Stored (uncompressed) zip entries and entries of uncompressed tar archives are read
+//     directly from the archive file, so the cost is independent of offset. For these,
+//     the zip CRC-32 checksum is not verified.
+//   - Deflated zip entries must be decompressed and discarded up to offset.
+//   - Entries of compressed tar archives (TarBz2, TarGz, TarXz) require the whole archive
+//     stream preceding the range, including any earlier entries, to be decompressed.
+func OpenRange(archivePath, entryName string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 {
+		return nil, errInvalidRange
+	}
+
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == Zip {
+		return openZipRange(archivePath, entryName, offset, length)
+	}
+	return openTarRange(archivePath, typ, entryName, offset, length)
+}
+
+// Returns a reader over a byte range of the named entry of a zip archive.
+func openZipRange(archivePath, entryName string, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	r, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	for _, f := range r.File {
+		if f.Name != entryName {
+			continue
+		}
+		if !zipFileMode(f).IsRegular() {
+			file.Close()
+			return nil, errUnsupportedEntry
+		}
+
+		start, n := clampRange(int64(f.UncompressedSize64), offset, length)
+		if f.Method == zip.Store {
+			dataOffset, err := f.DataOffset()
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+			}
+			return &multiReadCloser{Reader: io.NewSectionReader(file, dataOffset+start, n), closers: []io.Closer{file}}, nil
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+		}
+		return discardRange(rc, start, n, rc, file)
+	}
+
+	file.Close()
+	return nil, ErrEntryNotFound
+}
+
+// Returns a reader over a byte range of the named entry of a tar-family archive.
+func openTarRange(archivePath string, typ Type, entryName string, offset, length int64) (io.ReadCloser, error) {
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			stream.Close()
+			return nil, ErrEntryNotFound
+		} else if err != nil {
+			stream.Close()
+			return nil, fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if header.Name != entryName {
+			continue
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			stream.Close()
+			return nil, errUnsupportedEntry
+		}
+
+		start, n := clampRange(header.Size, offset, length)
+
+		// The tar reader does no buffering of its own, so an uncompressed archive is
+		// positioned at the start of the entry's contents unless the entry is sparse.
+		if file, ok := stream.(*os.File); ok && !isSparse(header) {
+			dataOffset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				stream.Close()
+				return nil, fmt.Errorf(fmtErrTarReadFailed, err)
+			}
+			return &multiReadCloser{Reader: io.NewSectionReader(file, dataOffset+start, n), closers: []io.Closer{file}}, nil
+		}
+
+		return discardRange(reader, start, n, stream)
+	}
+}
+
+// Discards the first start bytes of reader and returns a reader over the next n bytes
+// that closes the given closers.
+func discardRange(reader io.Reader, start, n int64, closers ...io.Closer) (io.ReadCloser, error) {
+	rc := &multiReadCloser{Reader: io.LimitReader(reader, n), closers: closers}
+	if _, err := io.CopyN(io.Discard, reader, start); err != nil {
+		rc.Close()
+		return nil, fmt.Errorf(fmtErrDecompress, err)
+	}
+	return rc, nil
+}
+
+// Limits the range of the given offset and length to an entry of the given size.
+func clampRange(size, offset, length int64) (int64, int64) {
+	if offset > size {
+		offset = size
+	}
+	if length > size-offset {
+		length = size - offset
+	}
+	return offset, length
+}
+
+// Reports whether the tar header describes a sparse file, whose contents are not
+// stored contiguously in the archive.
+func isSparse(header *tar.Header) bool {
+	if header.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
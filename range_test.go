@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type rangeTest struct {
+	offset, length int64
+	expected       string
+}
+
+var rangeBody = strings.Repeat("0123456789", 100)
+
+var ranges = []rangeTest{
+	{0, 10, "0123456789"},
+	{5, 3, "567"},
+	{995, 10, "56789"},
+	{1000, 10, ""},
+	{2000, 10, ""},
+	{42, 0, ""},
+	{0, 1000, rangeBody},
+}
+
+var rangeEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/before.txt", body: "lorem ipsum"},
+	{name: "dir/digits.txt", body: rangeBody},
+	{name: "dir/after.txt", body: "dolor"},
+}
+
+// Writes rangeEntries to a zip archive using the store method.
+func writeStoredZip(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stored.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for _, e := range rangeEntries {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestOpenRange(t *testing.T) {
+	paths := []string{writeStoredZip(t)}
+	for _, filename := range []string{"range.tar", "range.tar.gz", "range.tar.xz", "range.zip"} {
+		paths = append(paths, writeTestArchive(t, filename, rangeEntries))
+	}
+
+	for _, path := range paths {
+		for _, c := range ranges {
+			reader, err := OpenRange(path, "dir/digits.txt", c.offset, c.length)
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", filepath.Base(path), err)
+				continue
+			}
+
+			result, err := io.ReadAll(reader)
+			if err != nil {
+				t.Errorf("Unexpected error reading %s: %v\n", filepath.Base(path), err)
+			}
+			if string(result) != c.expected {
+				t.Errorf("%s [%d:+%d]: expecting '%s', got '%s'\n", filepath.Base(path), c.offset, c.length, c.expected, result)
+			}
+
+			if err := reader.Close(); err != nil {
+				t.Errorf("Unexpected error closing %s: %v\n", filepath.Base(path), err)
+			}
+		}
+	}
+}
+
+func TestOpenRange_errors(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		if _, err := OpenRange(archivePath, "sample/missing.txt", 0, 1); err != ErrEntryNotFound {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+		}
+
+		if _, err := OpenRange(archivePath, "sample/text/", 0, 1); err != errUnsupportedEntry {
+			t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedEntry, err)
+		}
+	}
+
+	if _, err := OpenRange("testdata/sample.zip", sampleFileName, -1, 1); err != errInvalidRange {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidRange, err)
+	}
+
+	if _, err := OpenRange("testdata/sample.zip", sampleFileName, 0, -1); err != errInvalidRange {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidRange, err)
+	}
+
+	if _, err := OpenRange("foo.123", sampleFileName, 0, 1); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	if _, err := OpenRange("nonexistent.zip", sampleFileName, 0, 1); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// reproducibleCreationWindow is how long before an archive file was last modified an
+// entry's modification time must fall to be taken as the time the archive was built.
+const reproducibleCreationWindow = 10 * time.Minute
+
+// IsReproducible reports whether the archive at archivePath, whose type is determined by
+// DetermineType, is free of the traits that commonly make archives built from the same
+// sources differ from one build to the next, as checked by reproducible-build projects.
+// If it is not, the returned reasons describe each trait found, in the order below, naming
+// the number of entries affected and the first of them:
+//
+//   - entries owned by a nonzero user or group ID, which reflect the account that built
+//     the archive; for zip archives, IDs are taken from Info-ZIP or PKWARE extra fields,
+//     as by ParseExtraOwner
+//   - zip entries whose MS-DOS modification times were written in a time zone other
+//     than UTC, as revealed by a UTC time in an extended timestamp field
+//   - entries last modified within ten minutes before the archive file itself, which
+//     suggests files generated during the build rather than timestamps clamped to a
+//     fixed value, such as that of SOURCE_DATE_EPOCH
+//   - entries that are not sorted by name, compared one path component at a time as
+//     GNU tar's --sort=name orders them, since unsorted entries usually follow the
+//     order in which a file system happened to list them
+//
+// Passing these checks does not prove an archive reproducible, as that can only be shown
+// by rebuilding it, but failing any of them is a likely cause of differing builds.
+func IsReproducible(archivePath string) (reproducible bool, reasons []string, err error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return false, nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	created := info.ModTime()
+
+	var owned, zoned, recent, unsorted reproducibilityTrait
+	var previous []string
+	err = forEachEntry(archivePath, func(e *entry) error {
+		var uid, gid int
+		if e.header != nil {
+			uid, gid = e.header.Uid, e.header.Gid
+		} else {
+			uid, gid, _ = ParseExtraOwner(e.file)
+		}
+		if uid != 0 || gid != 0 {
+			owned.add(e.name)
+		}
+
+		if e.file != nil {
+			if _, offset := e.file.Modified.Zone(); offset != 0 {
+				zoned.add(e.name)
+			}
+		}
+
+		if age := created.Sub(e.modTime); age >= 0 && age <= reproducibleCreationWindow {
+			recent.add(e.name)
+		}
+
+		components := strings.Split(strings.TrimSuffix(e.name, "/"), "/")
+		if previous != nil && slices.Compare(previous, components) > 0 {
+			unsorted.add(e.name)
+		}
+		previous = components
+		return nil
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	reasons = owned.appendReason(reasons, "owned by a nonzero user or group ID")
+	reasons = zoned.appendReason(reasons, "timestamped in a time zone other than UTC")
+	reasons = recent.appendReason(reasons, "modified shortly before the archive was created")
+	reasons = unsorted.appendReason(reasons, "out of name order")
+
+	return len(reasons) == 0, reasons, nil
+}
+
+// Struct reproducibilityTrait counts the entries exhibiting a trait that hinders
+// reproducibility, remembering the first of them.
+type reproducibilityTrait struct {
+	count int
+	first string
+}
+
+// Records an entry exhibiting the trait.
+func (t *reproducibilityTrait) add(name string) {
+	if t.count == 0 {
+		t.first = name
+	}
+	t.count++
+}
+
+// Returns reasons with a reason describing the trait appended, if any entry exhibits it.
+func (t *reproducibilityTrait) appendReason(reasons []string, description string) []string {
+	if t.count == 0 {
+		return reasons
+	}
+
+	noun := "entries are"
+	if t.count == 1 {
+		noun = "entry is"
+	}
+	return append(reasons, fmt.Sprintf("%d %s %s, starting with %q", t.count, noun, description, t.first))
+}
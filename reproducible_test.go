@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var isReproducibleTests = []struct {
+	entries []testEntry
+	reasons []string
+}{
+	{[]testEntry{{name: "a/"}, {name: "a/b.txt"}, {name: "a-b.txt"}, {name: "c.txt"}}, nil},
+	{
+		[]testEntry{{name: "b.txt"}, {name: "a.txt"}, {name: "c/"}, {name: "c/b.txt"}, {name: "c/a.txt"}},
+		[]string{`2 entries are out of name order, starting with "a.txt"`},
+	},
+	{
+		[]testEntry{{name: "a.txt"}, {name: "b.txt", modTime: time.Now().Add(-time.Minute)}},
+		[]string{`1 entry is modified shortly before the archive was created, starting with "b.txt"`},
+	},
+}
+
+func TestIsReproducible(t *testing.T) {
+	for _, test := range isReproducibleTests {
+		for _, filename := range []string{"repro.tar.gz", "repro.zip"} {
+			path := writeTestArchive(t, filename, test.entries)
+
+			reproducible, reasons, err := IsReproducible(path)
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", filename, err)
+			}
+			if reproducible != (test.reasons == nil) || !reflect.DeepEqual(reasons, test.reasons) {
+				t.Errorf("%s: expecting '%v', got %t '%v'\n", filename, test.reasons, reproducible, reasons)
+			}
+		}
+	}
+
+	if _, _, err := IsReproducible("nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+func TestIsReproducible_owner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owner.tar")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(file)
+	for _, header := range []*tar.Header{
+		{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0644, ModTime: testModTime},
+		{Name: "b.txt", Typeflag: tar.TypeReg, Mode: 0644, ModTime: testModTime, Uid: 1000, Gid: 1000},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	expected := []string{`1 entry is owned by a nonzero user or group ID, starting with "b.txt"`}
+	if reproducible, reasons, err := IsReproducible(path); reproducible || !reflect.DeepEqual(reasons, expected) || err != nil {
+		t.Errorf("Expecting '%v', got %t '%v' (error: %v)\n", expected, reproducible, reasons, err)
+	}
+}
+
+func TestIsReproducible_timeZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zone.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(file)
+	modified := testModTime.In(time.FixedZone("CEST", 2*60*60))
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: "a.txt", Modified: modified}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	expected := []string{`1 entry is timestamped in a time zone other than UTC, starting with "a.txt"`}
+	if reproducible, reasons, err := IsReproducible(path); reproducible || !reflect.DeepEqual(reasons, expected) || err != nil {
+		t.Errorf("Expecting '%v', got %t '%v' (error: %v)\n", expected, reproducible, reasons, err)
+	}
+}
@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotHeaderBoundary is returned by WalkTarFrom when the starting offset does not
+// locate the beginning of a header within the tar archive.
+var ErrNotHeaderBoundary = errors.New("archive: offset is not a tar header boundary")
+
+// TarOffsetCallback is the type of function called for each file or directory entry
+// visited by WalkTarOffsets. The offset is the position within the archive of the
+// entry's first header block, including any extended or long name headers.
+type TarOffsetCallback func(reader *tar.Reader, header *tar.Header, offset int64) error
+
+// WalkTarOffsets walks the contents of an uncompressed tar file and invokes the
+// callback function for each entry along with the offset at which the entry begins.
+// Any of the reported offsets can later be passed to WalkTarFrom to resume a walk
+// at that entry.
+func WalkTarOffsets(archivePath string, callback TarOffsetCallback) error {
+	return walkTarOffsets(archivePath, 0, callback)
+}
+
+// WalkTarFrom walks the contents of an uncompressed tar file beginning at startOffset,
+// which must be an offset previously reported by WalkTarOffsets, and invokes the
+// callback function for each entry from that point on. This allows the processing of
+// a large archive to be checkpointed and resumed without rereading the entries that
+// precede the checkpoint. If startOffset is negative, not a multiple of the tar block
+// size, beyond the end of the archive, or does not locate a valid header, an error
+// wrapping ErrNotHeaderBoundary is returned.
+func WalkTarFrom(archivePath string, startOffset int64, callback TarCallback) error {
+	if startOffset < 0 || startOffset%tarBlockSize != 0 {
+		return ErrNotHeaderBoundary
+	}
+
+	return walkTarOffsets(archivePath, startOffset, func(reader *tar.Reader, header *tar.Header, _ int64) error {
+		if callback == nil {
+			return nil
+		}
+		return callback(reader, header)
+	})
+}
+
+// Walks an uncompressed tar file beginning at the header located at start,
+// tracking the offset of each entry.
+func walkTarOffsets(archivePath string, start int64, callback TarOffsetCallback) error {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	if start > info.Size() {
+		return ErrNotHeaderBoundary
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf(fmtErrTarReadFailed, err)
+	}
+
+	// The tar reader does no buffering of its own, so the file's position after each
+	// call to Next is the start of the entry's contents.
+	reader := tar.NewReader(file)
+	offset := start
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			if offset == start && start != 0 {
+				return fmt.Errorf("%w: %v", ErrNotHeaderBoundary, err)
+			}
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		dataStart, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if callback != nil {
+			if err := callback(reader, header, offset); err != nil {
+				return fmt.Errorf(fmtErrTarCallbackFailed, err)
+			}
+		}
+
+		end := dataStart + storedSize(header)
+		if isSparse(header) {
+			// The stored size of a sparse file is not exposed, so its contents are
+			// consumed to find where they end.
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				return fmt.Errorf(fmtErrTarReadFailed, err)
+			}
+			if end, err = file.Seek(0, io.SeekCurrent); err != nil {
+				return fmt.Errorf(fmtErrTarReadFailed, err)
+			}
+		}
+		offset = (end + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+	}
+}
+
+// Returns the number of content bytes stored in the archive for a non-sparse entry.
+// Links, directories, devices, and named pipes store no contents regardless of size.
+func storedSize(header *tar.Header) int64 {
+	switch header.Typeflag {
+	case tar.TypeLink, tar.TypeSymlink, tar.TypeChar, tar.TypeBlock, tar.TypeDir, tar.TypeFifo:
+		return 0
+	}
+	return header.Size
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var resumeEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "lorem ipsum"},
+	{name: "dir/" + strings.Repeat("long", 40) + ".txt", body: strings.Repeat("dolor ", 200)},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+	{name: "dir/hard", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+	{name: "dir/empty.txt"},
+	{name: "dir/b.txt", body: strings.Repeat("x", tarBlockSize)},
+	{name: "c.txt", body: "sit amet"},
+}
+
+func TestWalkTarFrom(t *testing.T) {
+	path := writeTestArchive(t, "resume.tar", resumeEntries)
+
+	var names []string
+	var offsets []int64
+	err := WalkTarOffsets(path, func(reader *tar.Reader, header *tar.Header, offset int64) error {
+		names = append(names, header.Name)
+		offsets = append(offsets, offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if len(offsets) != len(resumeEntries) || offsets[0] != 0 {
+		t.Fatalf("Unexpected offsets: %v\n", offsets)
+	}
+
+	for i, offset := range offsets {
+		var resumed []string
+		err := WalkTarFrom(path, offset, func(reader *tar.Reader, header *tar.Header) error {
+			resumed = append(resumed, header.Name)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error resuming at %d: %v\n", offset, err)
+		}
+		if !reflect.DeepEqual(resumed, names[i:]) {
+			t.Errorf("Expecting '%v', got '%v'\n", names[i:], resumed)
+		}
+	}
+
+	var resumed []string
+	err = WalkTarOffsets("testdata/sample.tar", func(reader *tar.Reader, header *tar.Header, offset int64) error {
+		if header.Name == sampleFileName {
+			return WalkTarFrom("testdata/sample.tar", offset, func(reader *tar.Reader, header *tar.Header) error {
+				resumed = append(resumed, header.Name)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil || len(resumed) == 0 || resumed[0] != sampleFileName {
+		t.Errorf("Failed to resume walk of sample archive: %v (error: %v)\n", resumed, err)
+	}
+}
+
+func TestWalkTarFrom_errors(t *testing.T) {
+	path := writeTestArchive(t, "resume.tar", resumeEntries)
+
+	var offsets []int64
+	err := WalkTarOffsets(path, func(reader *tar.Reader, header *tar.Header, offset int64) error {
+		offsets = append(offsets, offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The block following the header of dir/b.txt holds its contents.
+	contents := offsets[6] + tarBlockSize
+
+	for _, offset := range []int64{-tarBlockSize, 1, tarBlockSize + 7, contents, 1 << 40} {
+		err := WalkTarFrom(path, offset, nil)
+		if !errors.Is(err, ErrNotHeaderBoundary) {
+			t.Errorf("%d: expecting '%s', got '%v'\n", offset, ErrNotHeaderBoundary, err)
+		}
+	}
+
+	errStop := errors.New("stop")
+	err = WalkTarOffsets(path, func(reader *tar.Reader, header *tar.Header, offset int64) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	err = WalkTarFrom(path, offsets[1], func(reader *tar.Reader, header *tar.Header) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	if err := WalkTarFrom("nonexistent.tar", 0, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+
+	if err := WalkTarOffsets("testdata/invalid.tar", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
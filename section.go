@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WalkZipAt walks the contents of a zip archive embedded within the file at path,
+// occupying the size bytes beginning at offset, and invokes the callback function for
+// each entry. This allows archives appended to or stored inside other files, such as
+// firmware images or self-extracting executables, to be inspected without first
+// being copied out. If the described section does not lie within the file, an error
+// is returned.
+func WalkZipAt(path string, offset, size int64, callback ZipCallback) error {
+	file, section, err := openSection(path, offset, size)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := zip.NewReader(section, size)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	return readZipWrapping(r.File, callback)
+}
+
+// WalkTarGzAt walks the contents of a gzip-compressed tar archive embedded within the
+// file at path, occupying the size bytes beginning at offset, and invokes the callback
+// function for each entry. If the described section does not lie within the file, an
+// error is returned.
+func WalkTarGzAt(path string, offset, size int64, callback TarCallback) error {
+	file, section, err := openSection(path, offset, size)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(section)
+	if err != nil {
+		return fmt.Errorf(fmtErrNewGzReader, err)
+	}
+	defer reader.Close()
+
+	return readTarWrapping(tar.NewReader(reader), callback)
+}
+
+// Opens the file at path and returns it along with a reader over the size bytes
+// beginning at offset, provided that they lie within the file.
+func openSection(path string, offset, size int64) (*os.File, *io.SectionReader, error) {
+	if offset < 0 || size < 0 {
+		return nil, nil, errInvalidRange
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	if offset > info.Size() || size > info.Size()-offset {
+		file.Close()
+		return nil, nil, errInvalidRange
+	}
+
+	return file, io.NewSectionReader(file, offset, size), nil
+}
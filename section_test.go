@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes the contents of the file at src between a prefix and suffix of junk
+// and returns the new file's path along with the offset and size of the copy.
+func embedInFile(t *testing.T, src string) (string, int64, int64) {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := strings.Repeat("firmware", 1000)
+	suffix := strings.Repeat("trailer", 100)
+	path := filepath.Join(t.TempDir(), "image.bin")
+	if err := os.WriteFile(path, []byte(prefix+string(data)+suffix), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, int64(len(prefix)), int64(len(data))
+}
+
+func TestWalkZipAt(t *testing.T) {
+	path, offset, size := embedInFile(t, "testdata/sample.zip")
+
+	var names []string
+	err := WalkZipAt(path, offset, size, func(f *zip.File) error {
+		names = append(names, f.Name)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if len(names) != len(sampleEntries) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(sampleEntries), len(names))
+	}
+
+	if err := WalkZipAt(path, 0, size, nil); err == nil {
+		t.Error("Failed to receive non-nil error for an incorrect offset.")
+	}
+
+	if err := WalkZipAt(path, offset, 1<<40, nil); err != errInvalidRange {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidRange, err)
+	}
+
+	if err := WalkZipAt(path, -1, size, nil); err != errInvalidRange {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidRange, err)
+	}
+
+	if err := WalkZipAt("nonexistent.bin", 0, 0, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+}
+
+func TestWalkTarGzAt(t *testing.T) {
+	path, offset, size := embedInFile(t, "testdata/sample.tar.gz")
+
+	var names []string
+	err := WalkTarGzAt(path, offset, size, func(reader *tar.Reader, header *tar.Header) error {
+		names = append(names, header.Name)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if len(names) != len(sampleEntries) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(sampleEntries), len(names))
+	}
+
+	if err := WalkTarGzAt(path, 0, size, nil); err == nil {
+		t.Error("Failed to receive non-nil error for an incorrect offset.")
+	}
+
+	if err := WalkTarGzAt(path, 1<<40, 1, nil); err != errInvalidRange {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidRange, err)
+	}
+}
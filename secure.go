@@ -0,0 +1,326 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractAllSecure extracts every entry of the archive at path, whose type is determined
+// by DetermineType, into dest as ExtractAll does, but follows symbolic links that remain
+// within dest rather than refusing every entry beneath one. Checking entry names
+// lexically cannot detect an archive whose symbolic links are individually harmless but
+// together lead outside of dest, such as "a/b" linking to ".." followed by "a/b/c"
+// linking to "..", so ExtractAll writes nothing beneath a symbolic link at all; it
+// cannot, however, detect a symbolic link planted in dest by another process while
+// extraction is under way.
+//
+// On Linux, every file, directory, and link is created relative to a handle on dest using
+// openat2(2) with RESOLVE_BENEATH, so the kernel itself refuses to resolve any path,
+// through symbolic links or otherwise, to a location outside of dest. Files are opened
+// with O_NOFOLLOW, so an entry is never written through a symbolic link occupying its
+// name. On other platforms, and on Linux kernels older than 5.6, which lack openat2, the
+// fully resolved parent directory of each entry is checked to lie within dest before the
+// entry is created; this detects links created by the archive itself but not those
+// created concurrently by another process.
+//
+// Symbolic links are recreated provided that their targets, taken lexically, resolve
+// to locations within dest; once created, they may be followed by later entries only
+// as far as they remain within dest. An entry that would be written outside of dest
+// causes an error wrapping ErrUnsafePath to be returned.
+func ExtractAllSecure(path, dest string) error {
+	root, err := openSecureRoot(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	return extractAllSecure(path, root)
+}
+
+// Interface secureRoot creates files, directories, and links at paths relative to
+// an extraction root, refusing to resolve any path to a location outside of it.
+type secureRoot interface {
+	mkdirAll(name string) error
+	create(name string, perm fs.FileMode) (*os.File, error)
+	open(name string) (*os.File, error)
+	symlink(linkname, name string) error
+	link(oldname, newname string) error
+	remove(name string) error
+	exists(name string) bool
+	Close() error
+}
+
+// Extracts every entry of an archive beneath root.
+func extractAllSecure(archivePath string, root secureRoot) error {
+	var pending []hardLink
+
+	err := forEachEntry(archivePath, func(e *entry) error {
+		name, err := SafeJoin(".", e.name)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case e.header != nil && e.header.Typeflag == tar.TypeLink:
+			oldname, err := SafeJoin(".", e.header.Linkname)
+			if err != nil {
+				return err
+			}
+
+			link := hardLink{oldname: oldname, newname: name}
+			if !root.exists(oldname) {
+				pending = append(pending, link)
+				return nil
+			}
+			return createSecureLink(root, link)
+		case e.mode.IsDir():
+			return root.mkdirAll(name)
+		case e.mode&fs.ModeSymlink != 0:
+			linkname, err := symlinkTarget(e)
+			if err != nil {
+				return err
+			}
+
+			if linkname == "" || filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") ||
+				!isWithin(".", filepath.Join(filepath.Dir(name), filepath.FromSlash(linkname))) {
+				return ErrUnsafePath
+			}
+			return root.symlink(filepath.FromSlash(linkname), name)
+		case !e.mode.IsRegular():
+			return nil
+		}
+
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		return writeSecureFile(root, name, reader, e.mode)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, link := range pending {
+		if err := createSecureLink(root, link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Creates a hard link beneath root, copying the target's contents if the link
+// cannot be created.
+func createSecureLink(root secureRoot, link hardLink) error {
+	if !root.exists(link.oldname) {
+		return fmt.Errorf("%w: %q", errLinkTargetNotFound, link.oldname)
+	}
+
+	if root.link(link.oldname, link.newname) == nil {
+		return nil
+	}
+
+	source, err := root.open(link.oldname)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+
+	return writeSecureFile(root, link.newname, source, info.Mode())
+}
+
+// Writes the contents of reader to a new file named name beneath root. If the
+// contents cannot be written in full, the partially-written file is removed.
+func writeSecureFile(root secureRoot, name string, reader io.Reader, mode fs.FileMode) error {
+	file, err := root.create(name, filePerm(mode))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		root.remove(name)
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+	return nil
+}
+
+// Struct pathRoot is a secureRoot that checks, before creating anything, that the
+// resolved parent directory of each path lies within the root. It cannot detect
+// links created concurrently by another process.
+type pathRoot struct {
+	dest string
+}
+
+// Returns a pathRoot for dest, creating dest if it does not exist.
+func openPathRoot(dest string) (secureRoot, error) {
+	if err := makeDir(dest); err != nil {
+		return nil, err
+	}
+
+	resolved, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrCreateDir, err)
+	}
+	return &pathRoot{dest: resolved}, nil
+}
+
+// Returns the path of dir within the root, provided that its longest existing
+// ancestor resolves to a location within the root.
+func (r *pathRoot) resolveDir(dir string) (string, error) {
+	target := filepath.Join(r.dest, dir)
+
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil || existing == r.dest {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil || !isWithin(r.dest, resolved) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, dir)
+	}
+	return target, nil
+}
+
+// Returns the path of name within the root, provided that its parent directory
+// resolves to a location within the root and that name is not a symbolic link.
+func (r *pathRoot) resolve(name string) (string, error) {
+	if _, err := r.resolveDir(filepath.Dir(name)); err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(r.dest, name)
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", fmt.Errorf("%w: %q is a symbolic link", ErrUnsafePath, name)
+	}
+	return target, nil
+}
+
+// Creates the directory name along with any missing parents.
+func (r *pathRoot) mkdirAll(name string) error {
+	target, err := r.resolveDir(name)
+	if err != nil {
+		return err
+	}
+	return makeDir(target)
+}
+
+// Creates or truncates the file name for writing, creating any missing parents.
+func (r *pathRoot) create(name string, perm fs.FileMode) (*os.File, error) {
+	if err := r.mkdirAll(filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+
+	target, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrCreateFile, err)
+	}
+	return file, nil
+}
+
+// Opens the file name for reading.
+func (r *pathRoot) open(name string) (*os.File, error) {
+	target, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrReadFile, err)
+	}
+	return file, nil
+}
+
+// Creates a symbolic link named name pointing to linkname, keeping an existing
+// link to the same destination.
+func (r *pathRoot) symlink(linkname, name string) error {
+	if err := r.mkdirAll(filepath.Dir(name)); err != nil {
+		return err
+	}
+
+	target := filepath.Join(r.dest, name)
+	if existing, err := os.Readlink(target); err == nil && existing == linkname {
+		return nil
+	}
+
+	if err := os.Symlink(linkname, target); err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	return nil
+}
+
+// Creates a hard link named newname to the file oldname, replacing any existing
+// file named newname.
+func (r *pathRoot) link(oldname, newname string) error {
+	oldTarget, err := r.resolve(oldname)
+	if err != nil {
+		return err
+	}
+
+	if err := r.mkdirAll(filepath.Dir(newname)); err != nil {
+		return err
+	}
+
+	newTarget, err := r.resolve(newname)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(newTarget); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+
+	if err := os.Link(oldTarget, newTarget); err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	return nil
+}
+
+// Removes the file name, without following a final symbolic link.
+func (r *pathRoot) remove(name string) error {
+	target, err := r.resolveDir(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filepath.Join(target, filepath.Base(name))); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+	return nil
+}
+
+// Reports whether anything exists at name, without following a final symbolic link.
+func (r *pathRoot) exists(name string) bool {
+	_, err := os.Lstat(filepath.Join(r.dest, name))
+	return err == nil
+}
+
+// Close releases the root; a pathRoot holds no resources.
+func (r *pathRoot) Close() error {
+	return nil
+}
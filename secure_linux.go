@@ -0,0 +1,210 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Struct openat2Root is a secureRoot that resolves every path relative to a handle
+// on the root directory using openat2(2), so that the kernel refuses to resolve any
+// path to a location outside of the root.
+type openat2Root struct {
+	fd int
+}
+
+// Returns a secureRoot for dest, creating dest if it does not exist. If the kernel
+// does not support openat2, a pathRoot is returned instead.
+func openSecureRoot(dest string) (secureRoot, error) {
+	if err := makeDir(dest); err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Open(dest, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrCreateDir, err)
+	}
+	root := &openat2Root{fd: fd}
+
+	probe, err := root.openat(".", unix.O_PATH|unix.O_DIRECTORY, 0)
+	if errors.Is(err, unix.ENOSYS) {
+		root.Close()
+		return openPathRoot(dest)
+	} else if err != nil {
+		root.Close()
+		return nil, fmt.Errorf(fmtErrCreateDir, err)
+	}
+	unix.Close(probe)
+
+	return root, nil
+}
+
+// Opens name relative to the root with the given flags and mode, refusing to
+// resolve it to a location outside of the root. An error wrapping ErrUnsafePath
+// is returned if resolution would escape the root or, with O_NOFOLLOW, if name
+// is a symbolic link.
+func (r *openat2Root) openat(name string, flags int, mode fs.FileMode) (int, error) {
+	how := &unix.OpenHow{
+		Flags:   uint64(flags | unix.O_CLOEXEC),
+		Mode:    uint64(mode),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+
+	for {
+		fd, err := unix.Openat2(r.fd, name, how)
+		switch {
+		case err == unix.EINTR || err == unix.EAGAIN:
+			continue
+		case err == unix.EXDEV || err == unix.ELOOP:
+			return -1, fmt.Errorf("%w: %q", ErrUnsafePath, name)
+		}
+		return fd, err
+	}
+}
+
+// Opens the parent directory of name, returning its handle along with the final
+// component of name.
+func (r *openat2Root) parent(name string) (int, string, error) {
+	fd, err := r.openat(filepath.Dir(name), unix.O_PATH|unix.O_DIRECTORY, 0)
+	return fd, filepath.Base(name), err
+}
+
+// Creates the directory name along with any missing parents, each relative to
+// the already-resolved handle of its own parent.
+func (r *openat2Root) mkdirAll(name string) error {
+	if name == "." {
+		return nil
+	}
+
+	components := strings.Split(name, string(filepath.Separator))
+	for i := range components {
+		parent, base, err := r.parent(filepath.Join(components[:i+1]...))
+		if err != nil {
+			return wrapUnlessUnsafe(fmtErrCreateDir, err)
+		}
+
+		err = unix.Mkdirat(parent, base, uint32(extractDirPerm))
+		unix.Close(parent)
+		if err != nil && err != unix.EEXIST {
+			return fmt.Errorf(fmtErrCreateDir, err)
+		}
+	}
+	return nil
+}
+
+// Creates or truncates the file name for writing, creating any missing parents.
+func (r *openat2Root) create(name string, perm fs.FileMode) (*os.File, error) {
+	if err := r.mkdirAll(filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+
+	fd, err := r.openat(name, unix.O_CREAT|unix.O_WRONLY|unix.O_TRUNC|unix.O_NOFOLLOW, perm)
+	if err != nil {
+		return nil, wrapUnlessUnsafe(fmtErrCreateFile, err)
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// Opens the file name for reading.
+func (r *openat2Root) open(name string) (*os.File, error) {
+	fd, err := r.openat(name, unix.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, wrapUnlessUnsafe(fmtErrReadFile, err)
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// Creates a symbolic link named name pointing to linkname, keeping an existing
+// link to the same destination.
+func (r *openat2Root) symlink(linkname, name string) error {
+	if err := r.mkdirAll(filepath.Dir(name)); err != nil {
+		return err
+	}
+
+	parent, base, err := r.parent(name)
+	if err != nil {
+		return wrapUnlessUnsafe(fmtErrCreateLink, err)
+	}
+	defer unix.Close(parent)
+
+	buf := make([]byte, len(linkname)+1)
+	if n, err := unix.Readlinkat(parent, base, buf); err == nil && string(buf[:n]) == linkname {
+		return nil
+	}
+
+	if err := unix.Symlinkat(linkname, parent, base); err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	return nil
+}
+
+// Creates a hard link named newname to the file oldname, replacing any existing
+// file named newname.
+func (r *openat2Root) link(oldname, newname string) error {
+	if err := r.mkdirAll(filepath.Dir(newname)); err != nil {
+		return err
+	}
+
+	oldParent, oldBase, err := r.parent(oldname)
+	if err != nil {
+		return wrapUnlessUnsafe(fmtErrCreateLink, err)
+	}
+	defer unix.Close(oldParent)
+
+	newParent, newBase, err := r.parent(newname)
+	if err != nil {
+		return wrapUnlessUnsafe(fmtErrCreateLink, err)
+	}
+	defer unix.Close(newParent)
+
+	if err := unix.Unlinkat(newParent, newBase, 0); err != nil && err != unix.ENOENT {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+
+	if err := unix.Linkat(oldParent, oldBase, newParent, newBase, 0); err != nil {
+		return fmt.Errorf(fmtErrCreateLink, err)
+	}
+	return nil
+}
+
+// Removes the file name, relative to the handle of its parent.
+func (r *openat2Root) remove(name string) error {
+	parent, base, err := r.parent(name)
+	if err != nil {
+		return wrapUnlessUnsafe(fmtErrWriteFile, err)
+	}
+	defer unix.Close(parent)
+
+	if err := unix.Unlinkat(parent, base, 0); err != nil {
+		return fmt.Errorf(fmtErrWriteFile, err)
+	}
+	return nil
+}
+
+// Reports whether anything exists at name, without following a final symbolic link.
+func (r *openat2Root) exists(name string) bool {
+	fd, err := r.openat(name, unix.O_PATH|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return false
+	}
+	unix.Close(fd)
+	return true
+}
+
+// Close closes the handle on the root directory.
+func (r *openat2Root) Close() error {
+	return unix.Close(r.fd)
+}
+
+// Returns err wrapped with format, unless it already reports an unsafe path.
+func wrapUnlessUnsafe(format string, err error) error {
+	if errors.Is(err, ErrUnsafePath) {
+		return err
+	}
+	return fmt.Errorf(format, err)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package archive
+
+// Returns a secureRoot for dest, creating dest if it does not exist. Platforms
+// other than Linux rely on path-based checks.
+func openSecureRoot(dest string) (secureRoot, error) {
+	return openPathRoot(dest)
+}
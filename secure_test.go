@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Functions opening the secure extraction roots under test; on Linux, the first
+// uses openat2 and the second the path-based fallback.
+var secureRootOpeners = []func(dest string) (secureRoot, error){
+	openSecureRoot,
+	openPathRoot,
+}
+
+// Extracts the archive at archivePath into dest using the root returned by open.
+func extractSecure(archivePath, dest string, open func(dest string) (secureRoot, error)) error {
+	root, err := open(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	return extractAllSecure(archivePath, root)
+}
+
+func TestExtractAllSecure(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		dest := filepath.Join(t.TempDir(), "dest")
+
+		if err := ExtractAllSecure(archivePath, dest); err != nil {
+			t.Errorf("Unexpected error extracting %s: %v\n", archivePath, err)
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(sampleFileName)))
+		if err != nil {
+			t.Errorf("Failed to stat extracted file: %v\n", err)
+		} else if info.Size() != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, info.Size())
+		}
+	}
+}
+
+func TestExtractAllSecure_links(t *testing.T) {
+	path := writeTestArchive(t, "links.tar", hardLinkEntries)
+
+	for _, open := range secureRootOpeners {
+		dest := t.TempDir()
+		if err := extractSecure(path, dest, open); err != nil {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+
+		original, err := os.Stat(filepath.Join(dest, "dir", "a.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"early.txt", "dir/b.txt"} {
+			linked, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+			if err != nil {
+				t.Errorf("Failed to stat hard link %s: %v\n", name, err)
+			} else if !os.SameFile(original, linked) {
+				t.Errorf("Expected %s to be a hard link to dir/a.txt.\n", name)
+			}
+		}
+
+		linkname, err := os.Readlink(filepath.Join(dest, "dir", "c"))
+		if err != nil || linkname != "a.txt" {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "a.txt", linkname, err)
+		}
+
+		// Extracting again keeps the symbolic link and replaces the hard links.
+		if err := extractSecure(path, dest, open); err != nil {
+			t.Errorf("Unexpected error on repeated extraction: %v\n", err)
+		}
+	}
+}
+
+// Each link is harmless on its own, but "a/b/c" resolves to the parent of the
+// destination directory once "a/b" has been created.
+var chainedLinkEntries = []testEntry{
+	{name: "a/"},
+	{name: "a/b", typeflag: tar.TypeSymlink, linkname: ".."},
+	{name: "a/b/c", typeflag: tar.TypeSymlink, linkname: ".."},
+	{name: "a/b/c/escaped.txt", body: "lorem ipsum"},
+}
+
+func TestExtractAllSecure_chainedLinks(t *testing.T) {
+	path := writeTestArchive(t, "chained.tar", chainedLinkEntries)
+
+	for _, open := range secureRootOpeners {
+		parent := t.TempDir()
+		dest := filepath.Join(parent, "dest")
+
+		if err := extractSecure(path, dest, open); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+		}
+		if _, err := os.Lstat(filepath.Join(parent, "escaped.txt")); err == nil {
+			t.Error("Expected no file to be written outside of the destination directory.")
+		}
+	}
+}
+
+func TestExtractAllSecure_errors(t *testing.T) {
+	// A symbolic link planted in the destination is not written through.
+	outside := t.TempDir()
+	for _, open := range secureRootOpeners {
+		dest := t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(dest, "planted")); err != nil {
+			t.Fatal(err)
+		}
+
+		path := writeTestArchive(t, "planted.tar", []testEntry{{name: "planted/file.txt", body: "lorem"}})
+		if err := extractSecure(path, dest, open); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafePath, err)
+		}
+		if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+			t.Errorf("Expected no files to be written, got %d\n", len(entries))
+		}
+	}
+
+	for _, entries := range unsafeLinkTests {
+		path := writeTestArchive(t, "unsafe.tar", entries)
+		if err := ExtractAllSecure(path, t.TempDir()); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expecting '%s', got '%v'\n", entries[0].name, ErrUnsafePath, err)
+		}
+	}
+
+	path := writeTestArchive(t, "missing.tar", []testEntry{
+		{name: "link.txt", typeflag: tar.TypeLink, linkname: "missing.txt"},
+	})
+	if err := ExtractAllSecure(path, t.TempDir()); !errors.Is(err, errLinkTargetNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", errLinkTargetNotFound, err)
+	}
+
+	if err := ExtractAllSecure("testdata/invalid.tar", t.TempDir()); err == nil {
+		t.Error("Failed to receive non-nil error when extracting an invalid tar file.")
+	}
+}
+
+func TestWriteSecureFile(t *testing.T) {
+	errRead := errors.New("read failed")
+	for _, open := range secureRootOpeners {
+		dest := t.TempDir()
+		root, err := open(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errRead))
+		if err := writeSecureFile(root, filepath.Join("dir", "file.txt"), reader, 0600); !errors.Is(err, errRead) {
+			t.Errorf("Expecting '%s', got '%v'\n", errRead, err)
+		}
+		if _, err := os.Lstat(filepath.Join(dest, "dir", "file.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected the partially-written file to be removed, got '%v'\n", err)
+		}
+		root.Close()
+	}
+}
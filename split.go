@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrEntryTooLarge is returned by Split when an entry cannot fit within a single part.
+// The returned error wraps ErrEntryTooLarge and names the entry and the size it needs.
+var ErrEntryTooLarge = errors.New("archive: entry too large for a part")
+
+// errInvalidPartSize is returned by Split for a part size too small to hold any archive.
+var errInvalidPartSize = errors.New("archive: part size must exceed the 1024-byte tar trailer")
+
+// tarTrailerSize is the size of the two zero blocks that end a tar archive.
+const tarTrailerSize = 2 * tarBlockSize
+
+// Split divides the tar-family archive at srcPath, whose type is determined by
+// DetermineType, into parts, each a complete tar archive holding a run of consecutive
+// entries, so that it can be distributed over transports that limit the size of files.
+// Entries are never divided between parts. Each part is compressed as the source is and
+// named after destPrefix, followed by its number, counting from 1 and padded to three
+// digits, and the usual extension for the type, so that a TarGz archive is split into
+// "prefix.001.tar.gz", "prefix.002.tar.gz", and so on. The paths of the parts are
+// returned in order; an archive without entries yields none.
+//
+// The uncompressed tar data of each part, comprising its entries' headers and padded
+// contents and the 1024-byte trailer, is at most maxPartBytes, so the parts of a Tar
+// archive are no larger than maxPartBytes. Compressed parts are usually smaller, though
+// compressing data that is already compressed can add a few bytes. If an entry alone
+// would exceed maxPartBytes, an error wrapping ErrEntryTooLarge is returned. Since
+// bzip2 and lzma compression are not available for writing, TarBz2 and TarLzma
+// archives are not supported. A hard link refers to its target by name, so extracting
+// a part holding the link requires that the part holding the target be extracted
+// first. If an error occurs, any parts already written are removed.
+func Split(srcPath, destPrefix string, maxPartBytes int64) (parts []string, err error) {
+	if maxPartBytes <= tarTrailerSize {
+		return nil, errInvalidPartSize
+	}
+
+	typ, err := DetermineType(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isCompressible(typ) {
+		return nil, errUnsupportedWriteType
+	}
+
+	src, err := openTarStream(srcPath, typ)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	w := &partWriter{prefix: destPrefix, typ: typ}
+	defer func() {
+		if cerr := w.closePart(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			for _, part := range w.parts {
+				os.Remove(part)
+			}
+			parts = nil
+		}
+	}()
+
+	reader := tar.NewReader(src)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		size, err := tarEntrySize(header)
+		if err != nil {
+			return nil, err
+		}
+		if size+tarTrailerSize > maxPartBytes {
+			return nil, fmt.Errorf("%w: %q needs %d bytes", ErrEntryTooLarge, header.Name, size+tarTrailerSize)
+		}
+
+		if w.tarWriter == nil || w.written+size+tarTrailerSize > maxPartBytes {
+			if err := w.closePart(); err != nil {
+				return nil, err
+			}
+			if err := w.openPart(); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := w.tarWriter.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		if _, err := io.Copy(w.tarWriter, reader); err != nil {
+			return nil, fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		w.written += size
+	}
+
+	return w.parts, nil
+}
+
+// Returns the number of bytes that header and the contents it describes occupy in a
+// tar stream written by a tar.Writer, which may include extended headers for long
+// names or other attributes.
+func tarEntrySize(header *tar.Header) (int64, error) {
+	var encoded bytes.Buffer
+	if err := tar.NewWriter(&encoded).WriteHeader(header); err != nil {
+		return 0, fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+
+	size := header.Size
+	if header.Typeflag == tar.TypeLink || header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeDir {
+		size = 0
+	}
+	return int64(encoded.Len()) + (size+tarBlockSize-1)/tarBlockSize*tarBlockSize, nil
+}
+
+// Struct partWriter writes the parts of a split archive in turn.
+type partWriter struct {
+	prefix     string
+	typ        Type
+	parts      []string       // paths of the parts created so far
+	file       *os.File       // the part being written, if any
+	compressor io.WriteCloser // compresses the data written to file
+	tarWriter  *tar.Writer    // writes entries to compressor; nil if no part is open
+	written    int64          // bytes of tar data written to the current part, excluding the trailer
+}
+
+// Creates the next part and prepares it for writing.
+func (w *partWriter) openPart() error {
+	name := fmt.Sprintf("%s.%03d%s", w.prefix, len(w.parts)+1, typeInfoMap[w.typ].extensions[0])
+	file, err := os.Create(filepath.Clean(name))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveCreate, err)
+	}
+	w.parts = append(w.parts, file.Name())
+
+	compressor, err := newCompressWriter(file, w.typ)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file, w.compressor, w.tarWriter, w.written = file, compressor, tar.NewWriter(compressor), 0
+	return nil
+}
+
+// Completes and closes the current part, if any.
+func (w *partWriter) closePart() error {
+	if w.tarWriter == nil {
+		return nil
+	}
+	defer func() { w.file, w.compressor, w.tarWriter = nil, nil, nil }()
+
+	err := w.tarWriter.Close()
+	if cerr := w.compressor.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
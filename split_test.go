@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var splitEntries = []testEntry{
+	{name: "a/"},
+	{name: "a/one.txt", body: "1"},
+	{name: "a/two.txt", body: strings.Repeat("2", 512)},
+	{name: "b.txt", body: strings.Repeat("3", 513)},
+	{name: "c.txt", body: "4"},
+}
+
+func TestSplit(t *testing.T) {
+	for _, filename := range []string{"split.tar", "split.tar.gz", "split.tar.xz"} {
+		path := writeTestArchive(t, filename, splitEntries)
+		typ, _ := DetermineType(filename)
+		ext := typeInfoMap[typ].extensions[0]
+		prefix := filepath.Join(t.TempDir(), "part")
+
+		// Each header occupies 512 bytes and contents are padded to 512-byte blocks, so
+		// with the 1024-byte trailer, the directory and the next two files fill the first
+		// part exactly, and the remaining files fill the second.
+		parts, err := Split(path, prefix, 3584)
+		if err != nil {
+			t.Errorf("Unexpected error splitting %s: %v\n", filename, err)
+			continue
+		}
+
+		expected := []string{prefix + ".001" + ext, prefix + ".002" + ext}
+		if !reflect.DeepEqual(parts, expected) {
+			t.Errorf("Expecting '%v', got '%v'\n", expected, parts)
+			continue
+		}
+
+		var names []string
+		for _, part := range parts {
+			names = append(names, tarEntryNames(t, part)...)
+			if info, err := os.Stat(part); typ == Tar && (err != nil || info.Size() > 3584) {
+				t.Errorf("Expecting %s to be at most 3584 bytes, got %v (error: %v)\n", part, info.Size(), err)
+			}
+		}
+		if original := tarEntryNames(t, path); !reflect.DeepEqual(names, original) {
+			t.Errorf("Expecting '%v', got '%v'\n", original, names)
+		}
+
+		reader, err := ContentReader(parts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if string(content) != splitEntries[3].body+splitEntries[4].body || err != nil {
+			t.Errorf("%s: expecting contents of %s and %s, got '%s' (error: %v)\n", filename, splitEntries[3].name, splitEntries[4].name, content, err)
+		}
+	}
+}
+
+func TestSplit_errors(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestArchive(t, "split.tar", splitEntries)
+
+	if _, err := Split(path, filepath.Join(dir, "part"), 2047); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrEntryTooLarge, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Failed to remove parts after a failed split, found %v\n", entries)
+	}
+
+	if _, err := Split(path, filepath.Join(dir, "part"), 1024); err != errInvalidPartSize {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidPartSize, err)
+	}
+	if _, err := Split("testdata/sample.tar.bz2", filepath.Join(dir, "part"), 1<<20); err != errUnsupportedWriteType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedWriteType, err)
+	}
+	if _, err := Split("testdata/sample.zip", filepath.Join(dir, "part"), 1<<20); err != errUnsupportedWriteType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedWriteType, err)
+	}
+
+	parts, err := Split(writeTestArchive(t, "empty.tar", nil), filepath.Join(dir, "part"), 1<<20)
+	if parts != nil || err != nil {
+		t.Errorf("Expecting no parts, got '%v' (error: %v)\n", parts, err)
+	}
+}
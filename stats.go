@@ -0,0 +1,349 @@
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// LargestEntry returns the name and uncompressed size of the largest entry in the archive
+// at archivePath, whose type is determined by DetermineType. Sizes are taken from
+// File.UncompressedSize64 for zip archives and Header.Size for tar-family archives, so
+// entry contents are never read. If several entries share the largest size, the first in
+// archive order is returned. An archive with no entries yields an empty name and a size
+// of zero.
+func LargestEntry(archivePath string) (name string, size int64, err error) {
+	size = -1
+	err = forEachEntry(archivePath, func(e *entry) error {
+		if e.size > size {
+			name, size = e.name, e.size
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if size < 0 {
+		size = 0
+	}
+	return name, size, nil
+}
+
+// MaxEntrySize returns the uncompressed size of the largest entry in the archive at
+// archivePath, whose type is determined by DetermineType, as recorded in its metadata.
+// Unlike LargestEntry, it reports only the size, which suits sizing buffers or rejecting
+// archives with oversized members before any contents are read. An archive with no
+// entries yields zero.
+func MaxEntrySize(archivePath string) (int64, error) {
+	var size int64
+	err := forEachEntry(archivePath, func(e *entry) error {
+		size = max(size, e.size)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// defaultBlockSize is the file system block size assumed by EstimateDiskUsage.
+const defaultBlockSize = 4096
+
+// EstimateDiskUsage estimates the disk space that extracting the archive at archivePath,
+// whose type is determined by DetermineType, would consume. The size of each regular file
+// entry is rounded up to a multiple of blockSize, reflecting the whole blocks that file
+// systems allocate, so the estimate exceeds the raw total of entry sizes for archives
+// holding many small files. If blockSize is not positive, a block size of 4096 bytes is
+// assumed. Empty files, directories, and links are counted as consuming no space, and
+// sparse files are counted at their full logical size.
+func EstimateDiskUsage(archivePath string, blockSize int64) (int64, error) {
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+
+	var total int64
+	err := forEachEntry(archivePath, func(e *entry) error {
+		if e.mode.IsRegular() {
+			total += (e.size + blockSize - 1) / blockSize * blockSize
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// DirectorySizes returns the total uncompressed size of the regular files beneath each
+// directory of the archive at archivePath, whose type is determined by DetermineType.
+// Totals roll up, so that each directory's total includes the files of all of its
+// descendants, and the total for the whole archive is recorded under ".". Directories are
+// identified by the cleaned form of their names, without a trailing slash, as in
+// "sample/text". Directories implied by the names of file entries are included whether or
+// not the archive contains entries for them, and directory entries holding no files are
+// included with a total of zero.
+func DirectorySizes(archivePath string) (map[string]int64, error) {
+	sizes := map[string]int64{".": 0}
+	err := forEachEntry(archivePath, func(e *entry) error {
+		name := path.Clean(e.name)
+		if e.mode.IsDir() {
+			if _, ok := sizes[name]; !ok {
+				sizes[name] = 0
+			}
+		}
+		if !e.mode.IsRegular() {
+			return nil
+		}
+
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			sizes[dir] += e.size
+		}
+		sizes["."] += e.size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// CommonPrefix returns the longest directory prefix shared by the names of every entry in
+// the archive at archivePath, whose type is determined by DetermineType, with a trailing
+// slash, as in "sample/" for the sample archives. Names are compared by their cleaned
+// path components, so a prefix never splits a directory name: entries named "foo/bar"
+// and "foo/baz" share the prefix "foo/", not "foo/ba". A directory entry contributes all
+// of its components and any other entry all but its last. An empty string is returned if
+// the entries share no directory, including for archives holding a file at their top
+// level or no entries at all. Tooling can use the prefix to decide whether to strip a
+// single enclosing directory when presenting or extracting the archive's contents.
+func CommonPrefix(archivePath string) (string, error) {
+	var prefix []string
+	first := true
+	err := forEachEntry(archivePath, func(e *entry) error {
+		name := strings.TrimPrefix(path.Clean("/"+e.name), "/")
+		components := strings.Split(name, "/")
+		if name == "" {
+			components = nil
+		} else if !e.mode.IsDir() {
+			components = components[:len(components)-1]
+		}
+
+		if first {
+			prefix, first = components, false
+			return nil
+		}
+
+		n := 0
+		for n < len(prefix) && n < len(components) && prefix[n] == components[n] {
+			n++
+		}
+		prefix = prefix[:n]
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(prefix) == 0 {
+		return "", nil
+	}
+	return strings.Join(prefix, "/") + "/", nil
+}
+
+// CompressionTotals holds the total compressed and uncompressed sizes of a set of entries.
+type CompressionTotals struct {
+	Compressed   int64 // total size of the entries' contents as stored in the archive
+	Uncompressed int64 // total size of the entries' contents once decompressed
+}
+
+// DirectoryCompression returns the total compressed and uncompressed sizes of the regular
+// files beneath each directory of the zip archive at archivePath, as recorded in its
+// central directory, which helps identify the parts of an archive that compress poorly,
+// such as directories of already-compressed media. Directories are identified and totals
+// roll up as for DirectorySizes, with the totals for the whole archive recorded under ".".
+// Only zip archives compress each entry separately; tar-family archives are compressed
+// as a single stream, so for them a non-nil error is returned.
+func DirectoryCompression(archivePath string) (map[string]CompressionTotals, error) {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if typ != Zip {
+		return nil, errNotZip
+	}
+
+	totals := map[string]CompressionTotals{".": {}}
+	err = forEachEntry(archivePath, func(e *entry) error {
+		name := path.Clean(e.name)
+		if e.mode.IsDir() {
+			if _, ok := totals[name]; !ok {
+				totals[name] = CompressionTotals{}
+			}
+		}
+		if !e.mode.IsRegular() {
+			return nil
+		}
+
+		compressed, uncompressed := int64(e.file.CompressedSize64), int64(e.file.UncompressedSize64)
+		for dir := path.Dir(name); ; dir = path.Dir(dir) {
+			if dir == "/" {
+				dir = "."
+			}
+			t := totals[dir]
+			t.Compressed += compressed
+			t.Uncompressed += uncompressed
+			totals[dir] = t
+			if dir == "." {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// EntryTypeBreakdown returns the number of entries of each kind in the archive at
+// archivePath, whose type is determined by DetermineType, giving a quick structural
+// summary of an archive for classification or for spotting anomalies, such as device
+// files, across batches of archives. Kinds of which the archive holds no entries are
+// absent from the result. A hard link entry of a tar-family archive is counted as
+// HardLink rather than as the kind of its target. The kinds of zip entries are decoded
+// from the Unix or MS-DOS file attributes recorded in their external attributes, so a
+// symbolic link in a zip archive written on Unix is counted as Symlink.
+func EntryTypeBreakdown(archivePath string) (map[Kind]int, error) {
+	counts := make(map[Kind]int)
+	err := forEachEntry(archivePath, func(e *entry) error {
+		counts[entryKind(e)]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// HasRegularFiles reports whether the archive at archivePath, whose type is determined
+// by DetermineType, holds at least one regular file, as classified by EntryTypeBreakdown.
+// An archive whose entries are all directories, symbolic links, or special files, which
+// some pipelines treat as an error or as nothing to do, yields false, as does an empty
+// archive. Hard links are not counted, since their targets are. The archive is read only
+// until its first regular file is found, and entry contents are never read.
+func HasRegularFiles(archivePath string) (bool, error) {
+	c, err := openCursor(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		if entryKind(e) == Regular {
+			return true, nil
+		}
+	}
+}
+
+// ExtensionHistogram returns the number of entries in the archive at archivePath, whose
+// type is determined by DetermineType, with each file extension, for summaries such as
+// "412 .jpg and 3 .json". Extensions are taken from the final element of each entry's
+// name, as by path.Ext, including the leading dot, and are lowercased, so "photo.JPG"
+// and "photo.jpg" are both counted under ".jpg". Entries without an extension, including
+// names such as ".profile" that consist of a leading dot alone, are counted under "".
+// Directory entries are excluded. Entry contents are never read.
+func ExtensionHistogram(archivePath string) (map[string]int, error) {
+	histogram := make(map[string]int)
+	err := forEachEntry(archivePath, func(e *entry) error {
+		if e.mode.IsDir() {
+			return nil
+		}
+
+		base := path.Base(e.name)
+		ext := path.Ext(base)
+		if ext == base {
+			ext = ""
+		}
+		histogram[strings.ToLower(ext)]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram, nil
+}
+
+// ModTimeRange returns the earliest and latest modification times of the entries in the
+// archive at archivePath, whose type is determined by DetermineType, so that backup
+// verification tools can confirm that an archive's contents fall within an expected
+// window. Times are taken from Header.ModTime for tar-family archives and File.Modified
+// for zip archives. Entries without a modification time, such as zip entries whose
+// timestamp could not be decoded, are skipped; if no entry has one, including for an
+// archive with no entries, both times are zero.
+func ModTimeRange(archivePath string) (oldest, newest time.Time, err error) {
+	err = forEachEntry(archivePath, func(e *entry) error {
+		if e.modTime.IsZero() {
+			return nil
+		}
+
+		if oldest.IsZero() || e.modTime.Before(oldest) {
+			oldest = e.modTime
+		}
+		if newest.IsZero() || e.modTime.After(newest) {
+			newest = e.modTime
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return oldest, newest, nil
+}
+
+// LinkCount returns, for each regular file in the archive at archivePath, whose type is
+// determined by DetermineType, the number of hard link entries whose targets name it, so
+// that backup tooling can understand an archive's link structure before extraction.
+// Every regular file appears in the result, keyed by its name as recorded in the archive,
+// with a count of zero if nothing links to it. Names are compared in their cleaned forms,
+// so a link to "./dir/a.txt" counts towards "dir/a.txt", and links are counted whether
+// they precede or follow their targets. Links whose targets are not regular files in the
+// archive are not counted. Only tar-family archives can hold hard links; the files of a
+// zip archive all have a count of zero.
+func LinkCount(archivePath string) (map[string]int, error) {
+	files := make(map[string]string)
+	links := make(map[string]int)
+	err := forEachEntry(archivePath, func(e *entry) error {
+		if e.header != nil && e.header.Typeflag == tar.TypeLink {
+			links[path.Clean(e.header.Linkname)]++
+		} else if e.mode.IsRegular() {
+			files[path.Clean(e.name)] = e.name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(files))
+	for cleaned, name := range files {
+		counts[name] = links[cleaned]
+	}
+	return counts, nil
+}
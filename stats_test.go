@@ -0,0 +1,406 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLargestEntry(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		name, size, err := LargestEntry(archivePath)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+
+		if name != sampleFileName || size != sampleFileSize {
+			t.Errorf("Expecting '%s' (%d), got '%s' (%d)\n", sampleFileName, sampleFileSize, name, size)
+		}
+	}
+
+	path := writeTestArchive(t, "ties.zip", []testEntry{
+		{name: "a.txt", body: "lorem"},
+		{name: "b.txt", body: "ipsum dolor"},
+		{name: "c.txt", body: "sit amet, c"},
+	})
+	if name, size, err := LargestEntry(path); name != "b.txt" || size != 11 || err != nil {
+		t.Errorf("Expecting '%s' (%d), got '%s' (%d) (error: %v)\n", "b.txt", 11, name, size, err)
+	}
+
+	path = writeTestArchive(t, "empty.tar", nil)
+	if name, size, err := LargestEntry(path); name != "" || size != 0 || err != nil {
+		t.Errorf("Expecting empty result, got '%s' (%d) (error: %v)\n", name, size, err)
+	}
+
+	if _, _, err := LargestEntry("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+func TestMaxEntrySize(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		size, err := MaxEntrySize(archivePath)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if size != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, size)
+		}
+	}
+
+	for _, filename := range []string{"max.tar.gz", "max.zip"} {
+		path := writeTestArchive(t, filename, diskUsageEntries)
+		if size, err := MaxEntrySize(path); size != 5000 || err != nil {
+			t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 5000, size, err)
+		}
+	}
+
+	path := writeTestArchive(t, "empty.tar", nil)
+	if size, err := MaxEntrySize(path); size != 0 || err != nil {
+		t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 0, size, err)
+	}
+
+	if _, err := MaxEntrySize("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+var diskUsageEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/one.txt", body: "1"},
+	{name: "dir/empty.txt"},
+	{name: "dir/block.txt", body: strings.Repeat("b", 512)},
+	{name: "dir/big.txt", body: strings.Repeat("b", 5000)},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "one.txt"},
+}
+
+type diskUsageTest struct {
+	blockSize int64
+	expected  int64
+}
+
+var diskUsages = []diskUsageTest{
+	{0, 4096 + 4096 + 8192},
+	{4096, 4096 + 4096 + 8192},
+	{512, 512 + 512 + 5120},
+	{1, 1 + 512 + 5000},
+}
+
+func TestEstimateDiskUsage(t *testing.T) {
+	for _, filename := range []string{"usage.tar.gz", "usage.zip"} {
+		path := writeTestArchive(t, filename, diskUsageEntries)
+
+		for _, c := range diskUsages {
+			usage, err := EstimateDiskUsage(path, c.blockSize)
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", filename, err)
+			}
+			if usage != c.expected {
+				t.Errorf("%s (%d): expecting '%d', got '%d'\n", filename, c.blockSize, c.expected, usage)
+			}
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if usage, err := EstimateDiskUsage(archivePath, 0); usage != 4096 || err != nil {
+			t.Errorf("Expecting '%d', got '%d' (error: %v)\n", 4096, usage, err)
+		}
+	}
+
+	if _, err := EstimateDiskUsage("foo.123", 0); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+var directorySizeEntries = []testEntry{
+	{name: "a/"},
+	{name: "a/one.txt", body: "1"},
+	{name: "a/b/two.txt", body: "22"},
+	{name: "a/b/c/three.txt", body: "333"},
+	{name: "a/empty/"},
+	{name: "./d/four.txt", body: "4444"},
+	{name: "top.txt", body: "55555"},
+	{name: "a/link", typeflag: tar.TypeSymlink, linkname: "one.txt"},
+}
+
+var directorySizesExpected = map[string]int64{
+	".":       15,
+	"a":       6,
+	"a/b":     5,
+	"a/b/c":   3,
+	"a/empty": 0,
+	"d":       4,
+}
+
+func TestDirectorySizes(t *testing.T) {
+	for _, filename := range []string{"sizes.tar", "sizes.zip"} {
+		path := writeTestArchive(t, filename, directorySizeEntries)
+
+		sizes, err := DirectorySizes(path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if !reflect.DeepEqual(sizes, directorySizesExpected) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, directorySizesExpected, sizes)
+		}
+	}
+
+	expected := map[string]int64{".": sampleFileSize, "sample": sampleFileSize, "sample/text": sampleFileSize}
+	for _, archivePath := range sampleArchives {
+		if sizes, err := DirectorySizes(archivePath); !reflect.DeepEqual(sizes, expected) || err != nil {
+			t.Errorf("Expecting '%v', got '%v' (error: %v)\n", expected, sizes, err)
+		}
+	}
+
+	if _, err := DirectorySizes("foo.123"); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestDirectoryCompression(t *testing.T) {
+	path := writeTestArchive(t, "sizes.zip", directorySizeEntries)
+	totals, err := DirectoryCompression(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if len(totals) != len(directorySizesExpected) {
+		t.Errorf("Expecting %d directories, got '%v'\n", len(directorySizesExpected), totals)
+	}
+	for dir, size := range directorySizesExpected {
+		if totals[dir].Uncompressed != size {
+			t.Errorf("%s: expecting uncompressed size %d, got %d\n", dir, size, totals[dir].Uncompressed)
+		}
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v\n", path, err)
+	}
+	defer r.Close()
+	var compressed int64
+	for _, f := range r.File {
+		if f.Mode().IsRegular() {
+			compressed += int64(f.CompressedSize64)
+		}
+	}
+	if totals["."].Compressed != compressed {
+		t.Errorf("Expecting compressed size %d, got %d\n", compressed, totals["."].Compressed)
+	}
+
+	path = writeTestArchive(t, "ratio.zip", []testEntry{
+		{name: "text/a.txt", body: strings.Repeat("a", 10000)},
+	})
+	if totals, err := DirectoryCompression(path); err != nil || totals["text"].Compressed >= totals["text"].Uncompressed {
+		t.Errorf("Expecting compressed size below uncompressed size, got '%v' (error: %v)\n", totals["text"], err)
+	}
+
+	for _, archivePath := range sampleArchives {
+		_, err := DirectoryCompression(archivePath)
+		if strings.HasSuffix(archivePath, ".zip") {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+			}
+		} else if err != errNotZip {
+			t.Errorf("Expecting '%s' for %s, got '%v'\n", errNotZip, archivePath, err)
+		}
+	}
+}
+
+var breakdownEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "a"},
+	{name: "dir/b.txt", body: "b"},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+	{name: "empty/"},
+}
+
+func TestEntryTypeBreakdown(t *testing.T) {
+	expected := map[Kind]int{Dir: 2, Regular: 2, Symlink: 1}
+	for _, filename := range []string{"breakdown.tar.gz", "breakdown.zip"} {
+		counts, err := EntryTypeBreakdown(writeTestArchive(t, filename, breakdownEntries))
+		if !reflect.DeepEqual(counts, expected) || err != nil {
+			t.Errorf("%s: expecting '%v', got '%v' (error: %v)\n", filename, expected, counts, err)
+		}
+	}
+
+	special := append(slices.Clone(breakdownEntries),
+		testEntry{name: "dir/c.txt", typeflag: tar.TypeLink, linkname: "dir/a.txt"},
+		testEntry{name: "dev/null", typeflag: tar.TypeChar},
+		testEntry{name: "dev/sda", typeflag: tar.TypeBlock},
+		testEntry{name: "pipe", typeflag: tar.TypeFifo},
+	)
+	expected = map[Kind]int{Dir: 2, Regular: 2, Symlink: 1, HardLink: 1, CharDevice: 1, BlockDevice: 1, Fifo: 1}
+	if counts, err := EntryTypeBreakdown(writeTestArchive(t, "special.tar", special)); !reflect.DeepEqual(counts, expected) || err != nil {
+		t.Errorf("Expecting '%v', got '%v' (error: %v)\n", expected, counts, err)
+	}
+
+	expected = map[Kind]int{Dir: 2, Regular: 1}
+	for _, archivePath := range sampleArchives {
+		if counts, err := EntryTypeBreakdown(archivePath); !reflect.DeepEqual(counts, expected) || err != nil {
+			t.Errorf("%s: expecting '%v', got '%v' (error: %v)\n", archivePath, expected, counts, err)
+		}
+	}
+}
+
+func TestHasRegularFiles(t *testing.T) {
+	fileless := []testEntry{
+		{name: "dir/"},
+		{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "../target"},
+		{name: "empty/"},
+	}
+	cases := []struct {
+		filename string
+		entries  []testEntry
+		expected bool
+	}{
+		{"files.tar.gz", breakdownEntries, true},
+		{"files.zip", breakdownEntries, true},
+		{"fileless.tar.gz", fileless, false},
+		{"fileless.zip", fileless, false},
+		{"devices.tar", []testEntry{{name: "dev/null", typeflag: tar.TypeChar}, {name: "pipe", typeflag: tar.TypeFifo}}, false},
+		{"empty.tar", nil, false},
+	}
+
+	for _, c := range cases {
+		actual, err := HasRegularFiles(writeTestArchive(t, c.filename, c.entries))
+		if actual != c.expected || err != nil {
+			t.Errorf("%s: expecting '%t', got '%t' (error: %v)\n", c.filename, c.expected, actual, err)
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if actual, err := HasRegularFiles(archivePath); !actual || err != nil {
+			t.Errorf("%s: expecting '%t', got '%t' (error: %v)\n", archivePath, true, actual, err)
+		}
+	}
+
+	if _, err := HasRegularFiles("testdata/nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+var commonPrefixTests = []struct {
+	entries  []testEntry
+	expected string
+}{
+	{[]testEntry{{name: "foo/bar/a.txt"}, {name: "foo/baz/b.txt"}}, "foo/"},
+	{[]testEntry{{name: "foo/bar"}, {name: "foo/baz"}}, "foo/"},
+	{[]testEntry{{name: "foo/bar/"}, {name: "foo/bar/a.txt"}, {name: "./foo/bar/c/d.txt"}}, "foo/bar/"},
+	{[]testEntry{{name: "foobar/a.txt"}, {name: "foobaz/b.txt"}}, ""},
+	{[]testEntry{{name: "foo/a.txt"}, {name: "b.txt"}}, ""},
+	{[]testEntry{{name: "foo"}}, ""},
+	{nil, ""},
+}
+
+func TestCommonPrefix(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		if prefix, err := CommonPrefix(archivePath); prefix != "sample/" || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "sample/", prefix, err)
+		}
+	}
+
+	for _, test := range commonPrefixTests {
+		path := writeTestArchive(t, "prefix.tar", test.entries)
+		if prefix, err := CommonPrefix(path); prefix != test.expected || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", test.expected, prefix, err)
+		}
+	}
+
+	if _, err := CommonPrefix("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+var extensionEntries = []testEntry{
+	{name: "photos/"},
+	{name: "photos/a.jpg"},
+	{name: "photos/b.JPG"},
+	{name: "photos/c.jpeg"},
+	{name: "data/config.json"},
+	{name: "data/archive.tar.gz"},
+	{name: "README"},
+	{name: ".profile"},
+	{name: "link.jpg", typeflag: tar.TypeSymlink, linkname: "photos/a.jpg"},
+}
+
+func TestExtensionHistogram(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		histogram, err := ExtensionHistogram(archivePath)
+		if expected := map[string]int{".txt": 1}; !reflect.DeepEqual(histogram, expected) || err != nil {
+			t.Errorf("Expecting '%v', got '%v' (error: %v)\n", expected, histogram, err)
+		}
+	}
+
+	expected := map[string]int{".jpg": 3, ".jpeg": 1, ".json": 1, ".gz": 1, "": 2}
+	for _, filename := range []string{"extensions.tar", "extensions.zip"} {
+		path := writeTestArchive(t, filename, extensionEntries)
+		if histogram, err := ExtensionHistogram(path); !reflect.DeepEqual(histogram, expected) || err != nil {
+			t.Errorf("%s: expecting '%v', got '%v' (error: %v)\n", filename, expected, histogram, err)
+		}
+	}
+
+	if _, err := ExtensionHistogram("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+var modTimeRangeEntries = []testEntry{
+	{name: "dir/", modTime: time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)},
+	{name: "dir/a.txt", modTime: time.Date(2019, time.July, 4, 8, 30, 0, 0, time.UTC)},
+	{name: "dir/b.txt", modTime: time.Date(2023, time.December, 31, 23, 59, 58, 0, time.UTC)},
+	{name: "dir/c.txt", modTime: time.Date(2021, time.January, 15, 6, 0, 0, 0, time.UTC)},
+}
+
+func TestModTimeRange(t *testing.T) {
+	oldestExpected := time.Date(2019, time.July, 4, 8, 30, 0, 0, time.UTC)
+	newestExpected := time.Date(2023, time.December, 31, 23, 59, 58, 0, time.UTC)
+
+	for _, filename := range []string{"times.tar.gz", "times.zip"} {
+		path := writeTestArchive(t, filename, modTimeRangeEntries)
+		oldest, newest, err := ModTimeRange(path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", filename, err)
+		}
+		if !oldest.Equal(oldestExpected) || !newest.Equal(newestExpected) {
+			t.Errorf("%s: expecting '%s' to '%s', got '%s' to '%s'\n", filename, oldestExpected, newestExpected, oldest, newest)
+		}
+	}
+
+	path := writeTestArchive(t, "empty.tar", nil)
+	if oldest, newest, err := ModTimeRange(path); !oldest.IsZero() || !newest.IsZero() || err != nil {
+		t.Errorf("Expecting zero times, got '%s' to '%s' (error: %v)\n", oldest, newest, err)
+	}
+
+	if _, _, err := ModTimeRange("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+func TestLinkCount(t *testing.T) {
+	entries := append(slices.Clone(hardLinkEntries),
+		testEntry{name: "dir/d.txt", typeflag: tar.TypeLink, linkname: "./dir/a.txt"},
+		testEntry{name: "dir/e.txt", body: "dolor"},
+		testEntry{name: "dangling.txt", typeflag: tar.TypeLink, linkname: "missing.txt"},
+	)
+	path := writeTestArchive(t, "links.tar.gz", entries)
+
+	expected := map[string]int{"dir/a.txt": 3, "dir/e.txt": 0}
+	if counts, err := LinkCount(path); !reflect.DeepEqual(counts, expected) || err != nil {
+		t.Errorf("Expecting '%v', got '%v' (error: %v)\n", expected, counts, err)
+	}
+
+	expected = map[string]int{sampleFileName: 0}
+	for _, archivePath := range sampleArchives {
+		if counts, err := LinkCount(archivePath); !reflect.DeepEqual(counts, expected) || err != nil {
+			t.Errorf("Expecting '%v', got '%v' (error: %v)\n", expected, counts, err)
+		}
+	}
+
+	if _, err := LinkCount("nonexistent.tar.gz"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
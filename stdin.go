@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxZipInMemory is the size of the largest zip archive read from a stream that is
+// held in memory to be walked; larger archives are spooled to a temporary file.
+const maxZipInMemory = 64 << 20
+
+// WalkStdin walks the contents of an archive read from standard input, invoking
+// tarCallback for each entry of a tar-family archive or zipCallback for each entry of a
+// zip archive. Since standard input has no name, the archive type is identified from its
+// leading bytes, as by DetermineTypeFromMagic, which allows the package to be used as
+// part of a pipeline such as "cat foo.tar.gz | mytool".
+//
+// Tar-family archives are decompressed and walked as they are read. A zip archive's
+// central directory is at its end, however, and standard input cannot be seeked, so a
+// zip archive must be read in its entirety before being walked. Archives of up to
+// 64 MiB are held in memory; larger ones are copied to a temporary file and walked from
+// there, as by WalkZipStreamBuffered, so that memory use stays bounded.
+func WalkStdin(tarCallback TarCallback, zipCallback ZipCallback) error {
+	return walkReader(os.Stdin, tarCallback, zipCallback)
+}
+
+// Walks the contents of an archive read from r, whose type is identified from
+// its leading bytes.
+func walkReader(r io.Reader, tarCallback TarCallback, zipCallback ZipCallback) error {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(tarBlockSize)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf(fmtErrReadMagic, err)
+	}
+
+	typ, err := typeFromMagic(magic)
+	if err != nil {
+		return err
+	}
+
+	if typ == Zip {
+		return readZipStream(buffered, maxZipInMemory, zipCallback)
+	}
+
+	stream, err := newTarStream(buffered, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return readTarWrapping(tar.NewReader(stream), tarCallback)
+}
+
+// Walks the contents of a zip archive read from r, which is held in memory if it is
+// no larger than maxInMemory bytes, and otherwise spooled to a temporary file.
+func readZipStream(r io.Reader, maxInMemory int64, callback ZipCallback) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxInMemory+1))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	if int64(len(data)) > maxInMemory {
+		return WalkZipStreamBuffered(io.MultiReader(bytes.NewReader(data), r), callback)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	return readZipWrapping(zr.File, callback)
+}
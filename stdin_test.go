@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Walks the archive at path, supplied as standard input, and returns the names
+// of the entries visited.
+func walkAsStdin(t *testing.T, path string) ([]string, error) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stdin := os.Stdin
+	os.Stdin = file
+	defer func() { os.Stdin = stdin }()
+
+	var names []string
+	err = WalkStdin(
+		func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			return nil
+		},
+		func(f *zip.File) error {
+			names = append(names, f.Name)
+			return nil
+		})
+	return names, err
+}
+
+func TestWalkStdin(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		names, err := walkAsStdin(t, archivePath)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if len(names) != len(sampleEntries) {
+			t.Errorf("%s: expecting '%d', got '%d'\n", archivePath, len(sampleEntries), len(names))
+		}
+	}
+
+	if _, err := walkAsStdin(t, "testdata/invalid.tar"); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+
+	if err := walkReader(strings.NewReader(""), nil, nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	if err := walkReader(strings.NewReader("PK\x03\x04 truncated"), nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a truncated zip archive.")
+	}
+
+	if err := walkReader(strings.NewReader("\x1f\x8b truncated"), nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a truncated gzip stream.")
+	}
+}
+
+func TestReadZipStream(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := describeZipWalk(t, func(cb ZipCallback) error { return WalkZip("testdata/sample.zip", cb) })
+
+	// Archives larger than the limit are spooled to a temporary file.
+	size := int64(len(data))
+	for _, limit := range []int64{0, size - 1, size, maxZipInMemory} {
+		actual := describeZipWalk(t, func(cb ZipCallback) error {
+			return readZipStream(bytes.NewReader(data), limit, cb)
+		})
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Limit %d: expecting '%v', got '%v'\n", limit, expected, actual)
+		}
+	}
+
+	for _, limit := range []int64{0, maxZipInMemory} {
+		if err := readZipStream(bytes.NewReader(data[:size/2]), limit, nil); err == nil {
+			t.Errorf("Limit %d: failed to receive non-nil error for a truncated zip archive.", limit)
+		}
+	}
+}
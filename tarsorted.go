@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// errNotUncompressedTar is returned by WalkTarSorted for an archive that is not an
+// uncompressed tar archive.
+var errNotUncompressedTar = errors.New("archive: not an uncompressed tar archive")
+
+// Struct tarEntryOffset records the header of a tar entry along with the offset
+// at which the entry begins.
+type tarEntryOffset struct {
+	header *tar.Header
+	offset int64
+}
+
+// WalkTarSorted walks the contents of the uncompressed tar archive at path, invoking the
+// callback for each entry in lexical order of entry name, as compared byte by byte,
+// rather than in the order in which the entries are stored. Entries sharing a name are
+// visited in storage order. This helps tools that must produce reproducible output
+// regardless of how an archive was assembled.
+//
+// The archive is read twice: once to record the header and offset of every entry, and
+// again, seeking directly to each entry in turn, to invoke the callback. Every header is
+// therefore held in memory for the duration of the walk, and the archive must be
+// seekable, which rules out compressed archives: an archive whose type, as determined
+// by DetermineType, is not Tar causes a non-nil error to be returned.
+func WalkTarSorted(path string, callback TarCallback) error {
+	typ, err := DetermineType(path)
+	if err != nil {
+		return err
+	}
+	if typ != Tar {
+		return errNotUncompressedTar
+	}
+
+	var entries []tarEntryOffset
+	err = WalkTarOffsets(path, func(reader *tar.Reader, header *tar.Header, offset int64) error {
+		entries = append(entries, tarEntryOffset{header: header, offset: offset})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortStableFunc(entries, func(a, b tarEntryOffset) int {
+		return strings.Compare(a.header.Name, b.header.Name)
+	})
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	for _, e := range entries {
+		if _, err := file.Seek(e.offset, io.SeekStart); err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		reader := tar.NewReader(file)
+		header, err := reader.Next()
+		if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if callback != nil {
+			if err := callback(reader, header); err != nil {
+				return fmt.Errorf(fmtErrTarCallbackFailed, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var unsortedEntries = []testEntry{
+	{name: "zeta.txt", body: "zeta"},
+	{name: "dir/"},
+	{name: "dir/b.txt", body: "b"},
+	{name: "alpha.txt", body: "alpha"},
+	{name: "dir/a.txt", body: "a first"},
+	{name: "dir/a.txt", body: "a second"},
+	{name: "Zulu.txt", body: "Zulu"},
+}
+
+func TestWalkTarSorted(t *testing.T) {
+	path := writeTestArchive(t, "unsorted.tar", unsortedEntries)
+
+	var visited []string
+	err := WalkTarSorted(path, func(reader *tar.Reader, header *tar.Header) error {
+		body, err := io.ReadAll(reader)
+		visited = append(visited, fmt.Sprintf("%s=%s", header.Name, body))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	expected := []string{
+		"Zulu.txt=Zulu", "alpha.txt=alpha", "dir/=", "dir/a.txt=a first",
+		"dir/a.txt=a second", "dir/b.txt=b", "zeta.txt=zeta",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, visited)
+	}
+
+	names := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+		return WalkTarSorted("testdata/sample.tar", tarCb)
+	})
+	if expected := []string{"sample/", "sample/text/", "sample/text/lorem.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+	}
+
+	errStop := errors.New("stop")
+	err = WalkTarSorted(path, func(reader *tar.Reader, header *tar.Header) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	for _, archivePath := range []string{"testdata/sample.tar.gz", "testdata/sample.zip"} {
+		if err := WalkTarSorted(archivePath, nil); err != errNotUncompressedTar {
+			t.Errorf("Expecting '%s', got '%v'\n", errNotUncompressedTar, err)
+		}
+	}
+
+	if err := WalkTarSorted("testdata/invalid.tar", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WalkTarGzThrottled walks the contents of a gzip-compressed tar file and invokes the
+// callback function for each entry, as WalkTarGz does, but limits the rate at which
+// decompressed data is read to bytesPerSec bytes per second, so that a background job
+// walking or extracting a large archive does not saturate the disk, network, or CPU of a
+// busy host. The limit applies to the decompressed tar stream, including the data that
+// callbacks read from each entry, and is enforced by a token bucket holding up to one
+// second's worth of bytes: reads proceed at full speed while tokens remain and are
+// delayed once they run out, so throughput averages bytesPerSec over any period longer
+// than a second. A rate of zero or less imposes no limit.
+func WalkTarGzThrottled(path string, bytesPerSec int64, callback TarCallback) error {
+	if bytesPerSec <= 0 {
+		return walkTarWrapping(path, TarGz, callback)
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf(fmtErrNewGzReader, err)
+	}
+	defer reader.Close()
+
+	return readTarWrapping(tar.NewReader(newThrottledReader(reader, bytesPerSec)), callback)
+}
+
+// Struct throttledReader limits the rate at which an underlying reader is read using
+// a token bucket, each token permitting one byte to be read.
+type throttledReader struct {
+	reader   io.Reader
+	rate     float64   // tokens added per second
+	capacity float64   // maximum number of tokens held
+	tokens   float64   // tokens available; negative while reads are owed a delay
+	last     time.Time // when tokens were last added
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// Returns a reader that reads from reader at no more than bytesPerSec bytes per
+// second on average, starting with a full bucket.
+func newThrottledReader(reader io.Reader, bytesPerSec int64) *throttledReader {
+	return &throttledReader{
+		reader:   reader,
+		rate:     float64(bytesPerSec),
+		capacity: float64(bytesPerSec),
+		tokens:   float64(bytesPerSec),
+		last:     time.Now(),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// Read reads at most a bucketful of bytes from the underlying reader, then waits
+// until enough tokens have accumulated to pay for them.
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.capacity) {
+		p = p[:int(r.capacity)]
+	}
+
+	n, err := r.reader.Read(p)
+
+	now := r.now()
+	r.tokens = min(r.capacity, r.tokens+now.Sub(r.last).Seconds()*r.rate) - float64(n)
+	r.last = now
+	if r.tokens < 0 {
+		r.sleep(time.Duration(-r.tokens / r.rate * float64(time.Second)))
+	}
+	return n, err
+}
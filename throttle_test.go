@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWalkTarGzThrottled(t *testing.T) {
+	expected := walkNames(t, func(tarCallback TarCallback, zipCallback ZipCallback) error {
+		return WalkTarGz("testdata/sample.tar.gz", tarCallback)
+	})
+
+	for _, rate := range []int64{0, 1 << 20} {
+		var names []string
+		err := WalkTarGzThrottled("testdata/sample.tar.gz", rate, func(reader *tar.Reader, header *tar.Header) error {
+			names = append(names, header.Name)
+			_, err := io.Copy(io.Discard, reader)
+			return err
+		})
+		if !reflect.DeepEqual(names, expected) || err != nil {
+			t.Errorf("Rate %d: expecting '%v', got '%v' (error: %v)\n", rate, expected, names, err)
+		}
+	}
+
+	errStop := errors.New("stop")
+	for _, rate := range []int64{0, 1 << 20} {
+		err := WalkTarGzThrottled("testdata/sample.tar.gz", rate, func(reader *tar.Reader, header *tar.Header) error {
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("Rate %d: expecting '%s', got '%v'\n", rate, errStop, err)
+		}
+	}
+
+	if err := WalkTarGzThrottled("testdata/sample.tar", 1<<20, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a file that is not gzip-compressed.")
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 10000)
+	reader := newThrottledReader(bytes.NewReader(data), 1000)
+
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	reader.last = clock
+	reader.now = func() time.Time { return clock }
+	reader.sleep = func(d time.Duration) {
+		slept += d
+		clock = clock.Add(d)
+	}
+
+	buf := make([]byte, 4096)
+	var total int
+	for {
+		n, err := reader.Read(buf)
+		if n > 1000 {
+			t.Errorf("Expecting reads of at most 1000 bytes, got %d\n", n)
+		}
+		total += n
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first second's worth of bytes is read from the full bucket without delay.
+	if expected := 9 * time.Second; total != len(data) || slept != expected {
+		t.Errorf("Expecting %d bytes after %v, got %d bytes after %v\n", len(data), expected, total, slept)
+	}
+}
@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrCallbackTimeout is returned by WalkWithEntryTimeout when a callback fails to
+// return within the allotted time.
+var ErrCallbackTimeout = errors.New("archive: callback timed out")
+
+// WalkWithEntryTimeout walks the contents of the tar-family archive at archivePath,
+// whose type is determined by DetermineType, and invokes the callback function for each
+// entry. Each invocation runs in its own goroutine and is allowed at most timeout to
+// return; if it does not, the walk stops and an error wrapping ErrCallbackTimeout and
+// naming the entry is returned. This bounds the time spent on an archive when callbacks
+// perform work, such as network I/O, that may hang on a pathological entry.
+//
+// A callback that has timed out cannot be interrupted, so it continues to run in the
+// background; the archive remains open until it returns, at which point the archive is
+// closed. Callbacks should therefore eventually return, and must not retain the reader
+// or header beyond their own return. Zip archives are not supported.
+func WalkWithEntryTimeout(archivePath string, timeout time.Duration, callback TarCallback) error {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return err
+	}
+
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return err
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return stream.Close()
+		} else if err != nil {
+			stream.Close()
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if callback == nil {
+			continue
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- callback(reader, header)
+		}()
+
+		timer := time.NewTimer(timeout)
+		select {
+		case err := <-done:
+			timer.Stop()
+			if err != nil {
+				stream.Close()
+				return fmt.Errorf(fmtErrTarCallbackFailed, err)
+			}
+		case <-timer.C:
+			// The archive is closed once the callback returns, so that it is never
+			// closed while the callback may still be reading from it.
+			go func() {
+				<-done
+				stream.Close()
+			}()
+			return fmt.Errorf("%w: %q did not complete within %v", ErrCallbackTimeout, header.Name, timeout)
+		}
+	}
+}
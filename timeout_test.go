@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var timeoutEntries = []testEntry{
+	{name: "fast.txt", body: "lorem"},
+	{name: "hang.txt", body: "ipsum"},
+	{name: "never.txt", body: "dolor"},
+}
+
+func TestWalkWithEntryTimeout(t *testing.T) {
+	for _, archivePath := range sampleArchives[:4] {
+		var visited int
+		err := WalkWithEntryTimeout(archivePath, time.Second, func(reader *tar.Reader, header *tar.Header) error {
+			visited++
+			_, err := io.Copy(io.Discard, reader)
+			return err
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if visited != len(sampleEntries) {
+			t.Errorf("Expecting '%d', got '%d'\n", len(sampleEntries), visited)
+		}
+	}
+
+	path := writeTestArchive(t, "timeout.tar.gz", timeoutEntries)
+	release := make(chan struct{})
+	visited := make(chan string, len(timeoutEntries))
+	err := WalkWithEntryTimeout(path, 50*time.Millisecond, func(reader *tar.Reader, header *tar.Header) error {
+		visited <- header.Name
+		if header.Name == "hang.txt" {
+			<-release
+		}
+		return nil
+	})
+	close(release)
+
+	if !errors.Is(err, ErrCallbackTimeout) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrCallbackTimeout, err)
+	}
+
+	expected := `archive: callback timed out: "hang.txt" did not complete within 50ms`
+	if err != nil && err.Error() != expected {
+		t.Errorf("Expecting '%s', got '%s'\n", expected, err)
+	}
+
+	if len(visited) != 2 {
+		t.Errorf("Expecting '%d', got '%d'\n", 2, len(visited))
+	}
+}
+
+func TestWalkWithEntryTimeout_errors(t *testing.T) {
+	errStop := errors.New("stop")
+	err := WalkWithEntryTimeout("testdata/sample.tar", time.Second, func(reader *tar.Reader, header *tar.Header) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expecting '%s', got '%v'\n", errStop, err)
+	}
+
+	if err := WalkWithEntryTimeout("testdata/sample.zip", time.Second, nil); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+
+	if err := WalkWithEntryTimeout("testdata/invalid.tar", time.Second, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TreeHandler is the type of function called for each archive file found by WalkTree.
+type TreeHandler func(archivePath string, typ Type) error
+
+// WalkTree walks the file tree rooted at root and invokes handler for each regular file
+// whose archive type can be determined by DetermineType. Other files are skipped. Up to
+// concurrency handlers run at once; a concurrency of less than one is treated as one.
+//
+// An error returned by a handler, or encountered while reading the file tree, does not
+// stop the walk. Instead, every such error is collected, prefixed with the offending
+// path, and returned as a single error built by errors.Join once all handlers have
+// finished. Because handlers run concurrently, the order of the joined errors is not
+// deterministic.
+func WalkTree(root string, concurrency int, handler TreeHandler) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	addErr := func(path string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+
+	// The walk function never returns an error, so WalkDir always completes.
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			addErr(path, err)
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		typ, err := DetermineType(path)
+		if err != nil || handler == nil {
+			return nil
+		}
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := handler(path, typ); err != nil {
+				addErr(path, err)
+			}
+		}()
+
+		return nil
+	})
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Prefixes drawn before the entries of a tree, as by tree --charset=ascii.
+const (
+	treeBranch     = "|-- "
+	treeLastBranch = "`-- "
+	treeIndent     = "|   "
+	treeLastIndent = "    "
+)
+
+// Tree returns a representation of the directory structure of the archive at
+// archivePath, whose type is determined by DetermineType, drawn in ASCII as by the tree
+// command, for pretty listings by command-line tools. The first line is ".", the root of
+// the archive, and each entry follows on a line of its own beneath its parent directory,
+// with symbolic links followed by " -> " and their targets:
+//
+//	.
+//	|-- docs
+//	|   `-- readme.txt
+//	`-- run.sh -> bin/run
+//
+// Names are cleaned, as for WalkOptions.CleanNames, and leading slashes are dropped.
+// Directories that are not recorded as entries of their own, as tar archives often
+// omit them, are inferred from the names of the entries beneath them, and an entry
+// recorded more than once is drawn once. Within each directory, subdirectories are drawn
+// first, followed by the remaining entries, each group ordered by name, so that the
+// result is fully determined by the archive's contents rather than its order.
+func Tree(archivePath string) (string, error) {
+	root := &treeNode{isDir: true}
+	err := forEachEntry(archivePath, func(e *entry) error {
+		name := strings.TrimLeft(cleanName(e.name), "/")
+		kind := entryKind(e)
+
+		node := root
+		for _, elem := range strings.Split(strings.TrimSuffix(name, "/"), "/") {
+			if elem == "" || elem == "." {
+				continue
+			}
+			node.isDir = true
+			node = node.child(elem)
+		}
+		if node == root {
+			return nil
+		}
+
+		if kind == Dir {
+			node.isDir = true
+		} else if kind == Symlink {
+			target, err := symlinkTarget(e)
+			if err != nil {
+				return err
+			}
+			node.target = target
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(".\n")
+	root.draw(&b, "")
+	return b.String(), nil
+}
+
+// Struct treeNode is an entry of the tree drawn by Tree.
+type treeNode struct {
+	children map[string]*treeNode
+	isDir    bool
+	target   string // the target of a symbolic link
+}
+
+// Returns the child of n with the given name, adding it if need be.
+func (n *treeNode) child(name string) *treeNode {
+	if n.children == nil {
+		n.children = make(map[string]*treeNode)
+	}
+
+	c, ok := n.children[name]
+	if !ok {
+		c = &treeNode{}
+		n.children[name] = c
+	}
+	return c
+}
+
+// Writes a line for each descendant of n to b, each preceded by prefix.
+func (n *treeNode) draw(b *strings.Builder, prefix string) {
+	names := slices.SortedFunc(maps.Keys(n.children), func(x, y string) int {
+		if n.children[x].isDir != n.children[y].isDir {
+			if n.children[x].isDir {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(x, y)
+	})
+
+	for i, name := range names {
+		c := n.children[name]
+		branch, indent := treeBranch, treeIndent
+		if i == len(names)-1 {
+			branch, indent = treeLastBranch, treeLastIndent
+		}
+
+		b.WriteString(prefix + branch + name)
+		if c.target != "" {
+			b.WriteString(" -> " + c.target)
+		}
+		b.WriteString("\n")
+
+		c.draw(b, prefix+indent)
+	}
+}
@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Creates a file tree containing copies of the sample archives alongside
+// files that are not archives, returning the tree's root.
+func makeArchiveTree(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for i, archivePath := range sampleArchives {
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := root
+		if i%2 == 1 {
+			dir = filepath.Join(root, "nested", "deeper")
+		}
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(archivePath)), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "readme.txt"), []byte("lorem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir.zip"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func TestWalkTree(t *testing.T) {
+	root := makeArchiveTree(t)
+
+	var (
+		mu      sync.Mutex
+		visited []string
+		running int32
+		maxSeen int32
+	)
+
+	err := WalkTree(root, 2, func(archivePath string, typ Type) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		visited = append(visited, filepath.Base(archivePath))
+		if n > maxSeen {
+			maxSeen = n
+		}
+		mu.Unlock()
+
+		if expected, _ := DetermineType(archivePath); typ != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, typ)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+
+	sort.Strings(visited)
+	expected := []string{"sample.tar", "sample.tar.bz2", "sample.tar.gz", "sample.tar.xz", "sample.zip"}
+	if len(visited) != len(expected) {
+		t.Fatalf("Expecting '%v', got '%v'\n", expected, visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("Expecting '%s', got '%s'\n", expected[i], visited[i])
+		}
+	}
+
+	if maxSeen > 2 {
+		t.Errorf("Concurrency limit exceeded: %d handlers ran at once.\n", maxSeen)
+	}
+}
+
+func TestWalkTree_errors(t *testing.T) {
+	root := makeArchiveTree(t)
+	errHandler := errors.New("an error in handler processing")
+
+	calls := int32(0)
+	err := WalkTree(root, 0, func(archivePath string, typ Type) error {
+		atomic.AddInt32(&calls, 1)
+		if typ == TarGz || typ == Zip {
+			return errHandler
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errHandler) {
+		t.Errorf("Expecting '%s', got '%v'\n", errHandler, err)
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Expecting two joined errors, got '%v'\n", err)
+	}
+
+	if calls != int32(len(sampleArchives)) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(sampleArchives), calls)
+	}
+
+	if err := WalkTree(filepath.Join(root, "nonexistent"), 1, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent root.")
+	}
+}
+
+var treeEntries = []testEntry{
+	{name: "./src/main.go", body: "package main"},
+	{name: "b.txt", body: "b"},
+	{name: "docs/"},
+	{name: "src/lib/util.go", body: "package lib"},
+	{name: "a.txt", body: "a"},
+	{name: "run", typeflag: tar.TypeSymlink, linkname: "src/main.go"},
+	{name: "src/README", body: "readme"},
+	{name: "b.txt", body: "b"},
+}
+
+const treeExpected = `.
+|-- docs
+|-- src
+|   |-- lib
+|   |   ` + "`" + `-- util.go
+|   |-- README
+|   ` + "`" + `-- main.go
+|-- a.txt
+|-- b.txt
+` + "`" + `-- run -> src/main.go
+`
+
+func TestTree(t *testing.T) {
+	for _, filename := range []string{"tree.tar.gz", "tree.zip"} {
+		actual, err := Tree(writeTestArchive(t, filename, treeEntries))
+		if actual != treeExpected || err != nil {
+			t.Errorf("%s: expecting '%s', got '%s' (error: %v)\n", filename, treeExpected, actual, err)
+		}
+	}
+
+	expected := ".\n`-- sample\n    `-- text\n        `-- lorem.txt\n"
+	for _, archivePath := range sampleArchives {
+		if actual, err := Tree(archivePath); actual != expected || err != nil {
+			t.Errorf("%s: expecting '%s', got '%s' (error: %v)\n", archivePath, expected, actual, err)
+		}
+	}
+
+	if actual, err := Tree(writeTestArchive(t, "empty.tar", nil)); actual != ".\n" || err != nil {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", ".\n", actual, err)
+	}
+
+	if _, err := Tree("testdata/nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
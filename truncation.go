@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrTruncated is returned by CheckTruncation when an archive ends prematurely. The
+// returned error wraps ErrTruncated and describes where the truncation was detected.
+var ErrTruncated = errors.New("archive: archive is truncated")
+
+// Format strings for truncation errors, each of which wraps ErrTruncated.
+const (
+	fmtErrTruncatedHeader  = "%w: tar data ends at offset %d, within a header"
+	fmtErrTruncatedEntry   = "%w: tar data ends at offset %d, within entry %q, which extends to offset %d"
+	fmtErrTruncatedSparse  = "%w: tar data ends at offset %d, within sparse entry %q"
+	fmtErrTruncatedMarker  = "%w: tar data ends at offset %d, without an end-of-archive marker"
+	fmtErrTruncatedStream  = "%w: compressed stream ends before its trailer, after %d bytes of tar data"
+	fmtErrTruncatedNoEOCD  = "%w: no end of central directory record found in the %d-byte file"
+	fmtErrTruncatedRecord  = "%w: %s at offset %d extends to offset %d, beyond the end of the %d-byte file"
+	fmtErrTruncatedCentral = "%w: central directory at offset %d extends to offset %d, beyond the end of central directory record at offset %d"
+)
+
+// Layout of the end of central directory record and the zip64 records that locate
+// the central directory of a large archive (APPNOTE.TXT, sections 4.3.14 to 4.3.16).
+const (
+	zipEndOfCentralLen         = 22
+	zipMaxCommentLen           = 0xffff
+	zip64EndOfCentralLocSig    = 0x07064b50
+	zip64EndOfCentralLocLen    = 20
+	zip64EndOfCentralMinLen    = 56
+	zipUint16Max               = 0xffff
+	zipEndOfCentralCountPos    = 10
+	zipEndOfCentralSizePos     = 12
+	zipEndOfCentralOffsetPos   = 16
+	zipEndOfCentralCommentPos  = 20
+	zip64EndOfCentralSizePos   = 40
+	zip64EndOfCentralOffsetPos = 48
+)
+
+// CheckTruncation reports whether the archive at path, whose type is determined by
+// DetermineType, is truncated, as partially-downloaded archives frequently are. It returns
+// nil if the archive is complete, and otherwise an error wrapping ErrTruncated that
+// describes where the truncation was detected, which is far more helpful than the
+// generic read error a walk would fail with partway through.
+//
+// For a zip archive, the end of central directory record is located and the central
+// directory it describes, along with any zip64 records, is checked to lie within the
+// file; entry contents are not read. For a tar-family archive, the archive is read in
+// its entirety: the truncation is reported at an offset within the tar data, which for
+// a compressed archive is the decompressed stream, along with the entry being read and
+// the offset at which it should have ended. A tar archive lacking its end-of-archive
+// marker is reported as truncated, as is a compressed archive whose compressed stream
+// ends prematurely after the tar data is complete. Errors other than truncation, such
+// as corrupt data, are returned as they are encountered.
+func CheckTruncation(path string) error {
+	typ, err := DetermineType(path)
+	if err != nil {
+		return err
+	}
+
+	if typ == Zip {
+		return checkZipTruncation(path)
+	}
+	return checkTarTruncation(path, typ)
+}
+
+// Checks that the central directory of the zip archive at path lies within the file.
+func checkZipTruncation(path string) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	size := info.Size()
+
+	tailStart := max(0, size-zipEndOfCentralLen-zipMaxCommentLen)
+	tail := make([]byte, size-tailStart)
+	if _, err := file.ReadAt(tail, tailStart); err != nil {
+		return fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	i := len(tail) - zipEndOfCentralLen
+	for ; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) == zipEndOfCentralSig {
+			break
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf(fmtErrTruncatedNoEOCD, ErrTruncated, size)
+	}
+
+	eocd := tail[i:]
+	eocdOffset := tailStart + int64(i)
+	if end := eocdOffset + zipEndOfCentralLen + int64(binary.LittleEndian.Uint16(eocd[zipEndOfCentralCommentPos:])); end > size {
+		return fmt.Errorf(fmtErrTruncatedRecord, ErrTruncated, "end of central directory record", eocdOffset, end, size)
+	}
+
+	count := binary.LittleEndian.Uint16(eocd[zipEndOfCentralCountPos:])
+	cdSize := int64(binary.LittleEndian.Uint32(eocd[zipEndOfCentralSizePos:]))
+	cdOffset := int64(binary.LittleEndian.Uint32(eocd[zipEndOfCentralOffsetPos:]))
+
+	// A zip64 archive records the true size and offset of its central directory in
+	// a zip64 end of central directory record, found by way of a locator.
+	if count == zipUint16Max || cdSize == int64(zipUint32Max) || cdOffset == int64(zipUint32Max) {
+		if locator := i - zip64EndOfCentralLocLen; locator >= 0 &&
+			binary.LittleEndian.Uint32(tail[locator:]) == zip64EndOfCentralLocSig {
+			offset := int64(binary.LittleEndian.Uint64(tail[locator+8:]))
+			if end := offset + zip64EndOfCentralMinLen; offset < 0 || end > size {
+				return fmt.Errorf(fmtErrTruncatedRecord, ErrTruncated, "zip64 end of central directory record", offset, end, size)
+			}
+
+			record := make([]byte, zip64EndOfCentralMinLen)
+			if _, err := file.ReadAt(record, offset); err != nil {
+				return fmt.Errorf(fmtErrZipReadFailed, err)
+			}
+			cdSize = int64(binary.LittleEndian.Uint64(record[zip64EndOfCentralSizePos:]))
+			cdOffset = int64(binary.LittleEndian.Uint64(record[zip64EndOfCentralOffsetPos:]))
+		}
+	}
+
+	if end := cdOffset + cdSize; cdOffset < 0 || cdSize < 0 || end > eocdOffset {
+		return fmt.Errorf(fmtErrTruncatedCentral, ErrTruncated, cdOffset, end, eocdOffset)
+	}
+	return nil
+}
+
+// Reads the tar-family archive of the given type at path in its entirety, reporting
+// where its data ends prematurely.
+func checkTarTruncation(path string, typ Type) error {
+	stream, err := openTarStream(path, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// The tar reader does no buffering of its own, so the count of bytes read
+	// through it is its position within the tar data.
+	counter := &countingReader{reader: stream}
+	reader := tar.NewReader(counter)
+
+	var end int64
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf(fmtErrTruncatedHeader, ErrTruncated, counter.n)
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		dataStart := counter.n
+		if _, err := io.Copy(io.Discard, reader); errors.Is(err, io.ErrUnexpectedEOF) {
+			if isSparse(header) {
+				return fmt.Errorf(fmtErrTruncatedSparse, ErrTruncated, counter.n, header.Name)
+			}
+			return fmt.Errorf(fmtErrTruncatedEntry, ErrTruncated, counter.n, header.Name, dataStart+storedSize(header))
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+		end = (counter.n + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+	}
+
+	// An end-of-archive marker consists of zero-filled blocks, at least one of
+	// which the tar reader consumes before reporting the end of the archive.
+	if counter.n <= end {
+		return fmt.Errorf(fmtErrTruncatedMarker, ErrTruncated, counter.n)
+	}
+
+	if typ != Tar {
+		if _, err := io.Copy(io.Discard, stream); errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf(fmtErrTruncatedStream, ErrTruncated, counter.n)
+		} else if err != nil {
+			return fmt.Errorf(fmtErrDecompress, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes the first n bytes of the file at path to a new file with the same base
+// name and returns its path.
+func truncateCopy(t *testing.T, path string, n int) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	truncated := filepath.Join(t.TempDir(), filepath.Base(path))
+	if err := os.WriteFile(truncated, data[:n], 0600); err != nil {
+		t.Fatal(err)
+	}
+	return truncated
+}
+
+func TestCheckTruncation(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		if err := CheckTruncation(archivePath); err != nil {
+			t.Errorf("%s: unexpected error: %v\n", archivePath, err)
+		}
+
+		info, err := os.Stat(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, n := range []int64{info.Size() / 2, info.Size() - 1} {
+			path := truncateCopy(t, archivePath, int(n))
+			if err := CheckTruncation(path); !errors.Is(err, ErrTruncated) {
+				t.Errorf("%s truncated to %d bytes: expecting '%s', got '%v'\n", archivePath, n, ErrTruncated, err)
+			}
+		}
+	}
+
+	if err := CheckTruncation("nonexistent.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
+
+type truncatedTarTest struct {
+	size     int
+	expected string
+}
+
+// The test archive holds a 1000-byte entry whose contents start at offset 512 and
+// end at offset 1512, padded to 1536, followed by a 1024-byte end-of-archive marker.
+var truncatedTars = []truncatedTarTest{
+	{100, "tar data ends at offset 100, within a header"},
+	{1000, `tar data ends at offset 1000, within entry "big.txt", which extends to offset 1512`},
+	{1536, "tar data ends at offset 1536, without an end-of-archive marker"},
+	{0, "tar data ends at offset 0, without an end-of-archive marker"},
+}
+
+func TestCheckTruncation_tar(t *testing.T) {
+	path := writeTestArchive(t, "entry.tar", []testEntry{{name: "big.txt", body: strings.Repeat("b", 1000)}})
+
+	for _, c := range truncatedTars {
+		err := CheckTruncation(truncateCopy(t, path, c.size))
+		if !errors.Is(err, ErrTruncated) {
+			t.Errorf("Expecting '%s', got '%v'\n", ErrTruncated, err)
+		} else if !strings.HasSuffix(err.Error(), c.expected) {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expected, err)
+		}
+	}
+}
+
+func TestCheckTruncation_zip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing bytes from the middle of the archive leaves the central directory
+	// offset pointing beyond its actual location.
+	path := filepath.Join(t.TempDir(), "gap.zip")
+	gapped := append(append([]byte(nil), data[:100]...), data[200:]...)
+	if err := os.WriteFile(path, gapped, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTruncation(path); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTruncated, err)
+	} else if !strings.Contains(err.Error(), "central directory") {
+		t.Errorf("Expecting a central directory error, got '%s'\n", err)
+	}
+
+	path = truncateCopy(t, "testdata/sample.zip", len(data)-zipEndOfCentralLen-1)
+	if err := CheckTruncation(path); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrTruncated, err)
+	}
+
+	// Prepended data does not disturb the check.
+	path = filepath.Join(t.TempDir(), "sfx.zip")
+	if err := os.WriteFile(path, append([]byte("#!/bin/sh\n"), data...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTruncation(path); err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VerifyManifest compares the entries of the archive at archivePath, whose type is
+// determined by DetermineType, against expected, a map of entry names to their expected
+// uncompressed sizes. It returns a description of each mismatch found: an entry that is
+// present in the archive but absent from expected ("extra"), an entry whose size differs
+// from its expected size ("size mismatch"), or an expected entry absent from the archive
+// ("missing"). Extra and size mismatches are reported in archive order, followed by
+// missing entries in lexical order. Directory entries are only checked when they appear
+// in expected. An empty result indicates that the archive matches the manifest.
+func VerifyManifest(archivePath string, expected map[string]int64) ([]string, error) {
+	var mismatches []string
+	found := make(map[string]bool, len(expected))
+
+	err := forEachEntry(archivePath, func(e *entry) error {
+		size, ok := expected[e.name]
+		switch {
+		case !ok && e.mode.IsDir():
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("extra: %s", e.name))
+		case size != e.size:
+			mismatches = append(mismatches, fmt.Sprintf("size mismatch: %s (expected %d, got %d)", e.name, size, e.size))
+		}
+
+		found[e.name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for name := range expected {
+		if !found[name] {
+			missing = append(missing, fmt.Sprintf("missing: %s", name))
+		}
+	}
+	sort.Strings(missing)
+
+	return append(mismatches, missing...), nil
+}
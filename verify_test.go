@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+)
+
+type manifestTest struct {
+	expected   map[string]int64
+	mismatches []string
+}
+
+var manifestTests = []manifestTest{
+	{
+		map[string]int64{sampleFileName: sampleFileSize},
+		nil,
+	},
+	{
+		map[string]int64{sampleFileName: sampleFileSize, "sample/": 0, "sample/text/": 0},
+		nil,
+	},
+	{
+		map[string]int64{sampleFileName: 42},
+		[]string{"size mismatch: sample/text/lorem.txt (expected 42, got 803)"},
+	},
+	{
+		map[string]int64{},
+		[]string{"extra: sample/text/lorem.txt"},
+	},
+	{
+		map[string]int64{sampleFileName: sampleFileSize, "sample/b.txt": 1, "sample/a.txt": 1},
+		[]string{"missing: sample/a.txt", "missing: sample/b.txt"},
+	},
+}
+
+func TestVerifyManifest(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		for _, c := range manifestTests {
+			mismatches, err := VerifyManifest(archivePath, c.expected)
+			if err != nil {
+				t.Errorf("Unexpected error verifying %s: %v\n", archivePath, err)
+			}
+
+			if !reflect.DeepEqual(mismatches, c.mismatches) {
+				t.Errorf("Expecting '%v', got '%v'\n", c.mismatches, mismatches)
+			}
+		}
+	}
+
+	if _, err := VerifyManifest("nonexistent.zip", nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
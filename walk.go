@@ -0,0 +1,441 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrUnsafeEntry is returned by a strict walk upon encountering an entry that is a
+// symbolic or hard link, has an absolute name, or has a name containing a ".."
+// component. The returned error wraps ErrUnsafeEntry and names the offending entry.
+var ErrUnsafeEntry = errors.New("archive: unsafe entry")
+
+// ArchiveInfo describes an archive that has been opened for walking.
+type ArchiveInfo struct {
+	// Type is the archive's type.
+	Type Type
+
+	// EntryCount is the number of entries recorded in a zip archive's central
+	// directory. It is -1 for tar-family archives, whose entries cannot be
+	// counted without reading the entire archive.
+	EntryCount int
+
+	// Comment is a zip archive's comment. It is empty for tar-family archives.
+	Comment string
+}
+
+// WalkOptions configures the behavior of WalkWithOptions.
+type WalkOptions struct {
+	// OnArchiveOpen, if non-nil, is invoked once after the archive has been
+	// opened and its type determined, but before the first entry is visited.
+	OnArchiveOpen func(info ArchiveInfo)
+
+	// Strict, if true, causes the walk to fail with an error wrapping
+	// ErrUnsafeEntry upon encountering any entry that is a symbolic or hard link,
+	// has an absolute name, or has a name containing a ".." component. Because a
+	// zip archive's central directory is read up front, every zip entry is checked
+	// before the first callback is invoked, so an unsafe zip archive is rejected
+	// in its entirety. Tar-family archives can only be checked as they are read,
+	// so callbacks may already have been invoked for entries preceding the
+	// offending one.
+	Strict bool
+
+	// CleanNames, if true, replaces the name of each entry with its canonical form,
+	// as produced by path.Clean, before the entry is checked or passed to a callback:
+	// duplicate separators and "." components are removed and ".." components are
+	// resolved where possible, as in "foo//bar/./baz" becoming "foo/bar/baz". The
+	// trailing slash of a directory name is preserved. Cleaning is opt-in because
+	// it can change the behavior of consumers of archives that intentionally rely
+	// on unusual names; note that two differently named entries may share the same
+	// cleaned name. ListOptions and ExtractOptions offer the same option.
+	CleanNames bool
+}
+
+// WalkWithOptions walks the contents of the archive at archivePath, whose type is
+// determined by DetermineType, invoking tarCallback for each entry of a tar-family
+// archive or zipCallback for each entry of a zip archive. The walk is configured by opts.
+func WalkWithOptions(archivePath string, opts WalkOptions, tarCallback TarCallback, zipCallback ZipCallback) error {
+	typ, err := DetermineType(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if typ == Zip {
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return fmt.Errorf(fmtErrArchiveOpen, err)
+		}
+		defer r.Close()
+
+		if opts.CleanNames {
+			for _, f := range r.File {
+				f.Name = cleanName(f.Name)
+			}
+		}
+
+		if opts.Strict {
+			for _, f := range r.File {
+				if err := checkStrict(f.Name, zipFileMode(f)&fs.ModeSymlink != 0); err != nil {
+					return err
+				}
+			}
+		}
+
+		if opts.OnArchiveOpen != nil {
+			opts.OnArchiveOpen(ArchiveInfo{Type: typ, EntryCount: len(r.File), Comment: r.Comment})
+		}
+
+		return readZipWrapping(r.File, zipCallback)
+	}
+
+	stream, err := openTarStream(archivePath, typ)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if opts.OnArchiveOpen != nil {
+		opts.OnArchiveOpen(ArchiveInfo{Type: typ, EntryCount: -1})
+	}
+
+	if opts.Strict || opts.CleanNames {
+		callback := tarCallback
+		tarCallback = func(reader *tar.Reader, header *tar.Header) error {
+			if opts.CleanNames {
+				header.Name = cleanName(header.Name)
+			}
+			if opts.Strict {
+				isLink := header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink
+				if err := checkStrict(header.Name, isLink); err != nil {
+					return err
+				}
+			}
+			if callback == nil {
+				return nil
+			}
+			return callback(reader, header)
+		}
+	}
+
+	return readTarWrapping(tar.NewReader(stream), tarCallback)
+}
+
+// Returns the canonical form of an entry name, preserving the trailing slash
+// of a directory name.
+func cleanName(name string) string {
+	cleaned := path.Clean(name)
+	if strings.HasSuffix(name, "/") && cleaned != "/" && cleaned != "." {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// Returns an error wrapping ErrUnsafeEntry if the named entry is a link or
+// its name is absolute or contains a ".." component.
+func checkStrict(name string, isLink bool) error {
+	switch {
+	case isLink:
+		return fmt.Errorf("%w: %q is a link", ErrUnsafeEntry, name)
+	case isAbsName(name):
+		return fmt.Errorf("%w: %q is an absolute path", ErrUnsafeEntry, name)
+	case hasDotDot(name):
+		return fmt.Errorf("%w: %q contains a \"..\" component", ErrUnsafeEntry, name)
+	}
+	return nil
+}
+
+// Device names reserved by Windows, which cannot be used as file names even when
+// followed by an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// IsSafePath reports whether an entry name is a relative path that can be safely
+// extracted beneath a destination directory on any common platform. Both forward and
+// backward slashes are treated as separators. It returns false for empty names, absolute
+// names such as "/etc/passwd" or `\\server\share`, names beginning with a Windows drive
+// letter such as "C:" or "c:foo", names with a ".." component, names containing a NUL
+// byte or a colon, which Windows uses to address alternate data streams, and names with
+// a component that Windows reserves for a device, such as "CON", "nul.txt", or "LPT1".
+// Extraction does not use it: SafeJoin rejects only names that would lead outside of
+// the destination on the host platform, so IsSafePath is stricter, and can be used to
+// validate names in custom callbacks, such as those that must accept only names
+// that can be extracted on Windows as well.
+func IsSafePath(name string) bool {
+	if name == "" || isAbsName(name) || hasDotDot(name) || strings.ContainsAny(name, "\x00:") {
+		return false
+	}
+
+	for _, component := range strings.FieldsFunc(name, isSeparator) {
+		base, _, _ := strings.Cut(component, ".")
+		if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether an entry name is absolute on any common platform: rooted
+// with a forward or backward slash, or beginning with a Windows drive letter.
+func isAbsName(name string) bool {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return true
+	}
+
+	return len(name) >= 2 && name[1] == ':' &&
+		(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z')
+}
+
+// Reports whether an entry name contains a ".." component, treating both
+// forward and backward slashes as separators.
+func hasDotDot(name string) bool {
+	for _, component := range strings.FieldsFunc(name, isSeparator) {
+		if component == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether r is a forward or backward slash.
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+// DedupCallback is the type of function called for each entry visited by WalkDedup.
+// For a regular file whose content is identical to that of a previously visited file,
+// dupOf holds the name of the first entry with that content; otherwise dupOf is empty.
+type DedupCallback func(name string, dupOf string, isDir bool) error
+
+// WalkDedup walks the contents of the archive at archivePath, whose type is determined
+// by DetermineType, and invokes the callback for each entry. The content of every regular
+// file is hashed so that files duplicating an earlier entry's content can be reported
+// via the callback's dupOf argument. A hard link in a tar archive holds its target's
+// content, and so is reported as a duplicate of the first entry holding that content;
+// a hard link to an entry that does not precede it in the archive results in an error.
+// Directories and other non-regular entries are never reported as duplicates.
+func WalkDedup(archivePath string, callback DedupCallback) error {
+	seen := make(map[[sha256.Size]byte]string)  // first entry holding each content
+	files := make(map[string][sha256.Size]byte) // content by cleaned name, for resolving hard links
+
+	report := func(name string, isDir bool, sum *[sha256.Size]byte) error {
+		dupOf := ""
+		if sum != nil {
+			files[path.Clean(name)] = *sum
+			if first, ok := seen[*sum]; ok {
+				dupOf = first
+			} else {
+				seen[*sum] = name
+			}
+		}
+
+		if callback == nil {
+			return nil
+		}
+		return callback(name, dupOf, isDir)
+	}
+
+	visit := func(name string, isDir, isRegular bool, reader io.Reader) error {
+		if !isRegular {
+			return report(name, isDir, nil)
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, reader); err != nil {
+			return err
+		}
+
+		var sum [sha256.Size]byte
+		copy(sum[:], hash.Sum(nil))
+		return report(name, false, &sum)
+	}
+
+	tarCallback := func(reader *tar.Reader, header *tar.Header) error {
+		if header.Typeflag == tar.TypeLink {
+			sum, ok := files[path.Clean(header.Linkname)]
+			if !ok {
+				return fmt.Errorf("%w: %q", errLinkTargetNotFound, header.Linkname)
+			}
+			return report(header.Name, false, &sum)
+		}
+
+		mode := header.FileInfo().Mode()
+		return visit(header.Name, mode.IsDir(), mode.IsRegular(), reader)
+	}
+
+	zipCallback := func(file *zip.File) error {
+		mode := zipFileMode(file)
+		if !mode.IsRegular() {
+			return visit(file.Name, mode.IsDir(), false, nil)
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf(fmtErrZipOpenFile, err)
+		}
+		defer reader.Close()
+
+		return visit(file.Name, false, true, reader)
+	}
+
+	return walkArchive(archivePath, tarCallback, zipCallback)
+}
+
+// WalkModifiedSince walks the contents of the archive at path, whose type is determined
+// by DetermineType, invoking the appropriate callback only for entries whose modification
+// time is after since. Tar entries are compared by Header.ModTime and zip entries by
+// File.Modified. Entries modified at or before since are skipped without being read.
+func WalkModifiedSince(path string, since time.Time, tarCallback TarCallback, zipCallback ZipCallback) error {
+	filteredTar := func(reader *tar.Reader, header *tar.Header) error {
+		if tarCallback == nil || !header.ModTime.After(since) {
+			return nil
+		}
+		return tarCallback(reader, header)
+	}
+
+	filteredZip := func(file *zip.File) error {
+		if zipCallback == nil || !file.Modified.After(since) {
+			return nil
+		}
+		return zipCallback(file)
+	}
+
+	return walkArchive(path, filteredTar, filteredZip)
+}
+
+// WalkNewerThan walks the contents of the archive at path, whose type is determined by
+// DetermineType, invoking the appropriate callback only for entries that are absent from
+// the archive at reference or that were modified after the entry of the same name there,
+// which is what an incremental backup built against a baseline archive must hold. The
+// names and modification times of the reference's entries are indexed before the walk
+// begins, so the reference is read in its entirety first; it need not be of the same
+// type as the archive at path. Names are compared in their canonical forms, as produced
+// by the CleanNames option of WalkOptions, and modification times as by
+// WalkModifiedSince. If the reference holds
+// several entries of the same name, the latest of their times is used.
+func WalkNewerThan(path, reference string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	baseline := make(map[string]time.Time)
+	err := forEachEntry(reference, func(e *entry) error {
+		name := cleanName(e.name)
+		if modTime, ok := baseline[name]; !ok || e.modTime.After(modTime) {
+			baseline[name] = e.modTime
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	isNewer := func(name string, modTime time.Time) bool {
+		baseTime, ok := baseline[cleanName(name)]
+		return !ok || modTime.After(baseTime)
+	}
+
+	filteredTar := func(reader *tar.Reader, header *tar.Header) error {
+		if tarCallback == nil || !isNewer(header.Name, header.ModTime) {
+			return nil
+		}
+		return tarCallback(reader, header)
+	}
+
+	filteredZip := func(file *zip.File) error {
+		if zipCallback == nil || !isNewer(file.Name, file.Modified) {
+			return nil
+		}
+		return zipCallback(file)
+	}
+
+	return walkArchive(path, filteredTar, filteredZip)
+}
+
+// WalkSubtree walks the contents of the archive at archivePath, whose type is determined
+// by DetermineType, invoking the appropriate callback only for the entry of the directory
+// dir, if present, and the entries beneath it, which suits inspecting or extracting a
+// single module of a larger archive, such as that of a monorepo. Both dir and entry names
+// are compared in their canonical forms, as produced by path.Clean, so "src/pkg",
+// "src/pkg/", and "./src/pkg" all select the entries "src/pkg/" and "src/pkg/a.go", but
+// not "src/pkgs/b.go". A dir of "" or "." selects every entry. Entries are passed to the
+// callbacks unchanged. Zip entries outside dir are skipped on the strength of the central
+// directory alone, without reading their data; tar-family entries outside dir must still
+// be read past, but are not passed to the callback.
+func WalkSubtree(archivePath, dir string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	dir = path.Clean(dir)
+
+	filteredTar := func(reader *tar.Reader, header *tar.Header) error {
+		if tarCallback == nil || !isWithinDir(header.Name, dir) {
+			return nil
+		}
+		return tarCallback(reader, header)
+	}
+
+	filteredZip := func(file *zip.File) error {
+		if zipCallback == nil || !isWithinDir(file.Name, dir) {
+			return nil
+		}
+		return zipCallback(file)
+	}
+
+	return walkArchive(archivePath, filteredTar, filteredZip)
+}
+
+// Reports whether the entry name, once cleaned, is the cleaned directory name dir or
+// lies beneath it.
+func isWithinDir(name, dir string) bool {
+	if dir == "." {
+		return true
+	}
+
+	name = path.Clean(name)
+	return name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// WalkCollectErrors walks the contents of the archive at path, whose type is determined
+// by DetermineType, invoking tarCallback for each entry of a tar-family archive or
+// zipCallback for each entry of a zip archive. Unlike the other walk functions, an error
+// returned by a callback does not stop the walk: each is recorded, prefixed with the name
+// of the offending entry as "name: error", and the walk continues with the next entry.
+// Once the walk is complete, the recorded errors are returned combined with errors.Join,
+// or nil if there were none. This suits validation passes that report every problem in
+// an archive rather than just the first. Errors reading the archive itself still stop
+// the walk and are joined after any recorded callback errors.
+func WalkCollectErrors(path string, tarCallback TarCallback, zipCallback ZipCallback) error {
+	var errs []error
+
+	collectingTar := func(reader *tar.Reader, header *tar.Header) error {
+		if tarCallback == nil {
+			return nil
+		}
+		if err := tarCallback(reader, header); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", header.Name, err))
+		}
+		return nil
+	}
+
+	collectingZip := func(file *zip.File) error {
+		if zipCallback == nil {
+			return nil
+		}
+		if err := zipCallback(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Name, err))
+		}
+		return nil
+	}
+
+	if err := walkArchive(path, collectingTar, collectingZip); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
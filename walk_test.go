@@ -0,0 +1,518 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var dedupEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "lorem"},
+	{name: "dir/b.txt", body: "ipsum"},
+	{name: "dir/c.txt", body: "lorem"},
+	{name: "d.txt", body: "ipsum"},
+	{name: "e.txt", body: "lorem"},
+}
+
+var dedupExpected = map[string]string{
+	"dir/":      "",
+	"dir/a.txt": "",
+	"dir/b.txt": "",
+	"dir/c.txt": "dir/a.txt",
+	"d.txt":     "dir/b.txt",
+	"e.txt":     "dir/a.txt",
+}
+
+func TestWalkDedup(t *testing.T) {
+	for _, filename := range []string{"dedup.tar", "dedup.tar.gz", "dedup.zip"} {
+		path := writeTestArchive(t, filename, dedupEntries)
+
+		visited := 0
+		err := WalkDedup(path, func(name string, dupOf string, isDir bool) error {
+			visited++
+			if expected := dedupExpected[name]; dupOf != expected {
+				t.Errorf("%s: expecting '%s', got '%s'\n", name, expected, dupOf)
+			}
+			if isDir != (name == "dir/") {
+				t.Errorf("%s: unexpected isDir value %t\n", name, isDir)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error walking %s: %v\n", filename, err)
+		}
+
+		if visited != len(dedupEntries) {
+			t.Errorf("Expecting '%d', got '%d'\n", len(dedupEntries), visited)
+		}
+	}
+
+	err := WalkDedup("testdata/sample.zip", func(name string, dupOf string, isDir bool) error {
+		return errors.New("an error in callback processing")
+	})
+	if err == nil {
+		t.Error("Failed to return error from callback.")
+	}
+
+	if err := WalkDedup("foo.123", nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestWalkDedup_hardLinks(t *testing.T) {
+	path := writeTestArchive(t, "links.tar", []testEntry{
+		{name: "empty.txt"},
+		{name: "dir/a.txt", body: "hello"},
+		{name: "link", typeflag: tar.TypeLink, linkname: "./dir/a.txt"},
+		{name: "dir/b.txt", body: "hello"},
+	})
+	expected := map[string]string{
+		"empty.txt": "",
+		"dir/a.txt": "",
+		"link":      "dir/a.txt",
+		"dir/b.txt": "dir/a.txt",
+	}
+
+	err := WalkDedup(path, func(name string, dupOf string, isDir bool) error {
+		if dupOf != expected[name] {
+			t.Errorf("%s: expecting '%s', got '%s'\n", name, expected[name], dupOf)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path = writeTestArchive(t, "dangling.tar", []testEntry{
+		{name: "link", typeflag: tar.TypeLink, linkname: "a.txt"},
+		{name: "a.txt", body: "hello"},
+	})
+	if err := WalkDedup(path, nil); !errors.Is(err, errLinkTargetNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", errLinkTargetNotFound, err)
+	}
+}
+
+func TestWalks_wrapCallbackErrors(t *testing.T) {
+	errStop := errors.New("stop")
+	tarCallback := func(reader *tar.Reader, header *tar.Header) error { return errStop }
+	zipCallback := func(file *zip.File) error { return errStop }
+	reference := writeTestArchive(t, "empty.tar", nil)
+
+	walks := map[string]func(path string) error{
+		"WalkDedup": func(path string) error {
+			return WalkDedup(path, func(name string, dupOf string, isDir bool) error { return errStop })
+		},
+		"WalkModifiedSince": func(path string) error {
+			return WalkModifiedSince(path, time.Time{}, tarCallback, zipCallback)
+		},
+		"WalkNewerThan": func(path string) error {
+			return WalkNewerThan(path, reference, tarCallback, zipCallback)
+		},
+		"WalkSubtree": func(path string) error {
+			return WalkSubtree(path, ".", tarCallback, zipCallback)
+		},
+	}
+
+	for name, walk := range walks {
+		for _, archivePath := range sampleArchives {
+			if err := walk(archivePath); !errors.Is(err, errStop) {
+				t.Errorf("%s %s: expecting '%s', got '%v'\n", name, archivePath, errStop, err)
+			}
+		}
+	}
+}
+
+var modifiedSinceEntries = []testEntry{
+	{name: "old.txt", modTime: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "new/", modTime: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	{name: "new/new.txt", body: "lorem", modTime: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+type modifiedSinceTest struct {
+	since    time.Time
+	expected int
+}
+
+var modifiedSinceTests = []modifiedSinceTest{
+	{time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), 3},
+	{time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), 2},
+	{time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), 2},
+	{time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), 0},
+}
+
+func TestWalkModifiedSince(t *testing.T) {
+	for _, filename := range []string{"modified.tar.gz", "modified.zip"} {
+		path := writeTestArchive(t, filename, modifiedSinceEntries)
+
+		for _, c := range modifiedSinceTests {
+			visited := 0
+			err := WalkModifiedSince(path, c.since,
+				func(reader *tar.Reader, header *tar.Header) error {
+					visited++
+					return nil
+				},
+				func(file *zip.File) error {
+					visited++
+					return nil
+				})
+			if err != nil {
+				t.Errorf("Unexpected error walking %s: %v\n", filename, err)
+			}
+
+			if visited != c.expected {
+				t.Errorf("%s: expecting '%d', got '%d'\n", filename, c.expected, visited)
+			}
+		}
+	}
+}
+
+func TestWalkNewerThan(t *testing.T) {
+	later := testModTime.Add(time.Hour)
+	baseline := []testEntry{
+		{name: "dir/"},
+		{name: "dir/same.txt", body: "a"},
+		{name: "dir/changed.txt", body: "b"},
+		{name: "old.txt", body: "c", modTime: later},
+		{name: "removed.txt", body: "d"},
+	}
+	current := []testEntry{
+		{name: "dir/"},
+		{name: "./dir/same.txt", body: "a"},
+		{name: "dir/changed.txt", body: "bb", modTime: later},
+		{name: "old.txt", body: "c"},
+		{name: "dir/added.txt", body: "e"},
+	}
+	expected := []string{"dir/changed.txt", "dir/added.txt"}
+
+	for _, referenceName := range []string{"baseline.tar.xz", "baseline.zip"} {
+		reference := writeTestArchive(t, referenceName, baseline)
+		for _, filename := range []string{"current.tar.gz", "current.zip"} {
+			path := writeTestArchive(t, filename, current)
+			names := walkNames(t, func(tarCallback TarCallback, zipCallback ZipCallback) error {
+				return WalkNewerThan(path, reference, tarCallback, zipCallback)
+			})
+			if !reflect.DeepEqual(names, expected) {
+				t.Errorf("%s against %s: expecting '%v', got '%v'\n", filename, referenceName, expected, names)
+			}
+		}
+	}
+
+	if err := WalkNewerThan(sampleArchives[0], "nonexistent.tar", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent reference archive.")
+	}
+}
+
+var subtreeEntries = []testEntry{
+	{name: "src/"},
+	{name: "src/pkg/"},
+	{name: "src/pkg/a.go", body: "a"},
+	{name: "./src/pkg/sub/b.go", body: "b"},
+	{name: "src/pkgs/c.go", body: "c"},
+	{name: "src/pkg.go", body: "d"},
+	{name: "README", body: "e"},
+}
+
+var subtreeTests = []struct {
+	dir      string
+	expected []string
+}{
+	{"src/pkg", []string{"src/pkg/", "src/pkg/a.go", "./src/pkg/sub/b.go"}},
+	{"./src/pkg/", []string{"src/pkg/", "src/pkg/a.go", "./src/pkg/sub/b.go"}},
+	{"src/pkg/sub", []string{"./src/pkg/sub/b.go"}},
+	{"src/pkg/a.go", []string{"src/pkg/a.go"}},
+	{"missing", nil},
+	{"", []string{"src/", "src/pkg/", "src/pkg/a.go", "./src/pkg/sub/b.go", "src/pkgs/c.go", "src/pkg.go", "README"}},
+}
+
+func TestWalkSubtree(t *testing.T) {
+	for _, filename := range []string{"subtree.tar.gz", "subtree.zip"} {
+		path := writeTestArchive(t, filename, subtreeEntries)
+
+		for _, test := range subtreeTests {
+			names := walkNames(t, func(tarCallback TarCallback, zipCallback ZipCallback) error {
+				return WalkSubtree(path, test.dir, tarCallback, zipCallback)
+			})
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("%s: %q: expecting '%v', got '%v'\n", filename, test.dir, test.expected, names)
+			}
+		}
+	}
+
+	if err := WalkSubtree("foo.123", "src", nil, nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
+
+func TestWalkWithOptions(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		var info *ArchiveInfo
+		visited := 0
+
+		opts := WalkOptions{
+			OnArchiveOpen: func(i ArchiveInfo) {
+				if info != nil {
+					t.Error("OnArchiveOpen invoked more than once.")
+				}
+				if visited != 0 {
+					t.Error("OnArchiveOpen invoked after the first entry.")
+				}
+				info = &i
+			},
+		}
+
+		err := WalkWithOptions(archivePath, opts,
+			func(reader *tar.Reader, header *tar.Header) error {
+				visited++
+				return nil
+			},
+			func(file *zip.File) error {
+				visited++
+				return nil
+			})
+		if err != nil {
+			t.Errorf("Unexpected error walking %s: %v\n", archivePath, err)
+		}
+
+		if visited != 3 {
+			t.Errorf("Expecting '%d', got '%d'\n", 3, visited)
+		}
+
+		if info == nil {
+			t.Errorf("OnArchiveOpen not invoked for %s.\n", archivePath)
+			continue
+		}
+
+		expectedType, _ := DetermineType(archivePath)
+		if info.Type != expectedType {
+			t.Errorf("Expecting '%s', got '%s'\n", expectedType, info.Type)
+		}
+
+		expectedCount := -1
+		if expectedType == Zip {
+			expectedCount = 3
+		}
+		if info.EntryCount != expectedCount {
+			t.Errorf("Expecting '%d', got '%d'\n", expectedCount, info.EntryCount)
+		}
+	}
+
+	if err := WalkWithOptions("testdata/sample.tar.gz", WalkOptions{}, nil, nil); err != nil {
+		t.Errorf("Unexpected error walking without options: %v\n", err)
+	}
+
+	if err := WalkWithOptions("nonexistent.zip", WalkOptions{}, nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent zip file.")
+	}
+}
+
+var strictTests = []struct {
+	entries []testEntry
+	message string
+}{
+	{[]testEntry{{name: "a.txt"}, {name: "link", typeflag: tar.TypeSymlink, linkname: "a.txt"}}, `"link" is a link`},
+	{[]testEntry{{name: "a.txt"}, {name: "/etc/passwd"}}, `"/etc/passwd" is an absolute path`},
+	{[]testEntry{{name: "a.txt"}, {name: `C:\evil.txt`}}, `"C:\\evil.txt" is an absolute path`},
+	{[]testEntry{{name: "a.txt"}, {name: "dir/../../evil.txt"}}, `"dir/../../evil.txt" contains a ".." component`},
+	{[]testEntry{{name: "a.txt"}, {name: `dir\..\evil.txt`}}, `"dir\\..\\evil.txt" contains a ".." component`},
+}
+
+func TestWalkWithOptions_strict(t *testing.T) {
+	for _, c := range strictTests {
+		for _, filename := range []string{"strict.tar", "strict.zip"} {
+			path := writeTestArchive(t, filename, c.entries)
+
+			visited := 0
+			err := WalkWithOptions(path, WalkOptions{Strict: true},
+				func(reader *tar.Reader, header *tar.Header) error {
+					visited++
+					return nil
+				},
+				func(file *zip.File) error {
+					visited++
+					return nil
+				})
+
+			if !errors.Is(err, ErrUnsafeEntry) {
+				t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafeEntry, err)
+			} else if !strings.HasSuffix(err.Error(), c.message) {
+				t.Errorf("Expecting message ending '%s', got '%s'\n", c.message, err)
+			}
+
+			// Zip archives are rejected before any callback; tar archives only upon reaching the entry.
+			expected := 1
+			if filename == "strict.zip" {
+				expected = 0
+			}
+			if visited != expected {
+				t.Errorf("%s: expecting '%d', got '%d'\n", filename, expected, visited)
+			}
+		}
+	}
+
+	for _, archivePath := range sampleArchives {
+		if err := WalkWithOptions(archivePath, WalkOptions{Strict: true}, nil, nil); err != nil {
+			t.Errorf("Unexpected error walking %s strictly: %v\n", archivePath, err)
+		}
+	}
+}
+
+var cleanNameEntries = []testEntry{
+	{name: "./foo/"},
+	{name: "foo//bar/./baz"},
+	{name: "foo/qux/../quux.txt"},
+	{name: "foo/plain.txt"},
+}
+
+var cleanNameExpected = []string{"foo/", "foo/bar/baz", "foo/quux.txt", "foo/plain.txt"}
+
+func TestWalkWithOptions_cleanNames(t *testing.T) {
+	for _, filename := range []string{"clean.tar", "clean.zip"} {
+		path := writeTestArchive(t, filename, cleanNameEntries)
+
+		for _, clean := range []bool{false, true} {
+			var names []string
+			err := WalkWithOptions(path, WalkOptions{CleanNames: clean},
+				func(reader *tar.Reader, header *tar.Header) error {
+					names = append(names, header.Name)
+					return nil
+				},
+				func(file *zip.File) error {
+					names = append(names, file.Name)
+					return nil
+				})
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v\n", filename, err)
+			}
+
+			expected := cleanNameExpected
+			if !clean {
+				expected = nil
+				for _, e := range cleanNameEntries {
+					expected = append(expected, e.name)
+				}
+			}
+			if !reflect.DeepEqual(names, expected) {
+				t.Errorf("%s (%t): expecting '%v', got '%v'\n", filename, clean, expected, names)
+			}
+		}
+	}
+
+	// Cleaning resolves ".." components where possible, so strict checks see the result.
+	path := writeTestArchive(t, "clean.tar", []testEntry{{name: "a/../b.txt"}, {name: "a/../../c.txt"}})
+	err := WalkWithOptions(path, WalkOptions{CleanNames: true, Strict: true}, nil, nil)
+	if !errors.Is(err, ErrUnsafeEntry) || !strings.HasSuffix(err.Error(), `"../c.txt" contains a ".." component`) {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrUnsafeEntry, err)
+	}
+}
+
+type cleanNameTest struct {
+	name     string
+	expected string
+}
+
+var cleanNames = []cleanNameTest{
+	{"foo//bar/./baz", "foo/bar/baz"},
+	{"./foo/", "foo/"},
+	{"foo/bar/../", "foo/"},
+	{"/abs//file", "/abs/file"},
+	{"./", "."},
+	{"/", "/"},
+	{"a/..", "."},
+}
+
+func TestCleanName(t *testing.T) {
+	for _, c := range cleanNames {
+		if result := cleanName(c.name); result != c.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expected, result)
+		}
+	}
+}
+
+type safePathTest struct {
+	name     string
+	expected bool
+}
+
+var safePaths = []safePathTest{
+	{"foo.txt", true},
+	{"a/b/c.txt", true},
+	{"./a/b", true},
+	{"dir/", true},
+	{`dir\file.txt`, true},
+	{"..foo/bar..", true},
+	{"console.txt", true},
+	{"com10", true},
+	{"", false},
+	{"/etc/passwd", false},
+	{`\windows\system32`, false},
+	{`\\server\share\file`, false},
+	{"C:/windows", false},
+	{`c:\windows`, false},
+	{"c:foo", false},
+	{"../foo", false},
+	{"a/../../foo", false},
+	{`a\..\..\foo`, false},
+	{"..", false},
+	{"file.txt:stream", false},
+	{"a\x00b", false},
+	{"CON", false},
+	{"dir/nul.txt", false},
+	{"Aux", false},
+	{"lpt1/file", false},
+	{"COM3.log", false},
+	{"prn .txt", false},
+}
+
+func TestIsSafePath(t *testing.T) {
+	for _, c := range safePaths {
+		if result := IsSafePath(c.name); result != c.expected {
+			t.Errorf("%q: expecting '%t', got '%t'\n", c.name, c.expected, result)
+		}
+	}
+}
+
+var errInvalidEntry = errors.New("invalid entry")
+
+func TestWalkCollectErrors(t *testing.T) {
+	for _, filename := range []string{"collect.tar.gz", "collect.zip"} {
+		path := writeTestArchive(t, filename, findEntries)
+
+		visited := 0
+		validate := func(name string) error {
+			visited++
+			if strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, ".md") {
+				return errInvalidEntry
+			}
+			return nil
+		}
+
+		err := WalkCollectErrors(path,
+			func(reader *tar.Reader, header *tar.Header) error { return validate(header.Name) },
+			func(file *zip.File) error { return validate(file.Name) })
+
+		if visited != len(findEntries) {
+			t.Errorf("%s: expecting '%d', got '%d'\n", filename, len(findEntries), visited)
+		}
+		if !errors.Is(err, errInvalidEntry) {
+			t.Errorf("Expecting '%s', got '%v'\n", errInvalidEntry, err)
+		}
+
+		expected := "src/main_test.go: invalid entry\ndocs/readme.md: invalid entry"
+		if err != nil && err.Error() != expected {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, err)
+		}
+
+		if err := WalkCollectErrors(path, nil, nil); err != nil {
+			t.Errorf("Unexpected error: %v\n", err)
+		}
+	}
+
+	if err := WalkCollectErrors("testdata/invalid.tar", nil, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
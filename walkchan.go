@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// WalkChan walks the contents of the archive at archivePath, whose type is determined by
+// DetermineType, in a separate goroutine, sending each entry in archive order on the
+// returned entry channel, which suits consumers that fan entries out to workers or
+// select over several sources. Once the walk ends, the entry channel is closed and its
+// outcome, nil or the error that ended it, is sent on the returned error channel, which
+// is then closed. Both channels are unbuffered, so the error must be received only after
+// the entry channel has been drained:
+//
+//	entries, errc := archive.WalkChan(ctx, path)
+//	for entry := range entries {
+//	    fmt.Println(entry.Name())
+//	}
+//	if err := <-errc; err != nil {
+//	    return err
+//	}
+//
+// Since the walk may advance to the next entry as soon as an entry has been received,
+// the contents of each regular file of a tar-family archive are read into memory before
+// the entry is sent, so that its Open method remains usable; at most two entries' contents
+// are held at once. Zip entries are read on demand, and the archive remains open until
+// the error has been received.
+//
+// A consumer that stops receiving early must cancel ctx, whereupon the goroutine closes
+// the archive and both channels without sending further; the error channel then yields
+// ctx.Err() or, if closed first, nil.
+func WalkChan(ctx context.Context, archivePath string) (<-chan Entry, <-chan error) {
+	entries := make(chan Entry)
+	errc := make(chan error)
+
+	go func() {
+		defer close(errc)
+
+		c, err := openCursor(archivePath)
+		if err == nil {
+			defer c.Close()
+			err = sendEntries(ctx, c, entries)
+		}
+		close(entries)
+
+		select {
+		case errc <- err:
+		case <-ctx.Done():
+		}
+	}()
+
+	return entries, errc
+}
+
+// Sends each entry read from c on entries until none remain or ctx is cancelled,
+// first reading the contents of tar-family regular files into memory.
+func sendEntries(ctx context.Context, c *cursor, entries chan<- Entry) error {
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if e.file == nil && e.mode.IsRegular() {
+			data, err := io.ReadAll(e.reader)
+			if err != nil {
+				return fmt.Errorf(fmtErrTarReadFailed, err)
+			}
+			e.reader = bytes.NewReader(data)
+		}
+
+		select {
+		case entries <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWalkChan(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		expected := walkNames(t, func(tarCb TarCallback, zipCb ZipCallback) error {
+			return walkArchive(archivePath, tarCb, zipCb)
+		})
+
+		var names []string
+		var received []Entry
+		entries, errc := WalkChan(context.Background(), archivePath)
+		for entry := range entries {
+			names = append(names, entry.Name())
+			received = append(received, entry)
+		}
+
+		// Contents remain readable until the error has been received.
+		for _, entry := range received {
+			if entry.Name() != sampleFileName {
+				continue
+			}
+
+			reader, err := entry.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil || int64(len(data)) != sampleFileSize {
+				t.Errorf("%s: expecting '%d', got '%d' (error: %v)\n", archivePath, sampleFileSize, len(data), err)
+			}
+		}
+
+		if err := <-errc; err != nil {
+			t.Errorf("Unexpected error for %s: %v\n", archivePath, err)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+		}
+	}
+}
+
+func TestWalkChan_cancel(t *testing.T) {
+	for _, archivePath := range sampleArchives {
+		ctx, cancel := context.WithCancel(context.Background())
+		entries, errc := WalkChan(ctx, archivePath)
+
+		<-entries
+		cancel()
+
+		// The goroutine exits without further sends, closing both channels.
+		for range entries {
+		}
+		if err := <-errc; err != nil && !errors.Is(err, context.Canceled) {
+			t.Errorf("Expecting '%s', got '%v'\n", context.Canceled, err)
+		}
+		if _, ok := <-errc; ok {
+			t.Error("Expected the error channel to be closed.")
+		}
+	}
+}
+
+func TestWalkChan_errors(t *testing.T) {
+	entries, errc := WalkChan(context.Background(), "nonexistent.tar.gz")
+	if _, ok := <-entries; ok {
+		t.Error("Expected no entries for a nonexistent archive.")
+	}
+	if err := <-errc; err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+
+	entries, errc = WalkChan(context.Background(), "testdata/invalid.tar")
+	for range entries {
+	}
+	if err := <-errc; err == nil {
+		t.Error("Failed to receive non-nil error when walking an invalid tar file.")
+	}
+}
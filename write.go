@@ -0,0 +1,336 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Format strings for errors encountered while writing archives. Unlike the original
+// read errors in archive.go, these have no earlier behavior to preserve, and they wrap
+// the underlying error so that its cause, such as a missing source file, an unsupported
+// compression method, or a failure of a reader supplied by the caller, can be examined
+// with errors.Is and errors.As.
+const (
+	fmtErrArchiveCreate  string = "archive: failed to create archive: %w"
+	fmtErrNewXzWriter    string = "archive: failed to create xz writer: %w"
+	fmtErrTarWriteFailed string = "archive: failed while writing tar contents: %w"
+	fmtErrZipWriteFailed string = "archive: failed while writing zip contents: %w"
+)
+
+// errUnsupportedWriteType is returned when asked to write an archive type for
+// which no compressor is available.
+var errUnsupportedWriteType = errors.New("archive: writing this archive type is not supported")
+
+// Transcode reads the tar stream of the tar-family archive at srcPath, whose type is
+// determined by DetermineType, and writes it to a new archive at dstPath compressed as
+// dstType. Entries are copied directly from the decompressed source stream to the
+// compressed destination stream without being extracted to disk. The destination may
+// be Tar, TarGz, or TarXz; bzip2 compression is not available for writing. If an error
+// occurs, the partially-written destination file is removed.
+func Transcode(srcPath, dstPath string, dstType Type) (err error) {
+	srcType, err := DetermineType(srcPath)
+	if err != nil {
+		return err
+	}
+
+	if !isCompressible(dstType) {
+		return errUnsupportedWriteType
+	}
+
+	src, err := openTarStream(srcPath, srcType)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filepath.Clean(dstPath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveCreate, err)
+	}
+	defer func() {
+		if cerr := dst.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf(fmtErrArchiveCreate, cerr)
+		}
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	compressor, err := newCompressWriter(dst, dstType)
+	if err != nil {
+		return err
+	}
+
+	if err := copyTar(tar.NewWriter(compressor), tar.NewReader(src)); err != nil {
+		compressor.Close()
+		return err
+	}
+
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
+
+// FilterCopy writes a new archive at dstPath, of the same type as the archive at srcPath,
+// containing only those entries of the source for which keep returns true, in their
+// original order. This strips unwanted files, such as .git directories or editor backups,
+// from an archive without extracting and repacking it. The type of the source is
+// determined by DetermineType, and keep is passed a description of each entry, whose
+// contents it should not read.
+//
+// Entries of a zip archive are copied byte for byte, still compressed, along with their
+// headers, so nothing is recompressed. A tar-family archive is decompressed and its kept
+// entries are written, headers intact, to a new tar stream compressed as the source was;
+// since bzip2 and lzma compression are not available for writing, TarBz2 and TarLzma
+// archives are not supported. Either way, each kept entry retains its mode, modification
+// time, and other header fields. Links are copied as they are, so a link whose target is
+// filtered out is left dangling. If an error occurs, the partially-written destination
+// file is removed.
+func FilterCopy(srcPath, dstPath string, keep func(Entry) bool) (err error) {
+	typ, err := DetermineType(srcPath)
+	if err != nil {
+		return err
+	}
+
+	if typ != Zip && !isCompressible(typ) {
+		return errUnsupportedWriteType
+	}
+
+	c, err := openCursorOfType(srcPath, typ)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	dst, err := os.Create(filepath.Clean(dstPath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveCreate, err)
+	}
+	defer func() {
+		if cerr := dst.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf(fmtErrArchiveCreate, cerr)
+		}
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	if typ == Zip {
+		return filterCopyZip(zip.NewWriter(dst), c, keep)
+	}
+
+	compressor, err := newCompressWriter(dst, typ)
+	if err != nil {
+		return err
+	}
+
+	if err := filterCopyTar(tar.NewWriter(compressor), c, keep); err != nil {
+		compressor.Close()
+		return err
+	}
+
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
+
+// Copies the raw contents and headers of the zip entries yielded by c for which keep
+// returns true to writer, and closes writer.
+func filterCopyZip(writer *zip.Writer, c *cursor, keep func(Entry) bool) error {
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if keep != nil && !keep(e) {
+			continue
+		}
+		if err := writer.Copy(e.file); err != nil {
+			return fmt.Errorf(fmtErrZipWriteFailed, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+	return nil
+}
+
+// Copies the tar entries yielded by c for which keep returns true to writer, and
+// closes writer, which flushes the tar trailer but leaves the underlying writer open.
+func filterCopyTar(writer *tar.Writer, c *cursor, keep func(Entry) bool) error {
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if keep != nil && !keep(e) {
+			continue
+		}
+		if err := writer.WriteHeader(e.header); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		if _, err := io.Copy(writer, e.reader); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
+
+// ExtractToTarWriter writes every entry of the archive at srcPath, whose type is determined
+// by DetermineType, to tw, so that archives of any supported type can be funneled into a
+// single tar stream, as for upload to a system that accepts only tar. Entries are written
+// in archive order and tw is neither flushed nor closed, so entries of several archives
+// can be written in turn before the caller closes it.
+//
+// Entries of tar-family archives are written with their headers unchanged. For a zip
+// entry, a header is derived from its file info, as by tar.FileInfoHeader: the entry's
+// name is kept, as are its permission bits and the file type recorded in its external
+// attributes; its modification time is File.Modified, which prefers a UTC time recorded
+// in an extra field to the MS-DOS time; its size is its uncompressed size; and the Unix
+// owner is taken from any extra field reporting it, as by ParseExtraOwner. Symbolic
+// links, whose targets a zip archive stores as their contents, become tar symbolic link
+// entries.
+func ExtractToTarWriter(srcPath string, tw *tar.Writer) error {
+	c, err := openCursor(srcPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		e, err := c.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		header := e.header
+		if header == nil {
+			if header, err = zipTarHeader(e); err != nil {
+				return err
+			}
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Size == 0 {
+			continue
+		}
+
+		reader, err := e.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+	}
+}
+
+// Returns a tar header describing the zip entry e.
+func zipTarHeader(e *entry) (*tar.Header, error) {
+	var linkname string
+	if e.mode&fs.ModeSymlink != 0 {
+		var err error
+		if linkname, err = symlinkTarget(e); err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(e.file.FileInfo(), linkname)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	header.Name = e.name
+	header.ModTime = e.modTime
+	if uid, gid, ok := ParseExtraOwner(e.file); ok {
+		header.Uid, header.Gid = uid, gid
+	}
+	return header, nil
+}
+
+// Copies every entry from reader to writer and closes writer, which flushes
+// the tar trailer but leaves the underlying writer open.
+func copyTar(writer *tar.Writer, reader *tar.Reader) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf(fmtErrTarReadFailed, err)
+		}
+
+		if err := writer.WriteHeader(header); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+		if _, err := io.Copy(writer, reader); err != nil {
+			return fmt.Errorf(fmtErrTarWriteFailed, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf(fmtErrTarWriteFailed, err)
+	}
+	return nil
+}
+
+// Reports whether archives of type typ can be written by newCompressWriter.
+func isCompressible(typ Type) bool {
+	return typ == Tar || typ == TarGz || typ == TarXz
+}
+
+// Returns a writer that compresses data written to it as appropriate for typ
+// before passing it to w. Closing the returned writer flushes the compressor
+// but does not close w.
+func newCompressWriter(w io.Writer, typ Type) (io.WriteCloser, error) {
+	switch typ {
+	case Tar:
+		return nopWriteCloser{w}, nil
+	case TarGz:
+		return gzip.NewWriter(w), nil
+	case TarXz:
+		writer, err := xz.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf(fmtErrNewXzWriter, err)
+		}
+		return writer, nil
+	}
+
+	return nil, errUnsupportedWriteType
+}
+
+// Struct nopWriteCloser adds a no-op Close method to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing and returns nil.
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the names of the entries in the tar-family archive at path.
+func tarEntryNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	var names []string
+	err := walkArchive(path, func(reader *tar.Reader, header *tar.Header) error {
+		names = append(names, header.Name)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return names
+}
+
+func TestTranscode(t *testing.T) {
+	expected := tarEntryNames(t, "testdata/sample.tar.bz2")
+
+	for _, filename := range []string{"out.tar", "out.tar.gz", "out.tar.xz"} {
+		dstPath := filepath.Join(t.TempDir(), filename)
+		dstType, _ := DetermineType(filename)
+
+		if err := Transcode("testdata/sample.tar.bz2", dstPath, dstType); err != nil {
+			t.Errorf("Unexpected error transcoding to %s: %v\n", filename, err)
+			continue
+		}
+
+		if names := tarEntryNames(t, dstPath); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expecting '%v', got '%v'\n", expected, names)
+		}
+
+		reader, err := ContentReader(dstPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := make([]byte, sampleFileSize+1)
+		if n, _ := reader.Read(content); n == 0 {
+			t.Errorf("Failed to read transcoded content from %s.\n", filename)
+		}
+		reader.Close()
+	}
+}
+
+func TestTranscode_errors(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, typ := range []Type{TarBz2, Zip, 0} {
+		if err := Transcode("testdata/sample.tar", filepath.Join(dir, "out"), typ); err != errUnsupportedWriteType {
+			t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedWriteType, err)
+		}
+	}
+
+	if err := Transcode("testdata/sample.zip", filepath.Join(dir, "out.tar"), Tar); err != errNotTar {
+		t.Errorf("Expecting '%s', got '%v'\n", errNotTar, err)
+	}
+
+	dstPath := filepath.Join(dir, "invalid.tar.gz")
+	if err := Transcode("testdata/invalid.tar", dstPath, TarGz); err == nil {
+		t.Error("Failed to receive non-nil error when transcoding an invalid tar file.")
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Error("Failed to remove the destination after a failed transcode.")
+	}
+
+	missing := filepath.Join(dir, "missing", "out.tar.gz")
+	if err := Transcode("testdata/sample.tar", missing, TarGz); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expecting '%s', got '%v'\n", fs.ErrNotExist, err)
+	}
+}
+
+var filterCopyEntries = []testEntry{
+	{name: "project/"},
+	{name: "project/.git/"},
+	{name: "project/.git/HEAD", body: "ref: refs/heads/main\n"},
+	{name: "project/main.go", body: "package main\n", mode: 0644},
+	{name: "project/run.sh", body: "#!/bin/sh\n", mode: 0755, modTime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+}
+
+func TestFilterCopy(t *testing.T) {
+	keep := func(e Entry) bool {
+		return !strings.Contains(e.Name(), "/.git/")
+	}
+
+	for _, filename := range []string{"filter.tar", "filter.tar.gz", "filter.tar.xz", "filter.zip"} {
+		srcPath := writeTestArchive(t, filename, filterCopyEntries)
+		dstPath := filepath.Join(t.TempDir(), filename)
+
+		if err := FilterCopy(srcPath, dstPath, keep); err != nil {
+			t.Errorf("Unexpected error copying %s: %v\n", filename, err)
+			continue
+		}
+
+		want := make(map[string]EntryInfo)
+		err := forEachEntry(srcPath, func(e *entry) error {
+			if keep(e) {
+				want[e.name] = EntryInfo{Name: e.name, Size: e.size, ModTime: e.modTime, Mode: e.mode}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		err = forEachEntry(dstPath, func(e *entry) error {
+			names = append(names, e.name)
+			got := EntryInfo{Name: e.name, Size: e.size, ModTime: e.modTime, Mode: e.mode}
+			if !reflect.DeepEqual(got, want[e.name]) {
+				t.Errorf("%s: expecting '%v', got '%v'\n", filename, want[e.name], got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error walking copy of %s: %v\n", filename, err)
+		}
+
+		expected := []string{"project/", "project/main.go", "project/run.sh"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expecting '%v', got '%v'\n", filename, expected, names)
+		}
+
+		reader, err := ContentReader(dstPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if expected := "package main\n#!/bin/sh\n"; string(content) != expected || err != nil {
+			t.Errorf("%s: expecting '%s', got '%s' (error: %v)\n", filename, expected, content, err)
+		}
+	}
+}
+
+func TestFilterCopy_errors(t *testing.T) {
+	dir := t.TempDir()
+
+	dstPath := filepath.Join(dir, "out.tar.bz2")
+	if err := FilterCopy("testdata/sample.tar.bz2", dstPath, nil); err != errUnsupportedWriteType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnsupportedWriteType, err)
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Error("Failed to leave the destination uncreated for an unsupported type.")
+	}
+
+	if err := FilterCopy("foo.123", filepath.Join(dir, "out"), nil); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+
+	dstPath = filepath.Join(dir, "invalid.tar")
+	if err := FilterCopy("testdata/invalid.tar", dstPath, nil); err == nil {
+		t.Error("Failed to receive non-nil error when copying an invalid tar file.")
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Error("Failed to remove the destination after a failed copy.")
+	}
+}
+
+var tarWriterEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/run.sh", body: "#!/bin/sh\n", mode: 0755, modTime: time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)},
+	{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "run.sh"},
+	{name: "empty.txt"},
+}
+
+func TestExtractToTarWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, filename := range []string{"source.zip", "source.tar.gz"} {
+		if err := ExtractToTarWriter(writeTestArchive(t, filename, tarWriterEntries), tw); err != nil {
+			t.Fatalf("Unexpected error writing %s: %v\n", filename, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: testModTime},
+		{Name: "dir/run.sh", Typeflag: tar.TypeReg, Mode: 0755, ModTime: tarWriterEntries[1].modTime, Size: 10},
+		{Name: "dir/link", Typeflag: tar.TypeSymlink, Mode: 0777, ModTime: testModTime, Linkname: "run.sh"},
+		{Name: "empty.txt", Typeflag: tar.TypeReg, Mode: 0644, ModTime: testModTime},
+	}
+	reader := tar.NewReader(&buf)
+	for i := 0; ; i++ {
+		header, err := reader.Next()
+		if err == io.EOF {
+			if i != 2*len(expected) {
+				t.Errorf("Expecting %d entries, got %d\n", 2*len(expected), i)
+			}
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		want := expected[i%len(expected)]
+		if i >= len(expected) && want.Typeflag == tar.TypeSymlink {
+			// Tar headers are copied unchanged, and the test archive's links have mode 0644.
+			want.Mode = 0644
+		}
+		if header.Name != want.Name || header.Typeflag != want.Typeflag || header.Mode != want.Mode ||
+			!header.ModTime.Equal(want.ModTime) || header.Size != want.Size || header.Linkname != want.Linkname {
+			t.Errorf("Entry %d: expecting '%+v', got '%+v'\n", i, want, *header)
+		}
+
+		content, err := io.ReadAll(reader)
+		if want.Name == "dir/run.sh" && (string(content) != tarWriterEntries[1].body || err != nil) {
+			t.Errorf("Entry %d: expecting '%s', got '%s' (error: %v)\n", i, tarWriterEntries[1].body, content, err)
+		}
+	}
+
+	if err := ExtractToTarWriter("foo.123", tar.NewWriter(io.Discard)); err != errUnknownType {
+		t.Errorf("Expecting '%s', got '%v'\n", errUnknownType, err)
+	}
+}
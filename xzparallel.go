@@ -0,0 +1,330 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Layout of the xz container format (The .xz File Format, version 1.0.4).
+const (
+	xzHeaderLen    = 12
+	xzFooterLen    = 12
+	xzFlagsOffset  = 6
+	xzIndexMarker  = 0x00
+	xzMaxVarintLen = 9
+
+	// No LZMA2 chunk, which holds at most 2 MiB of data, is stored in fewer than
+	// six bytes, which bounds the uncompressed size of a block of a given size.
+	xzMaxRatio = 1 << 19
+)
+
+// xzFooterMagic ends every xz stream footer.
+var xzFooterMagic = []byte("YZ")
+
+// errXzIndex is returned when the index of an xz stream cannot be parsed.
+var errXzIndex = errors.New("archive: invalid xz index")
+
+// Struct xzBlock locates a single block within an xz stream.
+type xzBlock struct {
+	offset       int64 // offset of the block header within the file
+	unpaddedSize int64 // size of the block excluding its trailing padding
+	size         int64 // uncompressed size of the block's data
+}
+
+// WalkTarXzParallel walks the contents of a lzma2-compressed (xz) tar file, as
+// WalkTarXz does, but decompresses up to threads blocks of the file concurrently.
+// Files compressed by multi-threaded encoders (such as "xz -T") are divided into
+// independently compressed blocks whose locations are recorded in the file's index,
+// which allows decompression to proceed in parallel with the walk. The callback is
+// still invoked sequentially, in archive order.
+//
+// Decompressed blocks are held in memory until consumed, so memory use grows with both
+// threads and the encoder's block size. Files consisting of a single block, files
+// containing more than one xz stream, and calls specifying fewer than two threads gain
+// nothing from parallelism and are walked serially, as by WalkTarXz, as are files whose
+// index records implausible sizes.
+func WalkTarXzParallel(archivePath string, threads int, callback TarCallback) error {
+	if threads < 2 {
+		return walkTarWrapping(archivePath, TarXz, callback)
+	}
+
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	header, blocks, err := readXzIndex(file)
+	if err != nil || len(blocks) < 2 {
+		return walkTarWrapping(archivePath, TarXz, callback)
+	}
+
+	reader := newParallelXzReader(file, header, blocks, threads)
+	defer reader.Close()
+
+	return readTarWrapping(tar.NewReader(reader), callback)
+}
+
+// Reads the stream header and index of a single-stream xz file, returning the
+// header and the locations of the stream's blocks.
+func readXzIndex(file *os.File) (header []byte, blocks []xzBlock, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header = make([]byte, xzHeaderLen)
+	footer := make([]byte, xzFooterLen)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, nil, err
+	}
+	if _, err := file.ReadAt(footer, info.Size()-xzFooterLen); err != nil {
+		return nil, nil, err
+	}
+
+	if !bytes.HasPrefix(header, xzMagic) || !bytes.HasSuffix(footer, xzFooterMagic) {
+		return nil, nil, errXzIndex
+	}
+
+	indexSize := (int64(binary.LittleEndian.Uint32(footer[4:8])) + 1) * 4
+	indexOffset := info.Size() - xzFooterLen - indexSize
+	if indexOffset < xzHeaderLen {
+		return nil, nil, errXzIndex
+	}
+
+	index := make([]byte, indexSize)
+	if _, err := file.ReadAt(index, indexOffset); err != nil {
+		return nil, nil, err
+	}
+
+	blocks, err = parseXzIndex(index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The sizes are checked before anything is allocated from them, so that a
+	// corrupt or crafted index cannot exhaust memory.
+	for _, block := range blocks {
+		if block.unpaddedSize <= 0 || block.unpaddedSize > indexOffset ||
+			block.size < 0 || block.size > block.unpaddedSize*xzMaxRatio || int64(int(block.size)) != block.size {
+			return nil, nil, errXzIndex
+		}
+	}
+
+	// The blocks must exactly fill the space between the header and the index;
+	// anything else indicates concatenated streams or stream padding.
+	offset := int64(xzHeaderLen)
+	for i := range blocks {
+		blocks[i].offset = offset
+		offset += roundUp4(blocks[i].unpaddedSize)
+	}
+	if offset != indexOffset {
+		return nil, nil, errXzIndex
+	}
+
+	return header, blocks, nil
+}
+
+// Parses the records of an xz index.
+func parseXzIndex(index []byte) ([]xzBlock, error) {
+	if len(index) < 8 || index[0] != xzIndexMarker {
+		return nil, errXzIndex
+	}
+
+	body := index[:len(index)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(index[len(index)-4:]) {
+		return nil, errXzIndex
+	}
+
+	r := bytes.NewReader(body[1:])
+	count, err := readXzVarint(r)
+	if err != nil || count > uint64(len(body)) {
+		return nil, errXzIndex
+	}
+
+	blocks := make([]xzBlock, count)
+	for i := range blocks {
+		unpadded, err := readXzVarint(r)
+		if err != nil {
+			return nil, errXzIndex
+		}
+		size, err := readXzVarint(r)
+		if err != nil {
+			return nil, errXzIndex
+		}
+		blocks[i] = xzBlock{unpaddedSize: int64(unpadded), size: int64(size)}
+	}
+
+	return blocks, nil
+}
+
+// Reads a variable-length integer as encoded in xz headers and indexes.
+func readXzVarint(r io.ByteReader) (uint64, error) {
+	var value uint64
+	for i := 0; i < xzMaxVarintLen; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		value |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+
+	return 0, errXzIndex
+}
+
+// Appends value to buf encoded as an xz variable-length integer.
+func appendXzVarint(buf []byte, value uint64) []byte {
+	for value >= 0x80 {
+		buf = append(buf, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buf, byte(value))
+}
+
+// Rounds n up to a multiple of four.
+func roundUp4(n int64) int64 {
+	return (n + 3) &^ 3
+}
+
+// Decompresses a single block by wrapping it in a minimal stream consisting of
+// the original stream header, the block, and a single-record index and footer.
+func decodeXzBlock(file io.ReaderAt, header []byte, block xzBlock) ([]byte, error) {
+	stream := make([]byte, 0, int64(len(header))+roundUp4(block.unpaddedSize)+64)
+	stream = append(stream, header...)
+
+	data := make([]byte, roundUp4(block.unpaddedSize))
+	if _, err := file.ReadAt(data, block.offset); err != nil {
+		return nil, fmt.Errorf(fmtErrNewXzReader, err)
+	}
+	stream = append(stream, data...)
+
+	indexStart := len(stream)
+	stream = append(stream, xzIndexMarker)
+	stream = appendXzVarint(stream, 1)
+	stream = appendXzVarint(stream, uint64(block.unpaddedSize))
+	stream = appendXzVarint(stream, uint64(block.size))
+	for (len(stream)-indexStart)%4 != 0 {
+		stream = append(stream, 0)
+	}
+	stream = binary.LittleEndian.AppendUint32(stream, crc32.ChecksumIEEE(stream[indexStart:]))
+
+	backwardSize := uint32((len(stream)-indexStart)/4 - 1)
+	footer := binary.LittleEndian.AppendUint32(nil, backwardSize)
+	footer = append(footer, header[xzFlagsOffset:xzFlagsOffset+2]...)
+	stream = binary.LittleEndian.AppendUint32(stream, crc32.ChecksumIEEE(footer))
+	stream = append(stream, footer...)
+	stream = append(stream, xzFooterMagic...)
+
+	reader, err := xz.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrNewXzReader, err)
+	}
+
+	// At most one byte more than the index records is decompressed, which is
+	// enough to tell that the block is larger than it should be.
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(reader, block.size+1)); err != nil {
+		return nil, fmt.Errorf(fmtErrDecompress, err)
+	}
+	if int64(buf.Len()) != block.size {
+		return nil, errXzIndex
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Struct xzBlockResult holds the outcome of decompressing a block.
+type xzBlockResult struct {
+	data []byte
+	err  error
+}
+
+// Struct parallelXzReader reads the decompressed contents of an xz stream,
+// decompressing up to a fixed number of blocks ahead of the reader concurrently.
+type parallelXzReader struct {
+	file    io.ReaderAt
+	header  []byte
+	blocks  []xzBlock
+	pending []chan xzBlockResult
+	current *bytes.Reader
+	next    int // index of the next block to be read
+	started int // number of blocks whose decompression has been started
+	wg      sync.WaitGroup
+}
+
+// Returns a reader over the decompressed blocks, with decompression of the
+// first threads blocks already under way.
+func newParallelXzReader(file io.ReaderAt, header []byte, blocks []xzBlock, threads int) *parallelXzReader {
+	r := &parallelXzReader{
+		file:    file,
+		header:  header,
+		blocks:  blocks,
+		pending: make([]chan xzBlockResult, len(blocks)),
+		current: bytes.NewReader(nil),
+	}
+
+	for r.started < threads && r.started < len(blocks) {
+		r.start()
+	}
+
+	return r
+}
+
+// Starts decompressing the next unstarted block.
+func (r *parallelXzReader) start() {
+	i := r.started
+	r.started++
+
+	// The channel is buffered so that the goroutine never blocks on send,
+	// even if the reader is closed before the result is received.
+	r.pending[i] = make(chan xzBlockResult, 1)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		data, err := decodeXzBlock(r.file, r.header, r.blocks[i])
+		r.pending[i] <- xzBlockResult{data: data, err: err}
+	}()
+}
+
+// Read reads decompressed data, waiting for the next block as needed.
+func (r *parallelXzReader) Read(p []byte) (int, error) {
+	for r.current.Len() == 0 {
+		if r.next >= len(r.blocks) {
+			return 0, io.EOF
+		}
+
+		result := <-r.pending[r.next]
+		r.pending[r.next] = nil
+		r.next++
+		if result.err != nil {
+			return 0, result.err
+		}
+
+		if r.started < len(r.blocks) {
+			r.start()
+		}
+		r.current = bytes.NewReader(result.data)
+	}
+
+	return r.current.Read(p)
+}
+
+// Close waits for any in-progress decompression to finish.
+func (r *parallelXzReader) Close() error {
+	r.wg.Wait()
+	return nil
+}
@@ -0,0 +1,322 @@
+package archive
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+const multiblockArchive = "testdata/sample.multiblock.tar.xz"
+
+// Returns a walk function over archivePath that records a digest of each entry,
+// along with the recorded digests.
+func digestWalk(t testing.TB, walk func(callback TarCallback) error) []string {
+	t.Helper()
+
+	var digests []string
+	err := walk(func(reader *tar.Reader, header *tar.Header) error {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, reader); err != nil {
+			return err
+		}
+		digests = append(digests, fmt.Sprintf("%s %x", header.Name, hash.Sum(nil)))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digests
+}
+
+func TestWalkTarXzParallel(t *testing.T) {
+	expected := digestWalk(t, func(callback TarCallback) error {
+		return WalkTarXz(multiblockArchive, callback)
+	})
+	if len(expected) != 14 {
+		t.Fatalf("Expecting '%d', got '%d'\n", 14, len(expected))
+	}
+
+	for _, archivePath := range []string{multiblockArchive, "testdata/sample.tar.xz"} {
+		serial := digestWalk(t, func(callback TarCallback) error {
+			return WalkTarXz(archivePath, callback)
+		})
+
+		for _, threads := range []int{0, 1, 2, 3, 16} {
+			result := digestWalk(t, func(callback TarCallback) error {
+				return WalkTarXzParallel(archivePath, threads, callback)
+			})
+
+			if !reflect.DeepEqual(result, serial) {
+				t.Errorf("%s with %d threads: expecting '%v', got '%v'\n", archivePath, threads, serial, result)
+			}
+		}
+	}
+}
+
+func TestReadXzIndex(t *testing.T) {
+	file, err := os.Open(multiblockArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	header, blocks, err := readXzIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(header) != xzHeaderLen || len(blocks) != 7 {
+		t.Fatalf("Expecting '%d' blocks, got '%d'\n", 7, len(blocks))
+	}
+
+	var total int64
+	for _, block := range blocks {
+		total += block.size
+	}
+	if total != 204800 {
+		t.Errorf("Expecting '%d', got '%d'\n", 204800, total)
+	}
+
+	if blocks[1].offset != 6508 {
+		t.Errorf("Expecting '%d', got '%d'\n", 6508, blocks[1].offset)
+	}
+}
+
+func TestWalkTarXzParallel_errors(t *testing.T) {
+	errStop := errors.New("stop")
+	callback := func(reader *tar.Reader, header *tar.Header) error {
+		return errStop
+	}
+
+	for _, archivePath := range []string{multiblockArchive, "testdata/sample.tar.xz"} {
+		for _, threads := range []int{1, 4} {
+			if err := WalkTarXzParallel(archivePath, threads, callback); !errors.Is(err, errStop) {
+				t.Errorf("%s with %d threads: expecting '%s', got '%v'\n", archivePath, threads, errStop, err)
+			}
+		}
+	}
+
+	if err := WalkTarXzParallel("nonexistent.tar.xz", 4, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent tar.xz file.")
+	}
+
+	if err := WalkTarXzParallel("testdata/sample.tar.gz", 4, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a tar.gz file via WalkTarXzParallel.")
+	}
+
+	data, err := os.ReadFile(multiblockArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[xzHeaderLen+100] ^= 0xff
+	path := filepath.Join(t.TempDir(), "corrupt.tar.xz")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkTarXzParallel(path, 4, nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a corrupt tar.xz file.")
+	}
+}
+
+// Returns a copy of the multi-block test archive whose index records the blocks
+// as modified by edit, with the index's checksum and the stream footer rewritten
+// to match.
+func rewriteXzIndex(t *testing.T, edit func(blocks []xzBlock)) string {
+	t.Helper()
+
+	file, err := os.Open(multiblockArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	header, blocks, err := readXzIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(blocks)
+
+	last := blocks[len(blocks)-1]
+	data := make([]byte, last.offset+roundUp4(last.unpaddedSize))
+	if _, err := file.ReadAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	indexStart := len(data)
+	data = append(data, xzIndexMarker)
+	data = appendXzVarint(data, uint64(len(blocks)))
+	for _, block := range blocks {
+		data = appendXzVarint(data, uint64(block.unpaddedSize))
+		data = appendXzVarint(data, uint64(block.size))
+	}
+	for (len(data)-indexStart)%4 != 0 {
+		data = append(data, 0)
+	}
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data[indexStart:]))
+
+	footer := binary.LittleEndian.AppendUint32(nil, uint32((len(data)-indexStart)/4-1))
+	footer = append(footer, header[xzFlagsOffset:xzFlagsOffset+2]...)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(footer))
+	data = append(data, footer...)
+	data = append(data, xzFooterMagic...)
+
+	path := filepath.Join(t.TempDir(), "crafted.tar.xz")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWalkTarXzParallel_craftedIndex(t *testing.T) {
+	path := rewriteXzIndex(t, func(blocks []xzBlock) {})
+	if result := digestWalk(t, func(callback TarCallback) error {
+		return WalkTarXzParallel(path, 4, callback)
+	}); len(result) != 14 {
+		t.Fatalf("Expecting '%d', got '%d'\n", 14, len(result))
+	}
+
+	for _, test := range []struct {
+		name string
+		edit func(blocks []xzBlock)
+	}{
+		{"huge size", func(blocks []xzBlock) { blocks[0].size = 1 << 62 }},
+		{"implausible size", func(blocks []xzBlock) { blocks[0].size = blocks[0].unpaddedSize*xzMaxRatio + 1 }},
+		{"negative size", func(blocks []xzBlock) { blocks[0].size = -1 }},
+		{"huge unpadded size", func(blocks []xzBlock) { blocks[0].unpaddedSize = 1 << 62 }},
+	} {
+		path := rewriteXzIndex(t, test.edit)
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = readXzIndex(file)
+		file.Close()
+		if err != errXzIndex {
+			t.Errorf("%s: expecting '%v', got '%v'\n", test.name, errXzIndex, err)
+		}
+
+		// Files whose index cannot be used are walked serially, as WalkTarXz would.
+		if result := digestWalk(t, func(callback TarCallback) error {
+			return WalkTarXzParallel(path, 4, callback)
+		}); len(result) != 14 {
+			t.Errorf("%s: expecting '%d', got '%d'\n", test.name, 14, len(result))
+		}
+	}
+
+	// A size that is plausible but wrong is caught once the block is decompressed,
+	// without decompressing more than the index records.
+	for _, delta := range []int64{-1, 1, 1 << 20} {
+		path := rewriteXzIndex(t, func(blocks []xzBlock) { blocks[1].size += delta })
+		if err := WalkTarXzParallel(path, 4, nil); err == nil {
+			t.Errorf("Failed to receive non-nil error for a block size off by %d.", delta)
+		}
+	}
+}
+
+func TestXzVarint(t *testing.T) {
+	for _, value := range []uint64{0, 1, 127, 128, 300, 1 << 32, 1<<63 - 1} {
+		buf := appendXzVarint(nil, value)
+		result, err := readXzVarint(&byteReader{buf: buf})
+		if err != nil || result != value {
+			t.Errorf("Expecting '%d', got '%d' (error: %v)\n", value, result, err)
+		}
+	}
+}
+
+// Struct byteReader implements io.ByteReader over a slice.
+type byteReader struct {
+	buf []byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+// Writes a multi-block tar.xz file of roughly size bytes of pseudo-random text,
+// compressed in blocks of blockSize bytes, and returns its path.
+func writeMultiblockTarXz(b *testing.B, size, blockSize int) string {
+	b.Helper()
+
+	path := filepath.Join(b.TempDir(), "bench.tar.xz")
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	xw, err := xz.WriterConfig{BlockSize: int64(blockSize)}.NewWriter(file)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit"}
+	tw := tar.NewWriter(xw)
+	for i := 0; i*blockSize/2 < size; i++ {
+		body := make([]byte, 0, blockSize/2)
+		for len(body) < blockSize/2-16 {
+			body = append(body, words[rng.Intn(len(words))]...)
+			body = append(body, fmt.Sprintf(" %d\n", rng.Intn(1000000))...)
+		}
+
+		if err := tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("file%03d.txt", i), Mode: 0644, Size: int64(len(body))}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		b.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		b.Fatal(err)
+	}
+
+	return path
+}
+
+func benchmarkWalkTarXz(b *testing.B, walk func(path string, callback TarCallback) error) {
+	path := writeMultiblockTarXz(b, 4<<20, 512<<10)
+	callback := func(reader *tar.Reader, header *tar.Header) error {
+		_, err := io.Copy(io.Discard, reader)
+		return err
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := walk(path, callback); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWalkTarXz(b *testing.B) {
+	benchmarkWalkTarXz(b, WalkTarXz)
+}
+
+func BenchmarkWalkTarXzParallel(b *testing.B) {
+	benchmarkWalkTarXz(b, func(path string, callback TarCallback) error {
+		return WalkTarXzParallel(path, runtime.NumCPU(), callback)
+	})
+}
@@ -0,0 +1,345 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Zip extra field header IDs (APPNOTE.TXT, section 4.5, and Info-ZIP's extrafld.txt).
+const (
+	ntfsExtraID        uint16 = 0x000a
+	unixExtraID        uint16 = 0x000d
+	extTimeExtraID     uint16 = 0x5455
+	infoZipUnixExtraID uint16 = 0x5855
+	unixOwnerExtraID   uint16 = 0x7875
+)
+
+// Host systems recorded in the upper byte of a zip entry's "version made by" field
+// whose external attributes hold a Unix mode in their upper 16 bits.
+const (
+	zipHostUnix  = 3
+	zipHostMacOS = 19
+)
+
+// Layout of a Unix mode held in the upper 16 bits of a zip entry's external attributes.
+// Bit 15 of the lower 16 bits, unused by MS-DOS attributes, is set by 7-Zip and others to
+// flag a Unix mode recorded by a host that is not Unix.
+const (
+	zipUnixModeFlag = 0x8000
+	unixTypeMask    = 0170000
+	unixPermMask    = 0777
+)
+
+// File types encoded in the type bits of a Unix mode (the S_IFMT field). Some tools
+// record only the permission bits, leaving the type bits clear for regular files.
+var unixFileTypes = map[uint32]fs.FileMode{
+	0:       0,
+	0010000: fs.ModeNamedPipe,
+	0020000: fs.ModeDevice | fs.ModeCharDevice,
+	0040000: fs.ModeDir,
+	0060000: fs.ModeDevice,
+	0100000: 0,
+	0120000: fs.ModeSymlink,
+	0140000: fs.ModeSocket,
+}
+
+// errMalformedExtra is returned by ParseExtraFields when a zip extra field
+// record extends beyond the end of the field.
+var errMalformedExtra = errors.New("archive: malformed zip extra field")
+
+// Layout of the NTFS extra field's timestamp attribute, which holds the modification,
+// access, and creation times in that order.
+const (
+	ntfsTimeAttrTag  uint16 = 0x0001
+	ntfsTimeAttrSize        = 24
+)
+
+// Flags of the extended timestamp extra field indicating which times are present.
+const (
+	extTimeModFlag    = 0x1
+	extTimeAccessFlag = 0x2
+	extTimeCreateFlag = 0x4
+)
+
+// ntfsEpochOffset is the number of 100-nanosecond intervals between the
+// Windows FILETIME epoch (1601-01-01) and the Unix epoch.
+const ntfsEpochOffset = 116444736000000000
+
+// Calls fn with the header ID and data of each well-formed record in a zip
+// extra field, stopping early if fn returns false.
+func eachExtraField(extra []byte, fn func(id uint16, data []byte) bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return
+		}
+
+		data := extra[4 : 4+size]
+		extra = extra[4+size:]
+
+		if !fn(id, data) {
+			return
+		}
+	}
+}
+
+// ParseExtraFields splits the extra field of a zip entry into its records, returning
+// each record's data keyed by its header ID, such as 0x0001 (zip64), 0x000a (NTFS),
+// 0x5455 (extended timestamp), or 0x7875 (Info-ZIP Unix UID/GID). Should a header ID
+// occur more than once, the first record is returned. The returned slices refer to
+// f.Extra. A non-nil error is returned if a record extends beyond the end of the field.
+func ParseExtraFields(f *zip.File) (map[uint16][]byte, error) {
+	fields := make(map[uint16][]byte)
+	extra := f.Extra
+	for len(extra) > 0 {
+		if len(extra) < 4 {
+			return nil, errMalformedExtra
+		}
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return nil, errMalformedExtra
+		}
+
+		if _, ok := fields[id]; !ok {
+			fields[id] = extra[4 : 4+size]
+		}
+		extra = extra[4+size:]
+	}
+
+	return fields, nil
+}
+
+// ExtraTimes holds the timestamps recorded in the extra field of a zip entry. Times
+// not recorded are zero.
+type ExtraTimes struct {
+	ModTime    time.Time
+	AccessTime time.Time
+	CreateTime time.Time
+}
+
+// ParseExtraTimes returns the UTC timestamps recorded in the extra field of a zip entry,
+// reporting whether any were found. The NTFS field (0x000a), which records all three times
+// to a resolution of 100 nanoseconds, is preferred over the extended timestamp field
+// (0x5455), which records times to the second; the latter usually carries only the
+// modification time when read from the central directory.
+func ParseExtraTimes(f *zip.File) (times ExtraTimes, found bool) {
+	fields, _ := ParseExtraFields(f)
+
+	if data, ok := fields[ntfsExtraID]; ok {
+		if times, found = ntfsTimes(data); found {
+			return times, true
+		}
+	}
+
+	data, ok := fields[extTimeExtraID]
+	if !ok || len(data) < 1 {
+		return ExtraTimes{}, false
+	}
+
+	// A flags byte is followed by a 4-byte Unix time for each time flagged as present,
+	// although the field may be truncated to hold only the first.
+	flags, values := data[0], data[1:]
+	next := func(flag byte) time.Time {
+		if flags&flag == 0 || len(values) < 4 {
+			return time.Time{}
+		}
+		t := time.Unix(int64(int32(binary.LittleEndian.Uint32(values[0:4]))), 0).UTC()
+		values = values[4:]
+		found = true
+		return t
+	}
+	times.ModTime = next(extTimeModFlag)
+	times.AccessTime = next(extTimeAccessFlag)
+	times.CreateTime = next(extTimeCreateFlag)
+
+	return times, found
+}
+
+// ParseExtraOwner returns the Unix user and group IDs recorded in the extra field of a
+// zip entry, reporting whether they were found. The Info-ZIP Unix UID/GID field (0x7875),
+// which holds IDs of any width, is preferred over the 16-bit IDs of the Info-ZIP Unix
+// (0x5855) and PKWARE Unix (0x000d) fields.
+func ParseExtraOwner(f *zip.File) (uid, gid int, found bool) {
+	fields, _ := ParseExtraFields(f)
+
+	// A version byte, then the UID and GID, each preceded by its size in bytes.
+	if data, ok := fields[unixOwnerExtraID]; ok && len(data) >= 2 && data[0] == 1 {
+		uid, rest, uidOK := readSizedID(data[1:])
+		gid, _, gidOK := readSizedID(rest)
+		if uidOK && gidOK {
+			return uid, gid, true
+		}
+	}
+
+	// The access and modification times are followed by 16-bit IDs.
+	for _, id := range []uint16{infoZipUnixExtraID, unixExtraID} {
+		if data, ok := fields[id]; ok && len(data) >= 12 {
+			return int(binary.LittleEndian.Uint16(data[8:10])), int(binary.LittleEndian.Uint16(data[10:12])), true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// Reads a little-endian ID preceded by its size in bytes, returning the ID and
+// the remaining data.
+func readSizedID(data []byte) (int, []byte, bool) {
+	if len(data) < 1 {
+		return 0, nil, false
+	}
+	size := int(data[0])
+	if size > 8 || len(data) < 1+size {
+		return 0, nil, false
+	}
+
+	var id uint64
+	for i := size - 1; i >= 0; i-- {
+		id = id<<8 | uint64(data[1+i])
+	}
+	return int(id), data[1+size:], true
+}
+
+// EntryModTime returns the modification time of a zip entry. Zip headers record
+// modification times as MS-DOS date and time values, which carry no time zone and are
+// conventionally written in the creator's local time. Many tools additionally record a
+// UTC modification time in an extra field: the extended timestamp (0x5455), Info-ZIP
+// Unix (0x5855), PKWARE Unix (0x000d), or NTFS (0x000a) field. When such a field is
+// present, the time it records is returned in UTC. Otherwise, the MS-DOS time is
+// returned interpreted in the local time zone of the caller, which is only correct if
+// the archive was created in the same time zone.
+//
+// File.Modified differs in that, lacking an extra field, it interprets the MS-DOS time
+// as UTC, which produces times that are off by the creator's UTC offset.
+func EntryModTime(f *zip.File) time.Time {
+	if modified, ok := extraModTime(f.Extra); ok {
+		return modified
+	}
+
+	m := f.Modified
+	return time.Date(m.Year(), m.Month(), m.Day(), m.Hour(), m.Minute(), m.Second(), 0, time.Local)
+}
+
+// Returns the UTC modification time recorded in a zip extra field, if any.
+func extraModTime(extra []byte) (modified time.Time, found bool) {
+	eachExtraField(extra, func(id uint16, data []byte) bool {
+		le := binary.LittleEndian
+
+		switch id {
+		case extTimeExtraID:
+			// A flags byte, whose lowest bit indicates the presence of the modification time.
+			if len(data) >= 5 && data[0]&0x1 != 0 {
+				modified, found = time.Unix(int64(int32(le.Uint32(data[1:5]))), 0), true
+			}
+		case infoZipUnixExtraID, unixExtraID:
+			// The access time followed by the modification time.
+			if len(data) >= 8 {
+				modified, found = time.Unix(int64(int32(le.Uint32(data[4:8]))), 0), true
+			}
+		case ntfsExtraID:
+			modified, found = ntfsModTime(data)
+		}
+
+		return !found
+	})
+
+	return modified.UTC(), found
+}
+
+// Returns the modification time from the timestamp attribute of an NTFS extra field.
+func ntfsModTime(data []byte) (time.Time, bool) {
+	times, ok := ntfsTimes(data)
+	return times.ModTime, ok
+}
+
+// Returns the times from the timestamp attribute of an NTFS extra field.
+func ntfsTimes(data []byte) (ExtraTimes, bool) {
+	if len(data) < 4 {
+		return ExtraTimes{}, false
+	}
+
+	// Four reserved bytes precede a sequence of tagged attributes.
+	attrs := data[4:]
+	for len(attrs) >= 4 {
+		tag := binary.LittleEndian.Uint16(attrs[0:2])
+		size := int(binary.LittleEndian.Uint16(attrs[2:4]))
+		if len(attrs) < 4+size {
+			break
+		}
+
+		if tag == ntfsTimeAttrTag && size == ntfsTimeAttrSize {
+			return ExtraTimes{
+				ModTime:    fileTime(attrs[4:12]),
+				AccessTime: fileTime(attrs[12:20]),
+				CreateTime: fileTime(attrs[20:28]),
+			}, true
+		}
+		attrs = attrs[4+size:]
+	}
+
+	return ExtraTimes{}, false
+}
+
+// Converts a little-endian Windows FILETIME value to a UTC time.
+func fileTime(data []byte) time.Time {
+	ticks := int64(binary.LittleEndian.Uint64(data)) - ntfsEpochOffset
+	return time.Unix(0, ticks*100).UTC()
+}
+
+// UnixMode returns the Unix mode of a zip entry, including its file type and its setuid,
+// setgid, and sticky bits, reporting whether one was recorded. Zip tools running on Unix
+// hold the mode in the upper 16 bits of the entry's external attributes, which the upper
+// byte of its "version made by" field identifies as written by a Unix or macOS host;
+// 7-Zip and others record it in the same place on other hosts, flagging its presence
+// with bit 15 of the attributes. Entries created on other hosts, such as by Windows tools
+// that record only MS-DOS attributes, report no mode. The Info-ZIP Unix extra fields
+// carry the owner of an entry but not its mode, so they are not consulted.
+//
+// File.Mode differs in that it only decodes the mode for Unix and macOS hosts, and
+// otherwise derives a mode from the MS-DOS attributes, so that entries lose their
+// execute bits.
+func UnixMode(f *zip.File) (fs.FileMode, bool) {
+	host := f.CreatorVersion >> 8
+	if host != zipHostUnix && host != zipHostMacOS && f.ExternalAttrs&zipUnixModeFlag == 0 {
+		return 0, false
+	}
+
+	unixMode := f.ExternalAttrs >> 16
+	if unixMode == 0 {
+		return 0, false
+	}
+
+	typ, ok := unixFileTypes[unixMode&unixTypeMask]
+	if !ok {
+		typ = fs.ModeIrregular
+	}
+
+	mode := typ | fs.FileMode(unixMode&unixPermMask)
+	if unixMode&tarModeSetuid != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if unixMode&tarModeSetgid != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if unixMode&tarModeSticky != 0 {
+		mode |= fs.ModeSticky
+	}
+	if strings.HasSuffix(f.Name, "/") {
+		mode |= fs.ModeDir
+	}
+
+	return mode, true
+}
+
+// Returns the mode of a zip entry, preferring the Unix mode recorded by UnixMode.
+func zipFileMode(f *zip.File) fs.FileMode {
+	if mode, ok := UnixMode(f); ok {
+		return mode
+	}
+	return f.Mode()
+}
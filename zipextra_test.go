@@ -0,0 +1,268 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Builds a zip extra field record.
+func extraRecord(id uint16, data ...byte) []byte {
+	record := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint16(record[0:2], id)
+	binary.LittleEndian.PutUint16(record[2:4], uint16(len(data)))
+	return append(record, data...)
+}
+
+// Returns v encoded as little-endian bytes of the given width.
+func le(v uint64, width int) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b[:width]
+}
+
+var extraTime = time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+type extraModTimeTest struct {
+	extra    []byte
+	expected time.Time
+	found    bool
+}
+
+var extraModTimes = []extraModTimeTest{
+	{extraRecord(extTimeExtraID, append([]byte{0x1}, le(uint64(extraTime.Unix()), 4)...)...), extraTime, true},
+	{extraRecord(extTimeExtraID, append([]byte{0x2}, le(uint64(extraTime.Unix()), 4)...)...), time.Time{}, false},
+	{extraRecord(infoZipUnixExtraID, append(le(0, 4), le(uint64(extraTime.Unix()), 4)...)...), extraTime, true},
+	{extraRecord(unixExtraID, append(le(0, 4), le(uint64(extraTime.Unix()), 4)...)...), extraTime, true},
+	{
+		extraRecord(ntfsExtraID, bytes.Join([][]byte{
+			le(0, 4), le(uint64(ntfsTimeAttrTag), 2), le(ntfsTimeAttrSize, 2),
+			le(uint64(extraTime.UnixNano()/100+ntfsEpochOffset), 8), le(0, 8), le(0, 8),
+		}, nil)...),
+		extraTime,
+		true,
+	},
+	{append(extraRecord(0xcafe, 1, 2, 3), extraRecord(extTimeExtraID, append([]byte{0x1}, le(uint64(extraTime.Unix()), 4)...)...)...), extraTime, true},
+	{extraRecord(0xcafe, 1, 2, 3), time.Time{}, false},
+	{[]byte{0x55, 0x54, 0xff}, time.Time{}, false},
+	{nil, time.Time{}, false},
+}
+
+func TestEntryModTime(t *testing.T) {
+	dosTime := time.Date(2016, time.May, 12, 15, 7, 0, 0, time.UTC)
+
+	for _, c := range extraModTimes {
+		f := &zip.File{FileHeader: zip.FileHeader{Extra: c.extra, Modified: dosTime}}
+
+		expected := c.expected
+		if !c.found {
+			expected = time.Date(2016, time.May, 12, 15, 7, 0, 0, time.Local)
+		}
+
+		if result := EntryModTime(f); !result.Equal(expected) {
+			t.Errorf("Expecting '%s', got '%s'\n", expected, result)
+		}
+
+		if _, found := extraModTime(c.extra); found != c.found {
+			t.Errorf("Expecting '%t', got '%t'\n", c.found, found)
+		}
+	}
+}
+
+func TestEntryModTime_writer(t *testing.T) {
+	modified := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: "a.txt", Modified: modified}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := EntryModTime(r.File[0])
+	if !result.Equal(modified) || result.Location() != time.UTC {
+		t.Errorf("Expecting '%s', got '%s'\n", modified.UTC(), result)
+	}
+}
+
+func TestParseExtraFields(t *testing.T) {
+	extra := bytes.Join([][]byte{
+		extraRecord(extTimeExtraID, 0x1, 1, 2, 3, 4),
+		extraRecord(0xcafe),
+		extraRecord(unixOwnerExtraID, 1, 1, 0xe8, 1, 0x64),
+		extraRecord(extTimeExtraID, 0x1, 9, 9, 9, 9),
+	}, nil)
+
+	fields, err := ParseExtraFields(&zip.File{FileHeader: zip.FileHeader{Extra: extra}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	expected := map[uint16][]byte{
+		extTimeExtraID:   {0x1, 1, 2, 3, 4},
+		0xcafe:           {},
+		unixOwnerExtraID: {1, 1, 0xe8, 1, 0x64},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expecting '%v', got '%v'\n", expected, fields)
+	}
+
+	if fields, err := ParseExtraFields(&zip.File{}); len(fields) != 0 || err != nil {
+		t.Errorf("Expecting no fields, got '%v' (error: %v)\n", fields, err)
+	}
+
+	for _, malformed := range [][]byte{{0x55, 0x54, 0xff}, {0x55, 0x54, 0x05, 0x00, 0x1}} {
+		if _, err := ParseExtraFields(&zip.File{FileHeader: zip.FileHeader{Extra: malformed}}); err != errMalformedExtra {
+			t.Errorf("Expecting '%s', got '%v'\n", errMalformedExtra, err)
+		}
+	}
+}
+
+func TestParseExtraTimes(t *testing.T) {
+	access := extraTime.Add(time.Hour)
+	create := extraTime.Add(-time.Hour)
+	filetime := func(t time.Time) []byte { return le(uint64(t.UnixNano()/100+ntfsEpochOffset), 8) }
+	unix := func(t time.Time) []byte { return le(uint64(t.Unix()), 4) }
+	precise := extraTime.Add(123456700)
+
+	cases := []struct {
+		extra    []byte
+		expected ExtraTimes
+		found    bool
+	}{
+		{
+			extraRecord(extTimeExtraID, bytes.Join([][]byte{{0x7}, unix(extraTime), unix(access), unix(create)}, nil)...),
+			ExtraTimes{extraTime, access, create},
+			true,
+		},
+		{
+			extraRecord(extTimeExtraID, bytes.Join([][]byte{{0x7}, unix(extraTime)}, nil)...),
+			ExtraTimes{ModTime: extraTime},
+			true,
+		},
+		{
+			extraRecord(extTimeExtraID, bytes.Join([][]byte{{0x6}, unix(access), unix(create)}, nil)...),
+			ExtraTimes{AccessTime: access, CreateTime: create},
+			true,
+		},
+		{
+			append(
+				extraRecord(extTimeExtraID, bytes.Join([][]byte{{0x1}, unix(extraTime)}, nil)...),
+				extraRecord(ntfsExtraID, bytes.Join([][]byte{
+					le(0, 4), le(uint64(ntfsTimeAttrTag), 2), le(ntfsTimeAttrSize, 2),
+					filetime(precise), filetime(access), filetime(create),
+				}, nil)...)...,
+			),
+			ExtraTimes{precise, access, create},
+			true,
+		},
+		{extraRecord(extTimeExtraID), ExtraTimes{}, false},
+		{nil, ExtraTimes{}, false},
+	}
+
+	for _, c := range cases {
+		times, found := ParseExtraTimes(&zip.File{FileHeader: zip.FileHeader{Extra: c.extra}})
+		if found != c.found || !times.ModTime.Equal(c.expected.ModTime) ||
+			!times.AccessTime.Equal(c.expected.AccessTime) || !times.CreateTime.Equal(c.expected.CreateTime) {
+			t.Errorf("Expecting '%v' (%t), got '%v' (%t)\n", c.expected, c.found, times, found)
+		}
+	}
+}
+
+func TestParseExtraOwner(t *testing.T) {
+	cases := []struct {
+		extra    []byte
+		uid, gid int
+		found    bool
+	}{
+		{extraRecord(unixOwnerExtraID, 1, 4, 0xe8, 0x03, 0, 0, 4, 0x64, 0, 0, 0), 1000, 100, true},
+		{extraRecord(unixOwnerExtraID, 1, 1, 0, 1, 0), 0, 0, true},
+		{extraRecord(unixOwnerExtraID, 1, 4, 0xe8), 0, 0, false},
+		{extraRecord(infoZipUnixExtraID, append(le(0, 8), 0xe8, 0x03, 0x64, 0)...), 1000, 100, true},
+		{extraRecord(unixExtraID, append(le(0, 8), 0xf5, 0x01, 0x14, 0)...), 501, 20, true},
+		{extraRecord(infoZipUnixExtraID, le(0, 8)...), 0, 0, false},
+		{nil, 0, 0, false},
+	}
+
+	for _, c := range cases {
+		uid, gid, found := ParseExtraOwner(&zip.File{FileHeader: zip.FileHeader{Extra: c.extra}})
+		if uid != c.uid || gid != c.gid || found != c.found {
+			t.Errorf("Expecting '%d %d %t', got '%d %d %t'\n", c.uid, c.gid, c.found, uid, gid, found)
+		}
+	}
+}
+
+func TestUnixMode(t *testing.T) {
+	cases := []struct {
+		name          string
+		creator       uint16
+		externalAttrs uint32
+		expected      fs.FileMode
+		found         bool
+	}{
+		{"run.sh", zipHostUnix << 8, 0100755 << 16, 0755, true},
+		{"run.sh", zipHostMacOS << 8, 0104755 << 16, fs.ModeSetuid | 0755, true},
+		{"dir/", zipHostUnix << 8, 041777 << 16, fs.ModeDir | fs.ModeSticky | 0777, true},
+		{"dir/", zipHostUnix << 8, 0755 << 16, fs.ModeDir | 0755, true},
+		{"link", zipHostUnix << 8, 0120777 << 16, fs.ModeSymlink | 0777, true},
+		{"fifo", zipHostUnix << 8, 010644 << 16, fs.ModeNamedPipe | 0644, true},
+		{"run.sh", 0, 0100755<<16 | zipUnixModeFlag, 0755, true},
+		{"run.sh", 10 << 8, 0100755<<16 | zipUnixModeFlag | 0x20, 0755, true},
+		{"run.sh", 0, 0100755 << 16, 0, false},
+		{"run.sh", zipHostUnix << 8, 0, 0, false},
+		{"run.sh", 0, 0x20, 0, false},
+	}
+
+	for _, c := range cases {
+		f := &zip.File{FileHeader: zip.FileHeader{Name: c.name, CreatorVersion: c.creator, ExternalAttrs: c.externalAttrs}}
+		mode, found := UnixMode(f)
+		if mode != c.expected || found != c.found {
+			t.Errorf("Expecting '%s %t', got '%s %t'\n", c.expected, c.found, mode, found)
+		}
+	}
+}
+
+func TestUnixMode_extract(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	// A header as written by 7-Zip on Windows, whose Unix mode File.Mode ignores.
+	header := &zip.FileHeader{Name: "run.sh", Method: zip.Deflate, ExternalAttrs: 0100750<<16 | zipUnixModeFlag | 0x20}
+	fw, err := w.CreateHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("#!/bin/sh\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "sevenzip.zip")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if err := ExtractAll(archivePath, dest); err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Expecting '%s', got '%v' (error: %v)\n", fs.FileMode(0750), info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,300 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned by WalkZipLowMem when the central directory cannot be read.
+var (
+	errNoCentralDirectory  = errors.New("archive: zip end of central directory record not found")
+	errBadCentralDirectory = errors.New("archive: invalid zip central directory")
+)
+
+// Layout of the zip records read by WalkZipLowMem (APPNOTE.TXT, sections 4.3.12 to 4.3.16).
+const (
+	zipCentralHdrLen           = 46
+	zipCentralNameLenPos       = 28
+	zipCentralExtraLenPos      = 30
+	zipCentralCommentLenPos    = 32
+	zipCentralOffsetPos        = 42
+	zipCentralCompressedPos    = 20
+	zipCentralUncompressedPos  = 24
+	zip64EndOfCentralCountPos  = 32
+	zip64EndOfCentralRemaining = zip64EndOfCentralMinLen - 12
+)
+
+// WalkZipLowMem walks the contents of the zip archive of the given size read from r,
+// invoking the callback function for each entry as WalkZip does, but without holding
+// the archive's central directory in memory. The standard library's zip.Reader parses the
+// entire central directory up front, keeping a zip.File, with its name and extra field,
+// for every entry for as long as the reader is in use, so its memory use grows with the
+// number of entries; for archives of millions of entries, that can amount to gigabytes.
+// WalkZipLowMem instead reads the central directory sequentially, one record at a time,
+// and presents each entry to the callback as soon as its record has been read, so that
+// memory use stays constant however many entries the archive holds.
+//
+// This comes at some cost. Each entry is passed to the callback as the only entry of an
+// archive of its own, whose zip.File remains valid after the callback returns but is
+// unknown to any other; entries cannot be looked up by name or visited out of order, and
+// checks that span the central directory, such as that its record count is consistent
+// with its size, are not made. Constructing each entry's archive costs a few small
+// allocations and reads of r, which makes the walk somewhat slower than WalkZip for
+// archives small enough that memory is not a concern. Applications that need random
+// access or repeated walks should prefer zip.Reader.
+func WalkZipLowMem(r io.ReaderAt, size int64, callback ZipCallback) error {
+	dir, err := findCentralDirectory(r, size)
+	if err != nil {
+		return err
+	}
+
+	records := bufio.NewReader(io.NewSectionReader(r, dir.start, dir.size))
+	for range dir.count {
+		record, err := readCentralRecord(records)
+		if err != nil {
+			return err
+		}
+
+		f, err := dir.entry(r, record)
+		if err != nil {
+			return err
+		}
+
+		if err := readZipWrapping([]*zip.File{f}, callback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Struct centralDirectory locates the central directory of a zip archive.
+type centralDirectory struct {
+	start  int64  // offset of the central directory in the file
+	size   int64  // length of the central directory
+	count  uint64 // number of entries
+	offset int64  // offset of the central directory as recorded in the archive
+	base   int64  // offset of the archive within the file, such as past a self-extractor
+}
+
+// Returns the location of the central directory of the zip archive of the given size
+// read from r, found by way of its end of central directory record and, for a zip64
+// archive, the zip64 records. As zip.Reader does, the archive is taken to begin partway
+// through the file if the central directory precedes that record by more than the
+// offset it records.
+func findCentralDirectory(r io.ReaderAt, size int64) (*centralDirectory, error) {
+	le := binary.LittleEndian
+
+	tailStart := max(0, size-zipEndOfCentralLen-zipMaxCommentLen)
+	tail := make([]byte, size-tailStart)
+	if _, err := r.ReadAt(tail, tailStart); err != nil && err != io.EOF {
+		return nil, fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	i := len(tail) - zipEndOfCentralLen
+	for ; i >= 0; i-- {
+		if le.Uint32(tail[i:]) == zipEndOfCentralSig {
+			break
+		}
+	}
+	if i < 0 {
+		return nil, errNoCentralDirectory
+	}
+
+	eocd := tail[i:]
+	dirEnd := tailStart + int64(i)
+	dir := &centralDirectory{
+		size:   int64(le.Uint32(eocd[zipEndOfCentralSizePos:])),
+		count:  uint64(le.Uint16(eocd[zipEndOfCentralCountPos:])),
+		offset: int64(le.Uint32(eocd[zipEndOfCentralOffsetPos:])),
+	}
+
+	if dir.count == zipUint16Max || dir.size == int64(zipUint32Max) || dir.offset == int64(zipUint32Max) {
+		if locator := i - zip64EndOfCentralLocLen; locator >= 0 && le.Uint32(tail[locator:]) == zip64EndOfCentralLocSig {
+			offset := int64(le.Uint64(tail[locator+8:]))
+			record := make([]byte, zip64EndOfCentralMinLen)
+			if _, err := r.ReadAt(record, offset); err != nil || le.Uint32(record) != zip64EndOfCentralSig {
+				return nil, errBadCentralDirectory
+			}
+			dir.count = le.Uint64(record[zip64EndOfCentralCountPos:])
+			dir.size = int64(le.Uint64(record[zip64EndOfCentralSizePos:]))
+			dir.offset = int64(le.Uint64(record[zip64EndOfCentralOffsetPos:]))
+			dirEnd = offset
+		}
+	}
+
+	dir.base = dirEnd - dir.size - dir.offset
+	if dir.base > 0 {
+		var sig [4]byte
+		if _, err := r.ReadAt(sig[:], dir.offset); err == nil && le.Uint32(sig[:]) == zipCentralHeaderSig {
+			dir.base = 0
+		}
+	}
+
+	dir.start = dir.base + dir.offset
+	if dir.size < 0 || dir.offset < 0 || dir.base < 0 || dir.start+dir.size > size {
+		return nil, errBadCentralDirectory
+	}
+	return dir, nil
+}
+
+// Reads the next central directory header from reader, returning the header
+// together with the name, extra field, and comment that follow it.
+func readCentralRecord(reader *bufio.Reader) ([]byte, error) {
+	header := make([]byte, zipCentralHdrLen)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	le := binary.LittleEndian
+	if le.Uint32(header) != zipCentralHeaderSig {
+		return nil, errBadCentralDirectory
+	}
+
+	varLen := int(le.Uint16(header[zipCentralNameLenPos:])) +
+		int(le.Uint16(header[zipCentralExtraLenPos:])) +
+		int(le.Uint16(header[zipCentralCommentLenPos:]))
+	record := make([]byte, zipCentralHdrLen+varLen)
+	copy(record, header)
+	if _, err := io.ReadFull(reader, record[zipCentralHdrLen:]); err != nil {
+		return nil, fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	return record, nil
+}
+
+// Returns the entry described by a central directory record of the archive read
+// from r, read by way of an archive holding that entry alone.
+func (dir *centralDirectory) entry(r io.ReaderAt, record []byte) (*zip.File, error) {
+	view := &zipEntryView{
+		r:         r,
+		dataStart: dir.base + recordedHeaderOffset(record),
+		dirStart:  dir.start,
+		trailer:   zipEntryTrailer(record, dir.start, dir.offset),
+	}
+
+	zr, err := zip.NewReader(view, view.size())
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	return zr.File[0], nil
+}
+
+// Returns the offset of an entry's local file header recorded in its central
+// directory record, taken from the zip64 extra field if need be.
+func recordedHeaderOffset(record []byte) int64 {
+	le := binary.LittleEndian
+	offset := le.Uint32(record[zipCentralOffsetPos:])
+	if offset != zipUint32Max {
+		return int64(offset)
+	}
+
+	nameLen := int(le.Uint16(record[zipCentralNameLenPos:]))
+	extraLen := int(le.Uint16(record[zipCentralExtraLenPos:]))
+	extra := record[zipCentralHdrLen+nameLen : zipCentralHdrLen+nameLen+extraLen]
+
+	// The zip64 field holds only the values whose 32-bit fields are saturated,
+	// in the order uncompressed size, compressed size, and offset.
+	result := int64(-1)
+	eachExtraField(extra, func(id uint16, data []byte) bool {
+		if id != zip64ExtraID {
+			return true
+		}
+
+		for _, pos := range []int{zipCentralUncompressedPos, zipCentralCompressedPos} {
+			if le.Uint32(record[pos:]) == zipUint32Max && len(data) >= 8 {
+				data = data[8:]
+			}
+		}
+		if len(data) >= 8 {
+			result = int64(le.Uint64(data))
+		}
+		return false
+	})
+
+	return result
+}
+
+// Returns a central directory holding only the given record, to be placed at dirStart,
+// followed by zip64 end of central directory records and an end of central directory
+// record recording dirOffset as its offset within the archive. The zip64 records are
+// written whatever the size of the archive, so that offsets of any size can be recorded.
+func zipEntryTrailer(record []byte, dirStart, dirOffset int64) []byte {
+	le := binary.LittleEndian
+	trailer := make([]byte, len(record)+zip64EndOfCentralMinLen+zip64EndOfCentralLocLen+zipEndOfCentralLen)
+	copy(trailer, record)
+
+	zip64End := trailer[len(record):]
+	le.PutUint32(zip64End[0:], zip64EndOfCentralSig)
+	le.PutUint64(zip64End[4:], zip64EndOfCentralRemaining)
+	le.PutUint16(zip64End[12:], le.Uint16(record[4:]))
+	le.PutUint16(zip64End[14:], le.Uint16(record[6:]))
+	le.PutUint64(zip64End[24:], 1)
+	le.PutUint64(zip64End[zip64EndOfCentralCountPos:], 1)
+	le.PutUint64(zip64End[zip64EndOfCentralSizePos:], uint64(len(record)))
+	le.PutUint64(zip64End[zip64EndOfCentralOffsetPos:], uint64(dirOffset))
+
+	// Unlike the other offsets, the locator records an offset within the file.
+	locator := zip64End[zip64EndOfCentralMinLen:]
+	le.PutUint32(locator[0:], zip64EndOfCentralLocSig)
+	le.PutUint64(locator[8:], uint64(dirStart+int64(len(record))))
+	le.PutUint32(locator[16:], 1)
+
+	eocd := locator[zip64EndOfCentralLocLen:]
+	le.PutUint32(eocd[0:], zipEndOfCentralSig)
+	le.PutUint16(eocd[8:], zipUint16Max)
+	le.PutUint16(eocd[zipEndOfCentralCountPos:], zipUint16Max)
+	le.PutUint32(eocd[zipEndOfCentralSizePos:], zipUint32Max)
+	le.PutUint32(eocd[zipEndOfCentralOffsetPos:], zipUint32Max)
+
+	return trailer
+}
+
+// Struct zipEntryView presents a single entry of a zip archive as an archive of its own:
+// the archive's data up to its central directory, of which only the entry's local header
+// and contents are read, with the bytes preceding them reading as zeros, followed by a
+// trailer holding the entry's central directory record.
+type zipEntryView struct {
+	r         io.ReaderAt
+	dataStart int64 // offset of the entry's local file header
+	dirStart  int64 // offset of the central directory, where the trailer begins
+	trailer   []byte
+}
+
+// Returns the size of the view.
+func (v *zipEntryView) size() int64 {
+	return v.dirStart + int64(len(v.trailer))
+}
+
+// ReadAt reads len(p) bytes of the view starting at offset off.
+func (v *zipEntryView) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		switch {
+		case pos < 0:
+			return n, errBadCentralDirectory
+		case pos < min(v.dataStart, v.dirStart):
+			m := int(min(int64(len(p)-n), min(v.dataStart, v.dirStart)-pos))
+			clear(p[n : n+m])
+			n += m
+		case pos < v.dirStart:
+			m := int(min(int64(len(p)-n), v.dirStart-pos))
+			k, err := v.r.ReadAt(p[n:n+m], pos)
+			n += k
+			if k < m {
+				return n, err
+			}
+		case pos < v.size():
+			n += copy(p[n:], v.trailer[pos-v.dirStart:])
+		default:
+			return n, io.EOF
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns a walk of the zip archive at path by WalkZipLowMem, reading the archive
+// from memory with prefix prepended to it.
+func lowMemWalk(t *testing.T, path string, prefix []byte) func(ZipCallback) error {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(append([]byte{}, prefix...), data...)
+
+	return func(cb ZipCallback) error {
+		return WalkZipLowMem(bytes.NewReader(data), int64(len(data)), cb)
+	}
+}
+
+func TestWalkZipLowMem(t *testing.T) {
+	var entries []testEntry
+	for i := range 20 {
+		entries = append(entries, testEntry{name: fmt.Sprintf("dir%d/", i)})
+		entries = append(entries, testEntry{
+			name: fmt.Sprintf("dir%d/file%02d.txt", i, i),
+			body: strings.Repeat(fmt.Sprintf("line %d\n", i), i*100),
+		})
+	}
+	generated := writeTestArchive(t, "lowmem.zip", entries)
+
+	for _, path := range []string{"testdata/sample.zip", generated} {
+		expected := describeZipWalk(t, func(cb ZipCallback) error { return WalkZip(path, cb) })
+
+		// A prefix stands in for the executable of a self-extracting archive.
+		for _, prefix := range [][]byte{nil, bytes.Repeat([]byte{0xcc}, 3000)} {
+			actual := describeZipWalk(t, lowMemWalk(t, path, prefix))
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%s with %d-byte prefix: expecting '%v', got '%v'\n", path, len(prefix), expected, actual)
+			}
+		}
+	}
+
+	errStop := errors.New("stop")
+	visited := 0
+	err := lowMemWalk(t, generated, nil)(func(f *zip.File) error {
+		visited++
+		if visited == 5 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || visited != 5 {
+		t.Errorf("Expecting '%s' after 5 entries, got '%v' after %d\n", errStop, err, visited)
+	}
+}
+
+func TestWalkZipLowMem_invalid(t *testing.T) {
+	err := lowMemWalk(t, "testdata/sample.tar", nil)(nil)
+	if !errors.Is(err, errNoCentralDirectory) {
+		t.Errorf("Expecting '%s', got '%v'\n", errNoCentralDirectory, err)
+	}
+
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Overwrite the central directory offset recorded in the end of central directory record.
+	binary.LittleEndian.PutUint32(data[len(data)-zipEndOfCentralLen+zipEndOfCentralOffsetPos:], uint32(len(data)))
+	err = WalkZipLowMem(bytes.NewReader(data), int64(len(data)), nil)
+	if !errors.Is(err, errBadCentralDirectory) {
+		t.Errorf("Expecting '%s', got '%v'\n", errBadCentralDirectory, err)
+	}
+}
+
+func TestRecordedHeaderOffset(t *testing.T) {
+	record := func(compressed, uncompressed, offset uint32, extra []byte) []byte {
+		r := make([]byte, zipCentralHdrLen, zipCentralHdrLen+1+len(extra))
+		binary.LittleEndian.PutUint32(r[zipCentralCompressedPos:], compressed)
+		binary.LittleEndian.PutUint32(r[zipCentralUncompressedPos:], uncompressed)
+		binary.LittleEndian.PutUint32(r[zipCentralOffsetPos:], offset)
+		binary.LittleEndian.PutUint16(r[zipCentralNameLenPos:], 1)
+		binary.LittleEndian.PutUint16(r[zipCentralExtraLenPos:], uint16(len(extra)))
+		return append(append(r, 'a'), extra...)
+	}
+
+	cases := []struct {
+		record   []byte
+		expected int64
+	}{
+		{record(10, 20, 1234, nil), 1234},
+		{record(10, 20, zipUint32Max, extraRecord(zip64ExtraID, le(1<<33, 8)...)), 1 << 33},
+		{record(zipUint32Max, zipUint32Max, zipUint32Max, extraRecord(zip64ExtraID, bytes.Join([][]byte{le(1, 8), le(2, 8), le(1<<34, 8)}, nil)...)), 1 << 34},
+		{record(10, 20, zipUint32Max, nil), -1},
+	}
+
+	for _, c := range cases {
+		if offset := recordedHeaderOffset(c.record); offset != c.expected {
+			t.Errorf("Expecting '%d', got '%d'\n", c.expected, offset)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+)
+
+// Format string for errors decoding zip entry names
+const fmtErrDecodeName string = "archive: failed to decode entry name %q: %v"
+
+// zipFlagUTF8 is the general purpose bit flag indicating that an entry's name and
+// comment are encoded in UTF-8 (APPNOTE.TXT, section 4.4.4, bit 11).
+const zipFlagUTF8 = 0x800
+
+// utf8BOM is the UTF-8 encoding of the byte order mark, U+FEFF.
+const utf8BOM = "\xef\xbb\xbf"
+
+// errInvalidUTF8Name is returned by DecodeEntryName for a name that is not valid
+// UTF-8 when no fallback encoding is given.
+var errInvalidUTF8Name = errors.New("archive: entry name is not valid UTF-8")
+
+// DecodeEntryName returns the name of the zip entry f decoded to UTF-8. Zip archives
+// written by many Windows tools store names in the creator's OEM or ANSI code page, such
+// as CP437 or Shift-JIS, rather than in UTF-8; reading such names as UTF-8 produces
+// mojibake. The entry's UTF-8 flag (general purpose bit 11) decides how its name is
+// read: if the flag is set, the name is already UTF-8 and is returned as it is. A name
+// beginning with a UTF-8 byte order mark, which some tools write instead of setting the
+// flag, is likewise taken to be UTF-8 and returned without the mark. Otherwise, the name
+// is decoded using fallbackEncoding, such as charmap.CodePage437, the encoding the zip
+// specification prescribes, or japanese.ShiftJIS, from golang.org/x/text.
+//
+// If fallbackEncoding is nil, a name without the flag is returned as it is provided
+// that it is valid UTF-8, and otherwise an error is returned. File.Name itself is left
+// unchanged.
+func DecodeEntryName(f *zip.File, fallbackEncoding encoding.Encoding) (string, error) {
+	if f.Flags&zipFlagUTF8 != 0 {
+		return f.Name, nil
+	}
+	if name, ok := strings.CutPrefix(f.Name, utf8BOM); ok {
+		return name, nil
+	}
+
+	if fallbackEncoding == nil {
+		if !utf8.ValidString(f.Name) {
+			return "", fmt.Errorf("%w: %q", errInvalidUTF8Name, f.Name)
+		}
+		return f.Name, nil
+	}
+
+	name, err := fallbackEncoding.NewDecoder().String(f.Name)
+	if err != nil {
+		return "", fmt.Errorf(fmtErrDecodeName, f.Name, err)
+	}
+	return name, nil
+}
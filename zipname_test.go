@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Returns the entries of a zip archive holding an empty file for each of the given raw
+// names, with the UTF-8 flag set only for those that are flagged.
+func zipFilesNamed(t *testing.T, names []string, flagged []bool) []*zip.File {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, name := range names {
+		if _, err := zw.CreateHeader(&zip.FileHeader{Name: name, NonUTF8: !flagged[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.File
+}
+
+func TestDecodeEntryName(t *testing.T) {
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String("日本語.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		flagged  bool
+		fallback encoding.Encoding
+		expected string
+	}{
+		{"caf\x82.txt", false, charmap.CodePage437, "café.txt"},
+		{shiftJIS, false, japanese.ShiftJIS, "日本語.txt"},
+		{"café.txt", true, charmap.CodePage437, "café.txt"},
+		{"\xef\xbb\xbfcafé.txt", false, charmap.CodePage437, "café.txt"},
+		{"plain.txt", false, nil, "plain.txt"},
+		{"café.txt", false, nil, "café.txt"},
+	}
+
+	names := make([]string, len(tests))
+	flagged := make([]bool, len(tests))
+	for i, test := range tests {
+		names[i], flagged[i] = test.name, test.flagged
+	}
+	files := zipFilesNamed(t, names, flagged)
+
+	for i, test := range tests {
+		if flag := files[i].Flags&zipFlagUTF8 != 0; flag != test.flagged {
+			t.Fatalf("%q: expecting UTF-8 flag %t, got %t\n", test.name, test.flagged, flag)
+		}
+		if name, err := DecodeEntryName(files[i], test.fallback); name != test.expected || err != nil {
+			t.Errorf("Expecting '%s', got '%s' (error: %v)\n", test.expected, name, err)
+		}
+	}
+
+	files = zipFilesNamed(t, []string{"caf\x82.txt"}, []bool{false})
+	if _, err := DecodeEntryName(files[0], nil); !errors.Is(err, errInvalidUTF8Name) {
+		t.Errorf("Expecting '%s', got '%v'\n", errInvalidUTF8Name, err)
+	}
+}
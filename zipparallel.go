@@ -0,0 +1,195 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// errWalkCanceled is returned by the decompression of an entry abandoned because
+// WalkZipParallel stopped early.
+var errWalkCanceled = errors.New("archive: walk canceled")
+
+// WalkZipParallel walks the contents of a zip file, as WalkZip does, but decompresses up
+// to workers entries concurrently, ahead of the callback. Because a zip archive compresses
+// each entry separately, entries can be decompressed independently, which speeds up walks
+// whose callbacks read every entry. The callback is still invoked sequentially, in archive
+// order, so output derived from the walk, such as an inventory, remains deterministic.
+//
+// Each entry other than a directory is passed to the callback as a file whose contents
+// have already been decompressed and checked against the entry's CRC-32 checksum and are
+// held in memory; its header is that of the original entry, except that Method reports
+// zip.Store and CompressedSize64 the uncompressed size. Memory use therefore grows with
+// both workers and the size of the largest entries. An entry that fails to decompress
+// causes the walk to stop with an error once the entry's turn comes, and the first error
+// returned by the callback stops the walk and abandons any decompression under way. Calls
+// specifying fewer than two workers gain nothing from parallelism and are walked serially,
+// as by WalkZip.
+func WalkZipParallel(archivePath string, workers int, callback ZipCallback) error {
+	if workers < 2 {
+		return walkZipWrapping(archivePath, callback)
+	}
+
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	w := newParallelZipWalker(r.File, workers)
+	defer w.Close()
+
+	for {
+		f, err := w.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if callback != nil {
+			if err := callback(f); err != nil {
+				return fmt.Errorf(fmtErrZipCallbackFailed, err)
+			}
+		}
+	}
+}
+
+// Struct zipEntryResult holds the outcome of decompressing an entry.
+type zipEntryResult struct {
+	file *zip.File
+	err  error
+}
+
+// Struct parallelZipWalker yields the entries of a zip archive in order, decompressing
+// up to a fixed number of entries ahead of the caller concurrently.
+type parallelZipWalker struct {
+	files   []*zip.File
+	pending []chan zipEntryResult
+	done    chan struct{}
+	yielded int // index of the next entry to be yielded
+	started int // number of entries whose decompression has been started
+	wg      sync.WaitGroup
+}
+
+// Returns a walker over files, with decompression of the first workers entries
+// already under way.
+func newParallelZipWalker(files []*zip.File, workers int) *parallelZipWalker {
+	w := &parallelZipWalker{
+		files:   files,
+		pending: make([]chan zipEntryResult, len(files)),
+		done:    make(chan struct{}),
+	}
+
+	for w.started < workers && w.started < len(files) {
+		w.start()
+	}
+
+	return w
+}
+
+// Starts decompressing the next unstarted entry.
+func (w *parallelZipWalker) start() {
+	i := w.started
+	w.started++
+
+	// The channel is buffered so that the goroutine never blocks on send,
+	// even if the walker is closed before the result is received.
+	w.pending[i] = make(chan zipEntryResult, 1)
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		f, err := decompressZipEntry(w.files[i], w.done)
+		w.pending[i] <- zipEntryResult{file: f, err: err}
+	}()
+}
+
+// Returns the next entry in archive order, waiting for its decompression to finish,
+// or io.EOF once every entry has been returned.
+func (w *parallelZipWalker) next() (*zip.File, error) {
+	if w.yielded >= len(w.files) {
+		return nil, io.EOF
+	}
+
+	result := <-w.pending[w.yielded]
+	w.pending[w.yielded] = nil
+	w.yielded++
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	if w.started < len(w.files) {
+		w.start()
+	}
+	return result.file, nil
+}
+
+// Close abandons any decompression under way and waits for it to stop.
+func (w *parallelZipWalker) Close() error {
+	close(w.done)
+	w.wg.Wait()
+	return nil
+}
+
+// Returns a copy of the entry f whose contents have been decompressed into memory and
+// stored uncompressed, giving up early if done is closed.
+func decompressZipEntry(f *zip.File, done <-chan struct{}) (*zip.File, error) {
+	if zipFileMode(f).IsDir() {
+		return f, nil
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	defer reader.Close()
+
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, &cancelableReader{reader: reader, done: done}); err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+
+	header := f.FileHeader
+	header.Method = zip.Store
+	header.CompressedSize64 = uint64(data.Len())
+	header.UncompressedSize64 = uint64(data.Len())
+
+	var stored bytes.Buffer
+	writer := zip.NewWriter(&stored)
+	entry, err := writer.CreateRaw(&header)
+	if err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	if _, err := entry.Write(data.Bytes()); err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(stored.Bytes()), int64(stored.Len()))
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return nil, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	return r.File[0], nil
+}
+
+// Struct cancelableReader reads from an underlying reader until done is closed,
+// after which every read fails with errWalkCanceled.
+type cancelableReader struct {
+	reader io.Reader
+	done   <-chan struct{}
+}
+
+// Read reads from the underlying reader unless done has been closed.
+func (r *cancelableReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.done:
+		return 0, errWalkCanceled
+	default:
+		return r.reader.Read(p)
+	}
+}
@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns a description of each entry of a zip archive visited by walk, in order.
+func describeZipWalk(t *testing.T, walk func(ZipCallback) error) []string {
+	t.Helper()
+
+	var descriptions []string
+	err := walk(func(f *zip.File) error {
+		content := ""
+		if !f.Mode().IsDir() {
+			reader, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			content = string(data)
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("%s %s %s %q", f.Name, f.Mode(), f.Modified.UTC(), content))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	return descriptions
+}
+
+func TestWalkZipParallel(t *testing.T) {
+	var entries []testEntry
+	for i := range 50 {
+		entries = append(entries, testEntry{name: fmt.Sprintf("dir%d/", i%3)})
+		entries = append(entries, testEntry{
+			name: fmt.Sprintf("dir%d/file%02d.txt", i%3, i),
+			body: strings.Repeat(fmt.Sprintf("line %d\n", i), i*100),
+		})
+	}
+	generated := writeTestArchive(t, "parallel.zip", entries)
+
+	for _, path := range []string{"testdata/sample.zip", generated} {
+		expected := describeZipWalk(t, func(cb ZipCallback) error { return WalkZip(path, cb) })
+
+		for _, workers := range []int{0, 1, 2, 8} {
+			actual := describeZipWalk(t, func(cb ZipCallback) error { return WalkZipParallel(path, workers, cb) })
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%s with %d workers: expecting '%v', got '%v'\n", path, workers, expected, actual)
+			}
+		}
+	}
+
+	errStop := errors.New("stop")
+	for _, workers := range []int{1, 4} {
+		err := WalkZipParallel(generated, workers, func(f *zip.File) error { return errStop })
+		if !errors.Is(err, errStop) {
+			t.Errorf("%d workers: expecting '%s', got '%v'\n", workers, errStop, err)
+		}
+	}
+
+	visited := 0
+	err := WalkZipParallel(generated, 4, func(f *zip.File) error {
+		visited++
+		if visited == 5 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || visited != 5 {
+		t.Errorf("Expecting '%s' after 5 entries, got '%v' after %d\n", errStop, err, visited)
+	}
+
+	r, err := zip.OpenReader(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset, err := r.File[21].DataOffset()
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offset+1] ^= 0xff
+	corrupt := filepath.Join(t.TempDir(), "corrupt.zip")
+	if err := os.WriteFile(corrupt, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	visited = 0
+	err = WalkZipParallel(corrupt, 4, func(f *zip.File) error {
+		visited++
+		return nil
+	})
+	if err == nil || visited != 21 {
+		t.Errorf("Failed to receive non-nil error after 21 entries for a corrupt entry (visited %d).\n", visited)
+	}
+
+	if err := WalkZipParallel("nonexistent.zip", 4, nil); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent archive.")
+	}
+}
@@ -0,0 +1,153 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// errLocalHeaderNotFound is returned by WalkZipRaw when the local file header
+// of an entry cannot be located.
+var errLocalHeaderNotFound = errors.New("archive: zip local file header not found")
+
+// Bounds on the length of a local file header, excluding the entry's name: the
+// fixed-length portion, including its signature, and the longest possible extra field.
+const (
+	zipLocalHeaderFixedLen = 4 + zipLocalHdrLen
+	zipMaxExtraLen         = 0xffff
+)
+
+// zipLocalHeaderWindow is the number of bytes preceding an entry's data that are first
+// searched for its local file header, which suffices unless the local extra field is
+// unusually long.
+const zipLocalHeaderWindow = 1024
+
+// ZipRawCallback is the type of function called for each file or directory entry
+// visited by WalkZipRaw.
+type ZipRawCallback func(f *zip.File, localHeaderOffset int64) error
+
+// WalkZipRaw walks the contents of a zip file and invokes the callback function for each
+// entry, supplying the offset from the start of the file of the entry's local file
+// header along with the entry itself. File.DataOffset reports where an entry's compressed
+// data begins but archive/zip does not expose where its local header begins, which tools
+// that repair or repackage archives need in order to copy entries, headers and compressed
+// data alike, without recompressing them. The offset is found by locating, immediately
+// before the entry's data, the local header whose recorded name and extra field lengths
+// account exactly for the bytes between it and the data, and whose name matches the
+// entry's. Offsets account for any data prepended to the archive, as in self-extracting
+// archives. A non-nil error is returned if an entry's local header cannot be located.
+func WalkZipRaw(archivePath string, callback ZipRawCallback) error {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	r, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	for _, f := range r.File {
+		offset, err := localHeaderOffset(file, f)
+		if err != nil {
+			return err
+		}
+
+		if callback != nil {
+			if err := callback(f, offset); err != nil {
+				return fmt.Errorf(fmtErrZipCallbackFailed, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the offset of the local file header of f within r, searching a short window
+// before the entry's data and then, if necessary, the longest window a header could span.
+func localHeaderOffset(r io.ReaderAt, f *zip.File) (int64, error) {
+	dataOffset, err := f.DataOffset()
+	if err != nil {
+		return 0, fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+
+	minLen := int64(zipLocalHeaderFixedLen + len(f.Name))
+	for _, window := range []int64{minLen + zipLocalHeaderWindow, minLen + zipMaxExtraLen} {
+		start := max(0, dataOffset-window)
+		buf := make([]byte, dataOffset-start)
+		if _, err := r.ReadAt(buf, start); err != nil {
+			return 0, fmt.Errorf(fmtErrZipOpenFile, err)
+		}
+
+		if i := findLocalHeader(buf, f.Name); i >= 0 {
+			return start + int64(i), nil
+		}
+		if start == 0 {
+			break
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", errLocalHeaderNotFound, f.Name)
+}
+
+// Returns the index within buf of the local file header for the entry named name whose
+// data immediately follows buf, or -1 if there is none. Shorter extra fields are tried
+// first, so the header nearest the data is found.
+func findLocalHeader(buf []byte, name string) int {
+	for extraLen := 0; extraLen <= zipMaxExtraLen; extraLen++ {
+		i := len(buf) - zipLocalHeaderFixedLen - len(name) - extraLen
+		if i < 0 {
+			break
+		}
+
+		header := buf[i:]
+		if binary.LittleEndian.Uint32(header) == zipLocalHeaderSig &&
+			int(binary.LittleEndian.Uint16(header[26:])) == len(name) &&
+			int(binary.LittleEndian.Uint16(header[28:])) == extraLen &&
+			string(header[zipLocalHeaderFixedLen:zipLocalHeaderFixedLen+len(name)]) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// OpenRawEntry returns a reader over the raw, still-compressed contents of the entry named
+// entryName in the zip archive at archivePath, along with the compression method with
+// which they were compressed, such as zip.Store or zip.Deflate. Together with
+// zip.Writer.CreateRaw, this lets entries be copied between archives byte for byte,
+// without the cost of decompressing and recompressing them. The contents are neither
+// decompressed nor checked against the entry's CRC-32 checksum. Closing the returned
+// reader closes the archive. Only zip archives, which compress each entry separately,
+// are supported; a non-nil error is returned for any other type of archive. If the
+// archive contains no such entry, ErrEntryNotFound is returned.
+func OpenRawEntry(archivePath, entryName string) (io.ReadCloser, uint16, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	for _, f := range r.File {
+		if f.Name != entryName {
+			continue
+		}
+
+		raw, err := f.OpenRaw()
+		if err != nil {
+			r.Close()
+			return nil, 0, fmt.Errorf(fmtErrZipOpenFile, err)
+		}
+		return &multiReadCloser{Reader: raw, closers: []io.Closer{r}}, f.Method, nil
+	}
+
+	r.Close()
+	return nil, 0, ErrEntryNotFound
+}
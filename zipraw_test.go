@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Walks the zip archive at path with WalkZipRaw, verifying that each reported offset
+// locates the entry's local header, and returns the offsets.
+func checkZipRawOffsets(t *testing.T, path string) []int64 {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var offsets []int64
+	err = WalkZipRaw(path, func(f *zip.File, offset int64) error {
+		offsets = append(offsets, offset)
+
+		header := data[offset:]
+		if sig := binary.LittleEndian.Uint32(header); sig != zipLocalHeaderSig {
+			t.Errorf("%s: expecting signature '%x', got '%x'\n", f.Name, zipLocalHeaderSig, sig)
+		}
+
+		nameLen := int64(binary.LittleEndian.Uint16(header[26:]))
+		extraLen := int64(binary.LittleEndian.Uint16(header[28:]))
+		dataOffset, err := f.DataOffset()
+		if err != nil {
+			return err
+		}
+		if expected := offset + zipLocalHeaderFixedLen + nameLen + extraLen; dataOffset != expected {
+			t.Errorf("%s: expecting '%d', got '%d'\n", f.Name, expected, dataOffset)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error for %s: %v\n", path, err)
+	}
+	return offsets
+}
+
+func TestWalkZipRaw(t *testing.T) {
+	offsets := checkZipRawOffsets(t, "testdata/sample.zip")
+	if len(offsets) != 3 || offsets[0] != 0 {
+		t.Errorf("Unexpected offsets: %v\n", offsets)
+	}
+
+	// Extra fields lengthen local headers, and one longer than the initial search
+	// window requires the search to be widened.
+	path := filepath.Join(t.TempDir(), "extra.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(file)
+	for i, extraLen := range []int{0, 16, zipLocalHeaderWindow + 100} {
+		extra := binary.LittleEndian.AppendUint16(nil, 0xcafe)
+		extra = binary.LittleEndian.AppendUint16(extra, uint16(extraLen))
+		extra = append(extra, bytes.Repeat([]byte{'P', 'K'}, extraLen/2)...)
+
+		header := &zip.FileHeader{Name: strings.Repeat("n", i+1), Method: zip.Deflate, Extra: extra}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte("lorem ipsum")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if offsets := checkZipRawOffsets(t, path); len(offsets) != 3 {
+		t.Errorf("Unexpected offsets: %v\n", offsets)
+	}
+
+	// Offsets account for data prepended to the archive.
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte("#!/bin/sh\nexec unzip \"$0\"\n")
+	prefixed := filepath.Join(t.TempDir(), "sfx.zip")
+	if err := os.WriteFile(prefixed, append(prefix, data...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	shifted := checkZipRawOffsets(t, prefixed)
+	for i := range shifted {
+		if shifted[i] != offsets[i]+int64(len(prefix)) {
+			t.Errorf("Expecting '%d', got '%d'\n", offsets[i]+int64(len(prefix)), shifted[i])
+		}
+	}
+}
+
+func TestWalkZipRaw_errors(t *testing.T) {
+	err := WalkZipRaw("testdata/sample.zip", func(f *zip.File, offset int64) error {
+		return errors.New("an error in callback processing")
+	})
+	if err == nil {
+		t.Error("Failed to return error from callback.")
+	}
+
+	// A local header whose name differs from the central directory's is not found.
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[zipLocalHeaderFixedLen] ^= 0x20
+	path := filepath.Join(t.TempDir(), "renamed.zip")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkZipRaw(path, nil); !errors.Is(err, errLocalHeaderNotFound) {
+		t.Errorf("Expecting '%s', got '%v'\n", errLocalHeaderNotFound, err)
+	}
+
+	if err := WalkZipRaw("testdata/sample.tar", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a tar file in WalkZipRaw.")
+	}
+
+	if err := WalkZipRaw("nonexistent.zip", nil); err == nil {
+		t.Error("Failed to receive non-nil error when walking a nonexistent zip file.")
+	}
+}
+
+func TestOpenRawEntry(t *testing.T) {
+	entries := []testEntry{
+		{name: "dir/"},
+		{name: "dir/lorem.txt", body: strings.Repeat("lorem ipsum ", 100)},
+	}
+	path := writeTestArchive(t, "raw.zip", entries)
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Copies each entry raw into a new archive and verifies that its contents survive.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range r.File {
+		raw, method, err := OpenRawEntry(path, f.Name)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\n", f.Name, err)
+		}
+		if method != f.Method {
+			t.Errorf("Expecting '%d', got '%d'\n", f.Method, method)
+		}
+
+		fw, err := zw.CreateRaw(&f.FileHeader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(fw, raw); err != nil {
+			t.Fatal(err)
+		}
+		raw.Close()
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range copied.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Errorf("Unexpected error reading %s: %v\n", f.Name, err)
+		}
+		if string(body) != entries[i].body {
+			t.Errorf("Expecting '%s', got '%s'\n", entries[i].body, body)
+		}
+	}
+
+	if _, _, err := OpenRawEntry(path, "missing.txt"); err != ErrEntryNotFound {
+		t.Errorf("Expecting '%s', got '%v'\n", ErrEntryNotFound, err)
+	}
+
+	if _, _, err := OpenRawEntry("testdata/sample.tar.gz", sampleFileName); err == nil {
+		t.Error("Failed to receive non-nil error when opening an entry of a tar.gz file.")
+	}
+}
@@ -0,0 +1,286 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// Format strings for zip stream errors
+const (
+	fmtErrZipSpool string = "archive: failed to buffer zip stream to temporary file: %w"
+)
+
+// Errors returned while walking a zip stream.
+var (
+	errZipStreamFormat      = errors.New("archive: invalid zip stream")
+	errZipStreamChecksum    = errors.New("archive: zip entry checksum mismatch")
+	errZipStreamEncrypted   = errors.New("archive: encrypted zip entries are not supported")
+	errZipStreamMethod      = errors.New("archive: unsupported zip compression method")
+	errZipStreamStoredDescr = errors.New("archive: stored zip entries with data descriptors cannot be streamed")
+)
+
+// Zip record signatures and flags (APPNOTE.TXT, sections 4.3 and 4.4).
+const (
+	zipLocalHeaderSig    uint32 = 0x04034b50
+	zipCentralHeaderSig  uint32 = 0x02014b50
+	zipEndOfCentralSig   uint32 = 0x06054b50
+	zip64EndOfCentralSig uint32 = 0x06064b50
+	zipDataDescriptorSig uint32 = 0x08074b50
+
+	zipFlagEncrypted      uint16 = 0x1
+	zipFlagDataDescriptor uint16 = 0x8
+
+	zip64ExtraID   uint16 = 0x0001
+	zipUint32Max   uint32 = 0xffffffff
+	zipLocalHdrLen        = 26
+)
+
+// ZipStreamCallback is the type of function called for each file or directory entry
+// visited by WalkZipStream. The reader yields the entry's decompressed contents.
+type ZipStreamCallback func(*zip.FileHeader, io.Reader) error
+
+// WalkZipStream walks the contents of a zip archive read sequentially from r and
+// invokes the callback function for each entry. Unlike WalkZip, the archive need not
+// be seekable or fully buffered: entries are parsed from their local file headers as
+// they arrive, and the walk ends upon reaching the central directory.
+//
+// Entries written in a streaming manner record their sizes and CRC-32 in a data
+// descriptor following the entry's data rather than in the local header. For such
+// entries, the CRC32 and size fields of the header passed to the callback are zero;
+// the values from the data descriptor are verified once the entry has been consumed.
+// Because the end of a stored (uncompressed) entry cannot be located without its size,
+// stored entries that use a data descriptor are not supported. Only the Store and
+// Deflate compression methods are supported.
+func WalkZipStream(r io.Reader, callback ZipStreamCallback) error {
+	reader := bufio.NewReader(r)
+
+	for {
+		var sig uint32
+		if err := binary.Read(reader, binary.LittleEndian, &sig); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(fmtErrZipReadFailed, err)
+		}
+
+		switch sig {
+		case zipLocalHeaderSig:
+		case zipCentralHeaderSig, zipEndOfCentralSig, zip64EndOfCentralSig:
+			return nil
+		default:
+			return errZipStreamFormat
+		}
+
+		if err := readZipStreamEntry(reader, callback); err != nil {
+			return err
+		}
+	}
+}
+
+// WalkZipStreamBuffered walks the contents of a zip archive read from r, which need not
+// support seeking, and invokes the callback function for each entry. Unlike WalkZipStream,
+// which reads entries from their local headers as they arrive, the stream is first copied
+// in its entirety to a temporary file created by os.CreateTemp, and the archive is then
+// walked by way of its central directory as WalkZip walks a file. This handles archives
+// from sources such as standard input or an HTTP response body that are too large to be
+// held in memory, as well as those that WalkZipStream cannot read, at the cost of the disk
+// space needed to hold them. The temporary file is removed before WalkZipStreamBuffered
+// returns, whether or not an error occurs.
+func WalkZipStreamBuffered(r io.Reader, callback ZipCallback) error {
+	spool, err := os.CreateTemp("", "archive-*.zip")
+	if err != nil {
+		return fmt.Errorf(fmtErrZipSpool, err)
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	size, err := io.Copy(spool, r)
+	if err != nil {
+		return fmt.Errorf(fmtErrZipSpool, err)
+	}
+
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+
+	return readZipWrapping(zr.File, callback)
+}
+
+// Reads one entry, starting just after its local file header signature, and
+// invokes the callback for it.
+func readZipStreamEntry(reader *bufio.Reader, callback ZipStreamCallback) error {
+	buf := make([]byte, zipLocalHdrLen)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	le := binary.LittleEndian
+	header := &zip.FileHeader{
+		ReaderVersion:      le.Uint16(buf[0:2]),
+		Flags:              le.Uint16(buf[2:4]),
+		Method:             le.Uint16(buf[4:6]),
+		Modified:           msDosTimeToTime(le.Uint16(buf[8:10]), le.Uint16(buf[6:8])),
+		CRC32:              le.Uint32(buf[10:14]),
+		CompressedSize64:   uint64(le.Uint32(buf[14:18])),
+		UncompressedSize64: uint64(le.Uint32(buf[18:22])),
+	}
+
+	nameLen := int(le.Uint16(buf[22:24]))
+	nameAndExtra := make([]byte, nameLen+int(le.Uint16(buf[24:26])))
+	if _, err := io.ReadFull(reader, nameAndExtra); err != nil {
+		return fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+	header.Name = string(nameAndExtra[:nameLen])
+	header.Extra = nameAndExtra[nameLen:]
+
+	isZip64 := applyZip64Extra(header, uint32(header.CompressedSize64), uint32(header.UncompressedSize64))
+	hasDescriptor := header.Flags&zipFlagDataDescriptor != 0
+
+	if header.Flags&zipFlagEncrypted != 0 {
+		return errZipStreamEncrypted
+	}
+
+	var content io.Reader
+	switch header.Method {
+	case zip.Store:
+		if hasDescriptor {
+			return errZipStreamStoredDescr
+		}
+		content = io.LimitReader(reader, int64(header.CompressedSize64))
+	case zip.Deflate:
+		// bufio.Reader implements io.ByteReader, so the decompressor will not
+		// read beyond the end of the compressed data.
+		decompressor := flate.NewReader(reader)
+		defer decompressor.Close()
+		content = decompressor
+	default:
+		return errZipStreamMethod
+	}
+
+	checksum := crc32.NewIEEE()
+	counter := &countingReader{reader: io.TeeReader(content, checksum)}
+
+	if callback != nil {
+		if err := callback(header, counter); err != nil {
+			return fmt.Errorf(fmtErrZipCallbackFailed, err)
+		}
+	}
+
+	if _, err := io.Copy(io.Discard, counter); err != nil {
+		return fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	crc, size := header.CRC32, header.UncompressedSize64
+	if hasDescriptor {
+		var err error
+		if crc, size, err = readDataDescriptor(reader, isZip64); err != nil {
+			return err
+		}
+	}
+
+	return verifyZipStreamEntry(checksum, counter.n, crc, size)
+}
+
+// Replaces 32-bit size placeholders in header with the values from a zip64 extended
+// information extra field, if present. Reports whether such a field was found.
+func applyZip64Extra(header *zip.FileHeader, compressed, uncompressed uint32) (found bool) {
+	eachExtraField(header.Extra, func(id uint16, data []byte) bool {
+		if id != zip64ExtraID {
+			return true
+		}
+
+		if uncompressed == zipUint32Max && len(data) >= 8 {
+			header.UncompressedSize64 = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		}
+		if compressed == zipUint32Max && len(data) >= 8 {
+			header.CompressedSize64 = binary.LittleEndian.Uint64(data)
+		}
+
+		found = true
+		return false
+	})
+
+	return found
+}
+
+// Reads a data descriptor, which may or may not begin with its optional signature,
+// returning the CRC-32 and uncompressed size it records.
+func readDataDescriptor(reader *bufio.Reader, isZip64 bool) (crc uint32, size uint64, err error) {
+	le := binary.LittleEndian
+
+	peek, err := reader.Peek(4)
+	if err != nil {
+		return 0, 0, fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+	if le.Uint32(peek) == zipDataDescriptorSig {
+		if _, err := reader.Discard(4); err != nil {
+			return 0, 0, fmt.Errorf(fmtErrZipReadFailed, err)
+		}
+	}
+
+	sizeLen := 4
+	if isZip64 {
+		sizeLen = 8
+	}
+
+	buf := make([]byte, 4+2*sizeLen)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return 0, 0, fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+
+	crc = le.Uint32(buf[0:4])
+	if isZip64 {
+		size = le.Uint64(buf[12:20])
+	} else {
+		size = uint64(le.Uint32(buf[8:12]))
+	}
+
+	return crc, size, nil
+}
+
+// Verifies the CRC-32 and length of the data read for an entry.
+func verifyZipStreamEntry(checksum hash.Hash32, n int64, crc uint32, size uint64) error {
+	if checksum.Sum32() != crc || uint64(n) != size {
+		return errZipStreamChecksum
+	}
+	return nil
+}
+
+// Converts an MS-DOS date and time into a time.Time in UTC.
+func msDosTimeToTime(dosDate, dosTime uint16) time.Time {
+	return time.Date(
+		int(dosDate>>9+1980),
+		time.Month(dosDate>>5&0xf),
+		int(dosDate&0x1f),
+		int(dosTime>>11),
+		int(dosTime>>5&0x3f),
+		int(dosTime&0x1f*2),
+		0,
+		time.UTC,
+	)
+}
+
+// Struct countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read reads from the underlying reader, adding to the count of bytes read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
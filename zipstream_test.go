@@ -0,0 +1,194 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+var zipStreamEntries = []testEntry{
+	{name: "dir/"},
+	{name: "dir/a.txt", body: "lorem ipsum dolor sit amet"},
+	{name: "dir/empty.txt"},
+	{name: "b.txt", body: "consectetur adipiscing elit"},
+}
+
+func TestWalkZipStream(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestZip(t, &buf, zipStreamEntries)
+
+	visited := 0
+	err := WalkZipStream(&buf, func(header *zip.FileHeader, reader io.Reader) error {
+		expected := zipStreamEntries[visited]
+		visited++
+
+		if header.Name != expected.name {
+			t.Errorf("Expecting '%s', got '%s'\n", expected.name, header.Name)
+		}
+
+		modTime := time.Date(2016, time.May, 12, 15, 7, 0, 0, time.UTC)
+		if !header.Modified.Equal(modTime) {
+			t.Errorf("Expecting '%s', got '%s'\n", modTime, header.Modified)
+		}
+
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if string(body) != expected.body {
+			t.Errorf("Expecting '%s', got '%s'\n", expected.body, body)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if visited != len(zipStreamEntries) {
+		t.Errorf("Expecting '%d', got '%d'\n", len(zipStreamEntries), visited)
+	}
+}
+
+func TestWalkZipStream_sample(t *testing.T) {
+	file, err := os.Open("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var names []string
+	err = WalkZipStream(file, func(header *zip.FileHeader, reader io.Reader) error {
+		names = append(names, header.Name)
+		if header.Name == sampleFileName && header.UncompressedSize64 != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, header.UncompressedSize64)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if len(names) != 3 || names[2] != sampleFileName {
+		t.Errorf("Unexpected entries: %v\n", names)
+	}
+}
+
+func TestWalkZipStream_errors(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestZip(t, &buf, zipStreamEntries)
+
+	err := WalkZipStream(bytes.NewReader(buf.Bytes()), func(header *zip.FileHeader, reader io.Reader) error {
+		return errors.New("an error in callback processing")
+	})
+	if err == nil {
+		t.Error("Failed to return error from callback.")
+	}
+
+	corrupt := append([]byte(nil), buf.Bytes()...)
+	corrupt[0] = 'X'
+	if err := WalkZipStream(bytes.NewReader(corrupt), nil); err != errZipStreamFormat {
+		t.Errorf("Expecting '%s', got '%v'\n", errZipStreamFormat, err)
+	}
+
+	buf.Reset()
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "stored.txt", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("lorem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WalkZipStream(&buf, nil); err != errZipStreamStoredDescr {
+		t.Errorf("Expecting '%s', got '%v'\n", errZipStreamStoredDescr, err)
+	}
+
+	if err := WalkZipStream(bytes.NewReader(nil), nil); err != nil {
+		t.Errorf("Unexpected error for empty stream: %v\n", err)
+	}
+}
+
+func TestWalkZipStreamBuffered(t *testing.T) {
+	spoolDir := t.TempDir()
+	t.Setenv("TMPDIR", spoolDir)
+
+	// Verifies that no temporary file has been left behind.
+	checkRemoved := func() {
+		t.Helper()
+		if entries, _ := os.ReadDir(spoolDir); len(entries) != 0 {
+			t.Errorf("Expected temporary files to be removed, got %d\n", len(entries))
+		}
+	}
+
+	data, err := os.ReadFile("testdata/sample.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A multi-reader hides the bytes.Reader's ability to seek.
+	var names []string
+	err = WalkZipStreamBuffered(io.MultiReader(bytes.NewReader(data)), func(f *zip.File) error {
+		names = append(names, f.Name)
+		if f.Name == sampleFileName && f.UncompressedSize64 != sampleFileSize {
+			t.Errorf("Expecting '%d', got '%d'\n", sampleFileSize, f.UncompressedSize64)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	if len(names) != 3 || names[2] != sampleFileName {
+		t.Errorf("Unexpected entries: %v\n", names)
+	}
+	checkRemoved()
+
+	// Stored entries with data descriptors, which WalkZipStream rejects, are readable.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "stored.txt", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("lorem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = WalkZipStreamBuffered(&buf, func(f *zip.File) error {
+		if f.Name != "stored.txt" {
+			t.Errorf("Expecting '%s', got '%s'\n", "stored.txt", f.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+	checkRemoved()
+
+	err = WalkZipStreamBuffered(bytes.NewReader(data), func(f *zip.File) error {
+		return errors.New("an error in callback processing")
+	})
+	if err == nil {
+		t.Error("Failed to return error from callback.")
+	}
+	checkRemoved()
+
+	if err := WalkZipStreamBuffered(bytes.NewReader([]byte("not a zip archive")), nil); err == nil {
+		t.Error("Failed to receive non-nil error for an invalid zip stream.")
+	}
+	checkRemoved()
+
+	if err := WalkZipStreamBuffered(iotest.ErrReader(io.ErrClosedPipe), nil); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expecting '%s', got '%v'\n", io.ErrClosedPipe, err)
+	}
+	checkRemoved()
+}
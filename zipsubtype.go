@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Maximum number of bytes read from the mimetype entry of an OpenDocument or EPUB file.
+const maxMimetypeLen = 256
+
+// Subtypes of zip container recognised from the contents of the mimetype entry.
+var mimetypeSubtypes = map[string]string{
+	"application/epub+zip":                            "epub",
+	"application/vnd.oasis.opendocument.text":         "odt",
+	"application/vnd.oasis.opendocument.spreadsheet":  "ods",
+	"application/vnd.oasis.opendocument.presentation": "odp",
+	"application/vnd.oasis.opendocument.graphics":     "odg",
+}
+
+// Subtypes of Office Open XML document recognised from the directory holding the
+// document's main part.
+var ooxmlSubtypes = []struct {
+	prefix  string
+	subtype string
+}{
+	{"word/", "docx"},
+	{"xl/", "xlsx"},
+	{"ppt/", "pptx"},
+}
+
+// ZipSubtype refines the type of the zip archive at path by inspecting the entries that
+// identify common zip-based container formats. It returns "epub", "odt", "ods", "odp", or
+// "odg" for files whose mimetype entry names the corresponding media type; "docx", "xlsx",
+// or "pptx" for Office Open XML documents, which carry a [Content_Types].xml entry; "apk"
+// for Android packages, which carry AndroidManifest.xml and classes.dex entries; "war" for
+// web application archives, which carry a WEB-INF/ directory; and "jar" for other archives
+// carrying META-INF/MANIFEST.MF. Any other zip archive yields "zip".
+func ZipSubtype(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf(fmtErrArchiveOpen, err)
+	}
+	defer r.Close()
+
+	names := make(map[string]*zip.File, len(r.File))
+	var web bool
+	for _, f := range r.File {
+		names[f.Name] = f
+		web = web || strings.HasPrefix(f.Name, "WEB-INF/")
+	}
+
+	if f, ok := names["mimetype"]; ok {
+		mimetype, err := readMimetype(f)
+		if err != nil {
+			return "", err
+		}
+		if subtype, ok := mimetypeSubtypes[mimetype]; ok {
+			return subtype, nil
+		}
+	}
+
+	if _, ok := names["[Content_Types].xml"]; ok {
+		for _, f := range r.File {
+			for _, o := range ooxmlSubtypes {
+				if strings.HasPrefix(f.Name, o.prefix) {
+					return o.subtype, nil
+				}
+			}
+		}
+	}
+
+	_, manifest := names["AndroidManifest.xml"]
+	_, dex := names["classes.dex"]
+	switch {
+	case manifest && dex:
+		return "apk", nil
+	case web:
+		return "war", nil
+	}
+
+	if _, ok := names["META-INF/MANIFEST.MF"]; ok {
+		return "jar", nil
+	}
+
+	return "zip", nil
+}
+
+// Reads the contents of a mimetype entry, which names the media type of the container.
+func readMimetype(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf(fmtErrZipOpenFile, err)
+	}
+	defer rc.Close()
+
+	mimetype, err := io.ReadAll(io.LimitReader(rc, maxMimetypeLen))
+	if err != nil {
+		return "", fmt.Errorf(fmtErrZipReadFailed, err)
+	}
+	return strings.TrimSpace(string(mimetype)), nil
+}
@@ -0,0 +1,48 @@
+package archive
+
+import (
+	"testing"
+)
+
+type zipSubtypeTest struct {
+	entries  []testEntry
+	expected string
+}
+
+var zipSubtypes = []zipSubtypeTest{
+	{[]testEntry{{name: "mimetype", body: "application/epub+zip"}, {name: "META-INF/container.xml", body: "<container/>"}}, "epub"},
+	{[]testEntry{{name: "mimetype", body: "application/vnd.oasis.opendocument.text"}, {name: "content.xml", body: "<doc/>"}}, "odt"},
+	{[]testEntry{{name: "mimetype", body: "application/vnd.oasis.opendocument.spreadsheet"}}, "ods"},
+	{[]testEntry{{name: "[Content_Types].xml", body: "<Types/>"}, {name: "_rels/.rels"}, {name: "word/document.xml"}}, "docx"},
+	{[]testEntry{{name: "[Content_Types].xml", body: "<Types/>"}, {name: "xl/workbook.xml"}}, "xlsx"},
+	{[]testEntry{{name: "[Content_Types].xml", body: "<Types/>"}, {name: "ppt/presentation.xml"}}, "pptx"},
+	{[]testEntry{{name: "AndroidManifest.xml"}, {name: "classes.dex"}, {name: "META-INF/MANIFEST.MF"}}, "apk"},
+	{[]testEntry{{name: "META-INF/MANIFEST.MF"}, {name: "WEB-INF/web.xml"}}, "war"},
+	{[]testEntry{{name: "META-INF/"}, {name: "META-INF/MANIFEST.MF", body: "Manifest-Version: 1.0\n"}, {name: "Main.class"}}, "jar"},
+	{[]testEntry{{name: "mimetype", body: "text/plain"}}, "zip"},
+	{[]testEntry{{name: "[Content_Types].xml"}}, "zip"},
+	{[]testEntry{{name: "readme.txt", body: "lorem"}}, "zip"},
+	{nil, "zip"},
+}
+
+func TestZipSubtype(t *testing.T) {
+	for _, c := range zipSubtypes {
+		path := writeTestArchive(t, "container.zip", c.entries)
+
+		subtype, err := ZipSubtype(path)
+		if err != nil {
+			t.Errorf("Unexpected error: %v\n", err)
+		}
+		if subtype != c.expected {
+			t.Errorf("Expecting '%s', got '%s'\n", c.expected, subtype)
+		}
+	}
+
+	if subtype, err := ZipSubtype("testdata/sample.zip"); subtype != "zip" || err != nil {
+		t.Errorf("Expecting '%s', got '%s' (error: %v)\n", "zip", subtype, err)
+	}
+
+	if _, err := ZipSubtype("testdata/sample.tar"); err == nil {
+		t.Error("Failed to receive non-nil error for a tar archive.")
+	}
+}
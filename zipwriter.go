@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Permissions recorded for entries added to a zip archive from a reader, and for
+// directory entries.
+const (
+	zipFilePerm fs.FileMode = 0644
+	zipDirPerm  fs.FileMode = 0755
+)
+
+// ZipWriter writes a zip archive, compressing each entry with a configurable method.
+// It is a convenience wrapper around zip.Writer for building archives from files on
+// disk and from readers.
+type ZipWriter struct {
+	writer *zip.Writer
+	method uint16
+}
+
+// NewZipWriter returns a ZipWriter that writes a zip archive to w. Entries are
+// compressed with zip.Deflate unless changed with SetMethod.
+func NewZipWriter(w io.Writer) *ZipWriter {
+	return &ZipWriter{writer: zip.NewWriter(w), method: zip.Deflate}
+}
+
+// SetMethod sets the compression method, such as zip.Store or zip.Deflate, used for
+// entries added subsequently. Directory entries are always stored. Adding an entry
+// fails if no compressor is registered for the method.
+func (z *ZipWriter) SetMethod(method uint16) {
+	z.method = method
+}
+
+// AddFile adds the file or directory at diskPath to the archive under archiveName,
+// recording its modification time and permission bits. A directory is added as a
+// single directory entry, whose name is given a trailing slash if it lacks one; its
+// contents are not added. Other non-regular files cannot be added.
+func (z *ZipWriter) AddFile(diskPath, archiveName string) error {
+	info, err := os.Stat(diskPath)
+	if err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+
+	if info.IsDir() {
+		return z.addDir(archiveName, info.ModTime())
+	}
+	if !info.Mode().IsRegular() {
+		return errUnsupportedEntry
+	}
+
+	file, err := os.Open(filepath.Clean(diskPath))
+	if err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+	defer file.Close()
+
+	return z.add(archiveName, file, info.Mode().Perm(), info.ModTime())
+}
+
+// AddReader adds a regular file entry named archiveName to the archive, whose contents
+// are read from r. The entry is given 0644 permission bits and the current time as its
+// modification time.
+func (z *ZipWriter) AddReader(archiveName string, r io.Reader) error {
+	return z.add(archiveName, r, zipFilePerm, time.Now())
+}
+
+// Close finishes writing the archive by writing its central directory. It does not
+// close the underlying writer.
+func (z *ZipWriter) Close() error {
+	if err := z.writer.Close(); err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+	return nil
+}
+
+// Adds a regular file entry with the given contents to the archive.
+func (z *ZipWriter) add(name string, r io.Reader, perm fs.FileMode, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name, Method: z.method, Modified: modTime}
+	header.SetMode(perm)
+
+	w, err := z.writer.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+	return nil
+}
+
+// Adds a directory entry to the archive.
+func (z *ZipWriter) addDir(name string, modTime time.Time) error {
+	if !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+
+	header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: modTime}
+	header.SetMode(fs.ModeDir | zipDirPerm)
+
+	if _, err := z.writer.CreateHeader(header); err != nil {
+		return fmt.Errorf(fmtErrZipWriteFailed, err)
+	}
+	return nil
+}
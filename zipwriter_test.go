@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZipWriter(t *testing.T) {
+	dir := t.TempDir()
+	diskFile := filepath.Join(dir, "lorem.txt")
+	if err := os.WriteFile(diskFile, []byte(strings.Repeat("lorem ipsum ", 100)), 0640); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(diskFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewZipWriter(&buf)
+	if err := w.AddFile(dir, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddFile(diskFile, "docs/lorem.txt"); err != nil {
+		t.Fatal(err)
+	}
+	w.SetMethod(zip.Store)
+	if err := w.AddReader("docs/stored.txt", strings.NewReader("dolor sit amet")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 3 {
+		t.Fatalf("Expecting '%d', got '%d'\n", 3, len(r.File))
+	}
+
+	dirEntry, fileEntry, storedEntry := r.File[0], r.File[1], r.File[2]
+
+	if dirEntry.Name != "docs/" || dirEntry.Mode() != fs.ModeDir|0755 || dirEntry.Method != zip.Store {
+		t.Errorf("Unexpected directory entry: %s %v %d\n", dirEntry.Name, dirEntry.Mode(), dirEntry.Method)
+	}
+
+	if fileEntry.Method != zip.Deflate || fileEntry.Mode() != 0640 || !fileEntry.Modified.Equal(modTime) {
+		t.Errorf("Unexpected file entry: %d %v %v\n", fileEntry.Method, fileEntry.Mode(), fileEntry.Modified)
+	}
+
+	if storedEntry.Method != zip.Store || storedEntry.Mode() != 0644 {
+		t.Errorf("Unexpected stored entry: %d %v\n", storedEntry.Method, storedEntry.Mode())
+	}
+
+	rc, err := storedEntry.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(content) != "dolor sit amet" {
+		t.Errorf("Expecting '%s', got '%s'\n", "dolor sit amet", content)
+	}
+}
+
+func TestZipWriter_errors(t *testing.T) {
+	w := NewZipWriter(io.Discard)
+	defer w.Close()
+
+	if err := w.AddFile("nonexistent.txt", "a.txt"); err == nil {
+		t.Error("Failed to receive non-nil error for a nonexistent file.")
+	}
+
+	w.SetMethod(99)
+	if err := w.AddReader("a.txt", strings.NewReader("lorem")); !errors.Is(err, zip.ErrAlgorithm) {
+		t.Errorf("Expecting '%s', got '%v'\n", zip.ErrAlgorithm, err)
+	}
+}